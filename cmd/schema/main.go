@@ -20,9 +20,9 @@ func main() {
 
 	schema, err := gqlfetch.BuildClientSchemaWithHeaders(
 		context.Background(),
-		wtsc.AppConfig.POKTscanApi,
+		wtsc.GetConfig().POKTscanApi,
 		http.Header{
-			"Authorization": []string{wtsc.AppConfig.POKTscanApiToken},
+			"Authorization": []string{wtsc.GetConfig().POKTscanApiToken},
 		},
 		false,
 	)
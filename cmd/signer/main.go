@@ -0,0 +1,93 @@
+// signer is a reference remote signing service for wtsc, meant for testing the remote_signer_url integration.
+// Keys are loaded with the same sources as wtsc (plain keys file and/or encrypted key files) and the bearer token
+// is read from WTSC_SIGNER_TOKEN.
+package main
+
+import (
+	"bufio"
+	"flag"
+	"github.com/pokt-scan/wtsc/wtsc"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const tokenEnv = "WTSC_SIGNER_TOKEN"
+
+func readKeysFile(path string) []string {
+	file, err := os.Open(path)
+	if err != nil {
+		wtsc.Logger.Fatal().Err(err).Str("path", path).Msg("failed to open keys file")
+	}
+	defer file.Close()
+
+	keys := make([]string, 0)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if key := strings.TrimSpace(scanner.Text()); !wtsc.IsEmptyString(key) {
+			keys = append(keys, key)
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		wtsc.Logger.Fatal().Err(err).Str("path", path).Msg("failed to read keys file")
+	}
+
+	return keys
+}
+
+func main() {
+	listen := flag.String("listen", "127.0.0.1:9092", "address to listen")
+	keysFile := flag.String("keys-file", "", "file with one hex private key per line")
+	keyFilesDir := flag.String("keyfiles-dir", "", "directory of encrypted key files (pocket accounts export format)")
+	passphraseFile := flag.String("passphrase-file", "", "file with the key files passphrase, "+wtsc.KeyFilePassphraseEnv+" env var is used otherwise")
+	flag.Parse()
+
+	wtsc.Logger = wtsc.GetDefaultLogger()
+
+	token := os.Getenv(tokenEnv)
+	if wtsc.IsEmptyString(token) {
+		wtsc.Logger.Fatal().Msg(tokenEnv + " is required")
+	}
+
+	cfg := &wtsc.Config{
+		ServicerKeyFilesDir:   *keyFilesDir,
+		KeyFilePassphraseFile: *passphraseFile,
+	}
+	if !wtsc.IsEmptyString(*keysFile) {
+		cfg.ServicerKeys = readKeysFile(*keysFile)
+	}
+
+	signers, err := wtsc.LoadSigners(cfg)
+	if err != nil {
+		wtsc.Logger.Fatal().Err(err).Msg("unable to load signers")
+	}
+	if len(signers) == 0 {
+		wtsc.Logger.Fatal().Msg("there are no keys to serve, use -keys-file and/or -keyfiles-dir")
+	}
+
+	for _, signer := range signers {
+		wtsc.Logger.Info().Str("address", signer.GetAddress()).Msg("serving key")
+	}
+
+	server := &http.Server{
+		Addr:              *listen,
+		Handler:           wtsc.NewRemoteSignerHandler(signers, token),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		wtsc.Logger.Info().Str("address", *listen).Msg("starting signer...")
+		if e := server.ListenAndServe(); e != nil && e != http.ErrServerClosed {
+			wtsc.Logger.Fatal().Err(e).Msg("signer stopped")
+		}
+	}()
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	sig := <-sigChan
+	wtsc.Logger.Info().Str("signal", sig.String()).Msg("received signal. exiting...")
+	_ = server.Close()
+}
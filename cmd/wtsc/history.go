@@ -0,0 +1,97 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/pokt-scan/wtsc/wtsc"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// History lists the latest evaluation runs or prints the full detail of a single one.
+//
+//	wtsc history [-limit N] [run-id]
+func History(args []string) {
+	fs := flag.NewFlagSet("history", flag.ExitOnError)
+	limit := fs.Int("limit", 20, "max amount of runs to list (0 means all)")
+	_ = fs.Parse(args)
+
+	commandLogger()
+
+	// Initialize wtsc
+	wtsc.Init()
+
+	history := wtsc.NewHistoryStore(wtsc.GetConfig().HistoryPath)
+	if history == nil {
+		wtsc.Logger.Fatal().Msg("history_path is not configured")
+	}
+
+	if fs.NArg() > 0 {
+		printRun(history, fs.Arg(0))
+		return
+	}
+
+	runs, err := history.ListRuns(*limit)
+	if err != nil {
+		wtsc.Logger.Fatal().Err(err).Msg("failed to list runs")
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "ID\tSTARTED\tDURATION\tOUTCOME\tDO_UPDATE\tGAIN_CHANGE_%\tRESULTS")
+	for _, run := range runs {
+		doUpdate := "-"
+		gain := "-"
+		if run.Response != nil {
+			doUpdate = fmt.Sprintf("%t", run.Response.GetWhatToStake.Do_update)
+			gain = fmt.Sprintf("%.2f", run.Response.GetWhatToStake.Gain_change_percent)
+		}
+		_, _ = fmt.Fprintf(
+			w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			run.ID,
+			run.StartedAt.Local().Format(time.DateTime),
+			(time.Duration(run.DurationMs) * time.Millisecond).String(),
+			run.Outcome,
+			doUpdate,
+			gain,
+			summarizeResults(run.Results),
+		)
+	}
+	_ = w.Flush()
+}
+
+func printRun(history *wtsc.HistoryStore, id string) {
+	run, err := history.GetRun(id)
+	if errors.Is(err, wtsc.ErrRecordNotFound) {
+		wtsc.Logger.Fatal().Str("run_id", id).Msg("run not found")
+	}
+	if err != nil {
+		wtsc.Logger.Fatal().Err(err).Str("run_id", id).Msg("failed to read run")
+	}
+
+	printJson(run)
+}
+
+func summarizeResults(results []*wtsc.ServicerResult) string {
+	if len(results) == 0 {
+		return "-"
+	}
+
+	counts := make(map[wtsc.TxStatus]int)
+	order := make([]wtsc.TxStatus, 0)
+	for _, result := range results {
+		if _, ok := counts[result.Status]; !ok {
+			order = append(order, result.Status)
+		}
+		counts[result.Status]++
+	}
+
+	parts := make([]string, 0, len(order))
+	for _, status := range order {
+		parts = append(parts, fmt.Sprintf("%s=%d", status, counts[status]))
+	}
+
+	return strings.Join(parts, " ")
+}
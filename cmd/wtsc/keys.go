@@ -0,0 +1,48 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/pokt-scan/wtsc/wtsc"
+	"os"
+	"text/tabwriter"
+)
+
+// Keys prints the addresses of the configured keys (plain keys, key files and remote signer) and the watch-only
+// addresses. Private keys are never printed.
+//
+//	wtsc keys list
+func Keys(args []string) {
+	fs := flag.NewFlagSet("keys", flag.ExitOnError)
+	_ = fs.Parse(args)
+
+	if fs.NArg() != 1 || fs.Arg(0) != "list" {
+		_, _ = fmt.Fprintln(os.Stderr, "usage: wtsc keys list")
+		os.Exit(2)
+	}
+
+	commandLogger()
+
+	// Initialize wtsc
+	wtsc.Init()
+
+	cfg := wtsc.GetConfig()
+	signers, err := wtsc.LoadSigners(cfg)
+	if err != nil {
+		wtsc.Logger.Fatal().Err(err).Msg("unable to load servicers")
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "ADDRESS\tPUBLIC_KEY\tTYPE")
+	for _, signer := range signers {
+		signerType := "local"
+		if _, ok := signer.(*wtsc.RemoteSigner); ok {
+			signerType = "remote"
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\n", signer.GetAddress(), signer.GetPublicKey(), signerType)
+	}
+	for _, address := range cfg.ServicerAddresses {
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\n", address, "-", "watch_only")
+	}
+	_ = w.Flush()
+}
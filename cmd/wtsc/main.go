@@ -1,8 +1,10 @@
 package main
 
 import (
+	"flag"
+	"fmt"
 	"github.com/pokt-scan/wtsc/wtsc"
-	"github.com/rs/zerolog/log"
+	"github.com/rs/zerolog"
 	"os"
 	"os/signal"
 	"strconv"
@@ -10,7 +12,8 @@ import (
 	"time"
 )
 
-func Run() {
+// HotReload reloads the config when the file changes, on SIGHUP and optionally every RELOAD_SECONDS
+func HotReload() {
 	defer func() {
 		if r := recover(); r != nil {
 			wtsc.Logger.Trace().Stack().Timestamp().
@@ -19,59 +22,219 @@ func Run() {
 		}
 	}()
 
-	// hardcode time for now - could be replaced by env var?
-	// Default duration
-	defaultDuration := 30 * time.Second
+	// SIGHUP forces a reload, i.e. `kill -HUP <pid>`
+	hupChan := make(chan os.Signal, 1)
+	signal.Notify(hupChan, syscall.SIGHUP)
 
-	// Get the environment variable
-	envReloadSeconds := os.Getenv("RELOAD_SECONDS")
-
-	// Parse the environment variable
-	reloadSeconds, err := strconv.Atoi(envReloadSeconds)
-	var sleepDuration time.Duration
+	// Polling is optional when the config file is watched, but it is the fallback when the watcher can't start
+	var pollDuration time.Duration
+	if reloadSeconds, err := strconv.Atoi(os.Getenv("RELOAD_SECONDS")); err == nil && reloadSeconds > 0 {
+		pollDuration = time.Duration(reloadSeconds) * time.Second
+	}
 
-	if err != nil || reloadSeconds <= 0 {
-		// Use the default duration if parsing fails or if the value is non-positive
-		sleepDuration = defaultDuration
+	watcher, err := wtsc.WatchConfig()
+	if err != nil {
+		wtsc.Logger.Error().Err(err).Msg("failed to watch config file, falling back to polling")
+		if pollDuration == 0 {
+			pollDuration = 30 * time.Second
+		}
 	} else {
-		// Use the parsed duration
-		sleepDuration = time.Duration(reloadSeconds) * time.Second
+		defer watcher.Stop()
 	}
 
-	wtsc.Logger.Info().Dur("duration", sleepDuration).Msg("config hot reload configured")
+	var pollChan <-chan time.Time
+	if pollDuration > 0 {
+		ticker := time.NewTicker(pollDuration)
+		defer ticker.Stop()
+		pollChan = ticker.C
+		wtsc.Logger.Info().Dur("duration", pollDuration).Msg("config hot reload polling configured")
+	}
 
 	for {
-		time.Sleep(sleepDuration)
-		wtsc.ReloadConfig()
+		select {
+		case <-hupChan:
+			wtsc.Logger.Info().Msg("received SIGHUP, reloading config")
+		case <-pollChan:
+		}
+		// an invalid file is logged and rejected, the last good config keeps running
+		_ = wtsc.ReloadConfig()
 	}
 }
 
-func main() {
+// Setup initializes wtsc and all the components needed to call what-to-stake and submit stake transactions. It
+// stops the process when the signers could not be loaded.
+func Setup() {
+	if err := TrySetup(); err != nil {
+		wtsc.Logger.Fatal().Err(err).Msg("failed to setup wtsc")
+	}
+}
+
+// TrySetup is Setup, but it returns the error of the signers instead of stopping the process
+func TrySetup() error {
 	// Initialize wtsc
 	wtsc.Init()
+	cfg := wtsc.GetConfig()
 
-	// Configure logger
-	wtsc.ConfigLogger(wtsc.AppConfig.LogLevel, wtsc.AppConfig.LogFormat)
+	// Configure logger, commands keep the one of commandLogger
+	if !commandLogs {
+		wtsc.ConfigLogger(cfg.LogLevel, cfg.LogFormat)
+	}
+
+	// Initialize the servicers map
+	signers, err := wtsc.NewSignerMap(cfg)
+	if err != nil {
+		return err
+	}
 
 	// Configure http client
-	wtsc.NewHttpClient(wtsc.AppConfig.POKTscanApiToken, wtsc.AppConfig.MaxRetries, wtsc.AppConfig.MaxTimeout)
+	httpClient := wtsc.NewHttpClient(cfg.POKTscanApiToken, cfg.MaxRetries, cfg.MaxTimeout)
 
-	// Create POKTscan Client
-	wtsc.NewPOKTscanClient(wtsc.AppConfig.POKTscanApi)
+	// publish the config with its dependencies
+	wtsc.SetRuntime(&wtsc.Runtime{
+		Config:     cfg,
+		HttpClient: httpClient,
+		Wts:        wtsc.NewPOKTscanClient(cfg.POKTscanApi, httpClient),
+		Rpc:        wtsc.NewPocketRpcProvider(cfg.PocketRPC, cfg.MaxRetries, cfg.MaxTimeout),
+		Signers:    signers,
+		// max capacity will be the amount of servicers
+		Workers: wtsc.NewWorker(cfg.MaxWorkers, uint(signers.Size())),
+		History: wtsc.NewHistoryStore(cfg.HistoryPath),
+	})
 
-	// Create PocketRpcProvider
-	wtsc.NewPocketRpcProvider(wtsc.AppConfig.PocketRPC, wtsc.AppConfig.MaxRetries, wtsc.AppConfig.MaxTimeout)
+	return nil
+}
 
-	// Initialize the worker pool
-	wtsc.NewWorker(wtsc.AppConfig.MaxWorkers, uint(len(wtsc.AppConfig.ServicerKeys)))
+const usage = `usage: wtsc [--config file] [--log-level level] <command> [args]
 
-	// Initialize the servicers map
-	wtsc.NewSignerMap(wtsc.AppConfig.ServicerKeys)
+commands:
+  run             start the daemon (default)
+  once            run a single evaluation and exit
+  plan            print the recommendation and its diff with the on-chain state of the nodes
+  apply           approve a pending plan and submit its stake transactions
+  export          sign the stake transactions of a plan into a file, without broadcasting them
+  assemble        add external signatures to a file written by export -unsigned
+  broadcast       submit the stake transactions of an exported file
+  history         list the evaluation runs or print one of them
+  keys list       print the addresses of the configured keys
+  nodes           print the on-chain state of the servicers
+  validate        check the config file
+  tx decode       print a raw stake transaction
+  mock-poktscan   serve a fake POKTscan API to run wtsc offline
+  mock-pocket     serve a fake Pocket RPC to submit the stake txs offline
+  version         print the version
+
+flags:
+`
+
+// logLevelFlag is the --log-level flag, empty when it is not set
+var logLevelFlag string
+
+// commandLogs is set by commandLogger, so Setup keeps the command logger instead of the one of the config
+var commandLogs bool
+
+// commandLogger keeps stdout for the command output: the logs go to stderr and only the warnings are written, unless
+// --log-level asks for more. Call it before Init or Setup, so their logs are quiet too.
+func commandLogger() {
+	level := zerolog.WarnLevel
+	if !wtsc.IsEmptyString(logLevelFlag) {
+		// already validated by main
+		level, _ = zerolog.ParseLevel(logLevelFlag)
+	}
+	commandLogs = true
+	wtsc.LogOutput = os.Stderr
+	wtsc.Logger = wtsc.GetDefaultLogger()
+	// the level is the global one, so it applies to the logger of Init as well
+	wtsc.ConfigLogger(level.String(), wtsc.LogTextFormat)
+}
+
+func main() {
+	fs := flag.NewFlagSet("wtsc", flag.ExitOnError)
+	configFile := fs.String("config", "", "config file (json, yaml or toml), instead of PROJECT_ROOT/CONFIG_FILE")
+	fs.StringVar(&logLevelFlag, "log-level", "", "log level, instead of log_level of the config")
+	fs.Usage = func() {
+		_, _ = fmt.Fprint(os.Stderr, usage)
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(os.Args[1:])
+
+	if !wtsc.IsEmptyString(*configFile) {
+		wtsc.SetConfigFilePath(*configFile)
+	}
+	if !wtsc.IsEmptyString(logLevelFlag) {
+		if _, err := zerolog.ParseLevel(logLevelFlag); err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "invalid --log-level: %s\n", err)
+			os.Exit(2)
+		}
+		// it is applied like any other env override, so it also survives the config reloads
+		_ = os.Setenv(wtsc.ConfigEnvName("log_level"), logLevelFlag)
+	}
+
+	command := "run"
+	args := fs.Args()
+	if len(args) > 0 {
+		command, args = args[0], args[1:]
+	}
+
+	switch command {
+	case "run":
+		RunDaemon(args)
+	case "once":
+		Once(args)
+	case "plan":
+		PlanCmd(args)
+	case "apply":
+		Apply(args)
+	case "export":
+		Export(args)
+	case "assemble":
+		Assemble(args)
+	case "broadcast":
+		Broadcast(args)
+	case "history":
+		History(args)
+	case "keys":
+		Keys(args)
+	case "nodes":
+		Nodes(args)
+	case "validate":
+		Validate(args)
+	case "tx":
+		Tx(args)
+	case "mock-poktscan":
+		MockPOKTscan(args)
+	case "mock-pocket":
+		MockPocket(args)
+	case "version":
+		fmt.Println(wtsc.GetVersion())
+	case "help":
+		fs.Usage()
+	default:
+		_, _ = fmt.Fprintf(os.Stderr, "unknown command %q\n\n", command)
+		fs.Usage()
+		os.Exit(2)
+	}
+}
+
+// RunDaemon starts the scheduled evaluations, the http servers and the config hot reload until SIGINT/SIGTERM.
+//
+//	wtsc run
+func RunDaemon(args []string) {
+	fs := flag.NewFlagSet("run", flag.ExitOnError)
+	_ = fs.Parse(args)
+
+	Setup()
+	cfg := wtsc.GetConfig()
+
+	// Initialize the http server (metrics, health and status)
+	wtsc.NewHttpServer(cfg.HttpAddress)
+
+	// Initialize the admin api
+	wtsc.NewAdminServer(cfg.AdminAddress, cfg.AdminToken)
 
 	// Initialize the cron job
-	_, err := wtsc.Schedule(wtsc.AppConfig.Schedule, wtsc.AppConfig.RunOnceAtStart)
+	_, err := wtsc.Schedule(cfg.Schedule, cfg.DigestSchedule, cfg.RunOnceAtStart)
 	if err != nil {
-		log.Fatal().Err(err).Msg("failed to schedule cron job")
+		wtsc.Logger.Fatal().Err(err).Msg("failed to schedule cron job")
 	}
 
 	// Create a channel to receive OS signals
@@ -81,19 +244,25 @@ func main() {
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 
 	wtsc.Logger.Info().Msg("starting what to stake consumer...")
-	go Run()
+	go HotReload()
 
 	// Block until we receive a signal
 	sig := <-sigChan
 	wtsc.Logger.Info().Str("signal", sig.String()).Msg("received signal. exiting...")
 	wtsc.Logger.Info().Msg("shutting down...")
 	// stop cron job schedule another one
-	wtsc.CronJob.Stop()
+	wtsc.StopSchedule()
+	// stop accepting admin requests that could trigger new evaluations
+	wtsc.StopAdminServer()
 	// wait for any in progress job.
-	if wtsc.WorkerPool.WaitingTasks() > 0 {
-		wtsc.Logger.Debug().Uint64("waiting_tasks", wtsc.WorkerPool.WaitingTasks()).Msg("shutting down workers...")
+	if rt := wtsc.GetRuntime(); rt.Workers.WaitingTasks() > 0 {
+		wtsc.Logger.Debug().Uint64("waiting_tasks", rt.Workers.WaitingTasks()).Msg("shutting down workers...")
 	}
-	wtsc.WorkerPool.StopAndWait()
+	wtsc.StopWorkers()
+	// let the last notifications go out
+	wtsc.WaitNotifications()
+	// stop serving metrics at the very end
+	wtsc.StopHttpServer()
 	wtsc.Logger.Info().Msg("see you later, baby!")
 	os.Exit(0)
 }
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"flag"
+	"github.com/pokt-scan/wtsc/wtsc"
+	"github.com/pokt-scan/wtsc/wtsc/mock"
+	"github.com/rs/zerolog"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// MockPOKTscan serves a fake POKTscan API, point poktscan_api to it to run wtsc without a POKTscan token.
+// Without -servicers, the addresses of the keys and servicer_addresses of the config are recommended.
+//
+//	wtsc mock-poktscan [-listen 127.0.0.1:9093] [-scenarios update,no_update,...] [-servicers a,b] [-token t] [-delay 5s]
+func MockPOKTscan(args []string) {
+	fs := flag.NewFlagSet("mock-poktscan", flag.ExitOnError)
+	listen := fs.String("listen", "127.0.0.1:9093", "address to listen")
+	scenariosFlag := fs.String("scenarios", string(mock.ScenarioUpdate), "comma separated scenarios answered in order, the last one is repeated")
+	servicersFlag := fs.String("servicers", "", "comma separated addresses of the recommendation, the config ones are used otherwise")
+	token := fs.String("token", "", "expected poktscan_api_token, empty accepts any")
+	delay := fs.Duration("delay", mock.DefaultSlowDelay, "delay of the slow scenario")
+	_ = fs.Parse(args)
+
+	mockLogger()
+
+	scenarios, err := mock.ParsePOKTscanScenarios(*scenariosFlag)
+	if err != nil {
+		wtsc.Logger.Fatal().Err(err).Msg("invalid -scenarios")
+	}
+
+	servicers := splitList(*servicersFlag)
+	if len(servicers) == 0 {
+		servicers = configAddresses()
+	}
+
+	p := &mock.POKTscan{
+		Scenarios: scenarios,
+		Servicers: servicers,
+		Token:     *token,
+		Delay:     *delay,
+	}
+
+	wtsc.Logger.Info().Strs("servicers", servicers).Str("address", *listen).Msg("starting mock poktscan api...")
+	serveUntilSignal(*listen, logRequests(p))
+}
+
+// MockPocket serves a fake Pocket RPC, point pocket_rpc to it to submit the stake txs without touching a network.
+// The nodes of the configured keys are registered staked on -chains, the txs must be signed for -network-id.
+//
+//	wtsc mock-pocket [-listen 127.0.0.1:9081] [-network-id testnet] [-chains 0001] [-tx-scenarios ok,failed,...] [-balance 1000000000]
+func MockPocket(args []string) {
+	fs := flag.NewFlagSet("mock-pocket", flag.ExitOnError)
+	listen := fs.String("listen", "127.0.0.1:9081", "address to listen")
+	networkID := fs.String("network-id", mock.DefaultNetworkID, "network id of the signatures")
+	chainsFlag := fs.String("chains", "0001", "comma separated chains of the registered nodes")
+	scenariosFlag := fs.String("tx-scenarios", string(mock.TxScenarioOk), "comma separated tx scenarios answered in order, the last one is repeated")
+	balance := fs.Int64("balance", 1000000000, "balance in upokt of each account")
+	tokens := fs.Int64("tokens", 15000000000, "staked upokt of the registered nodes")
+	_ = fs.Parse(args)
+
+	mockLogger()
+
+	scenarios, err := mock.ParseTxScenarios(*scenariosFlag)
+	if err != nil {
+		wtsc.Logger.Fatal().Err(err).Msg("invalid -tx-scenarios")
+	}
+
+	p := mock.NewPocketRpc()
+	p.NetworkID = *networkID
+	p.TxScenarios = scenarios
+	p.DefaultBalance = *balance
+
+	for _, signer := range configSigners() {
+		node, e := mock.NewNode(signer.GetPublicKey(), splitList(*chainsFlag), *tokens)
+		if e != nil {
+			wtsc.Logger.Fatal().Err(e).Str("address", signer.GetAddress()).Msg("unable to register node")
+		}
+		p.AddNode(node)
+		wtsc.Logger.Info().Str("address", node.Address).Strs("chains", node.Chains).Msg("node registered")
+	}
+
+	wtsc.Logger.Info().Str("network_id", p.NetworkID).Str("address", *listen).Msg("starting mock pocket rpc...")
+	serveUntilSignal(*listen, logRequests(p))
+}
+
+// configSigners returns the signers of the config, if it could be loaded
+func configSigners() []wtsc.Signer {
+	cfg, err := wtsc.ReadConfig(wtsc.GetConfigFilePath())
+	if err != nil {
+		wtsc.Logger.Warn().Err(err).Msg("unable to read the config, no node is registered")
+		return nil
+	}
+
+	signers, err := wtsc.LoadSigners(cfg)
+	if err != nil {
+		wtsc.Logger.Warn().Err(err).Msg("unable to load the signers of the config")
+	}
+	return signers
+}
+
+// configAddresses returns the addresses of the keys and servicer_addresses of the config, if it could be loaded
+func configAddresses() []string {
+	cfg, err := wtsc.ReadConfig(wtsc.GetConfigFilePath())
+	if err != nil {
+		wtsc.Logger.Warn().Err(err).Msg("unable to read the config, the recommendation has no servicers")
+		return nil
+	}
+
+	addresses := make([]string, 0)
+	signers, err := wtsc.LoadSigners(cfg)
+	if err != nil {
+		wtsc.Logger.Warn().Err(err).Msg("unable to load the signers of the config")
+	}
+	for _, signer := range signers {
+		addresses = append(addresses, signer.GetAddress())
+	}
+
+	return append(addresses, cfg.ServicerAddresses...)
+}
+
+// mockLogger logs each request of the mock servers, unless --log-level asks for less
+func mockLogger() {
+	level := zerolog.InfoLevel.String()
+	if !wtsc.IsEmptyString(logLevelFlag) {
+		level = logLevelFlag
+	}
+	wtsc.Logger = wtsc.GetDefaultLogger()
+	wtsc.ConfigLogger(level, wtsc.LogTextFormat)
+}
+
+func splitList(value string) []string {
+	items := make([]string, 0)
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); !wtsc.IsEmptyString(item) {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func logRequests(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		wtsc.Logger.Info().Str("method", r.Method).Str("path", r.URL.Path).Msg("mock request")
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// serveUntilSignal serves the handler until SIGINT/SIGTERM
+func serveUntilSignal(listen string, handler http.Handler) {
+	server := &http.Server{
+		Addr:              listen,
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		if e := server.ListenAndServe(); e != nil && e != http.ErrServerClosed {
+			wtsc.Logger.Fatal().Err(e).Msg("mock server stopped")
+		}
+	}()
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	<-sigChan
+	_ = server.Close()
+}
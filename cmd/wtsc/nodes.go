@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	pocketGoProvider "github.com/pokt-foundation/pocket-go/provider"
+	"github.com/pokt-scan/wtsc/wtsc"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// Nodes prints the current on-chain state of the servicers of the configured keys and the watch-only addresses.
+//
+//	wtsc nodes
+func Nodes(args []string) {
+	fs := flag.NewFlagSet("nodes", flag.ExitOnError)
+	_ = fs.Parse(args)
+
+	commandLogger()
+	Setup()
+
+	cfg := wtsc.GetConfig()
+	signers := wtsc.GetRuntime().Signers
+
+	addresses := make([]string, 0, signers.Size()+len(cfg.ServicerAddresses))
+	signers.Range(func(address string, _ wtsc.Signer) bool {
+		addresses = append(addresses, address)
+		return true
+	})
+	sort.Strings(addresses)
+	for _, address := range cfg.ServicerAddresses {
+		if _, ok := signers.Load(address); !ok {
+			addresses = append(addresses, address)
+		}
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "ADDRESS\tTYPE\tSTATUS\tJAILED\tTOKENS\tCHAINS\tSERVICE_URL")
+	for _, address := range addresses {
+		nodeType := "signer"
+		if _, ok := signers.Load(address); !ok {
+			nodeType = "watch_only"
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.MaxTimeout)*time.Millisecond)
+		node, err := wtsc.DefaultApp.Rpc.GetNodeWithCtx(ctx, address, &pocketGoProvider.GetNodeOptions{Height: 0})
+		cancel()
+		if err != nil {
+			_, _ = fmt.Fprintf(w, "%s\t%s\terror: %s\t-\t-\t-\t-\n", address, nodeType, err)
+			continue
+		}
+
+		_, _ = fmt.Fprintf(
+			w, "%s\t%s\t%s\t%t\t%s\t%s\t%s\n",
+			address,
+			nodeType,
+			nodeStatus(node.Status),
+			node.Jailed,
+			node.Tokens,
+			strings.Join(node.Chains, ","),
+			node.ServiceURL,
+		)
+	}
+	_ = w.Flush()
+}
+
+// nodeStatus returns the name of the staking status of pocket-core
+func nodeStatus(status int) string {
+	switch status {
+	case 0:
+		return "unstaked"
+	case 1:
+		return "unstaking"
+	case 2:
+		return "staked"
+	default:
+		return fmt.Sprintf("unknown(%d)", status)
+	}
+}
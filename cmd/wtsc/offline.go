@@ -0,0 +1,221 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/pokt-scan/wtsc/wtsc"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// Export turns a plan into signed raw stake txs written to a bundle file, nothing is broadcast. The plan could be
+// a file written by `wtsc plan` on an online machine (`-` reads it from stdin), a plan of the history or, without
+// both, a new one. Broadcast the bundle later with `wtsc broadcast`.
+//
+// With -unsigned no key is needed, the bundle has the sign bytes of each tx to be signed by an external tool
+// (i.e. a hardware wallet), then `wtsc assemble` adds the signatures.
+//
+//	wtsc export [-plan file|-plan-id id] [-unsigned] [-out stake_txs.json]
+func Export(args []string) {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	planFile := fs.String("plan", "", "plan file written by `wtsc plan`, - reads it from stdin")
+	planID := fs.String("plan-id", "", "id of a plan of the history")
+	unsigned := fs.Bool("unsigned", false, "export the txs and their sign bytes without signing them")
+	out := fs.String("out", "stake_txs.json", "bundle file to write (stake_txs.unsigned.json with -unsigned)")
+	_ = fs.Parse(args)
+
+	if *unsigned && !isFlagSet(fs, "out") {
+		*out = "stake_txs.unsigned.json"
+	}
+
+	commandLogger()
+	Setup()
+
+	var plan *wtsc.Plan
+	switch {
+	case !wtsc.IsEmptyString(*planFile):
+		plan = readPlanFile(*planFile)
+	case !wtsc.IsEmptyString(*planID):
+		history := wtsc.DefaultApp.History()
+		if history == nil {
+			wtsc.Logger.Fatal().Msg("history_path is not configured")
+		}
+		p, err := history.GetPlan(*planID)
+		if err != nil {
+			wtsc.Logger.Fatal().Err(err).Str("plan_id", *planID).Msg("failed to read plan")
+		}
+		// an applied, failed or expired plan has a stale on-chain state, its txs would undo newer changes
+		if p.Status != wtsc.PlanStatusPending {
+			wtsc.Logger.Fatal().Err(wtsc.ErrPlanNotPending).Str("plan_id", *planID).Str("status", string(p.Status)).Msg("failed to export plan")
+		}
+		if p.IsExpired(time.Now()) {
+			wtsc.Logger.Fatal().Err(wtsc.ErrPlanExpired).Str("plan_id", *planID).Msg("failed to export plan")
+		}
+		plan = p
+	default:
+		plan = newPlan()
+	}
+
+	var bundle *wtsc.TxBundle
+	if *unsigned {
+		bundle = wtsc.DefaultApp.ExportUnsignedPlan(plan)
+	} else {
+		bundle = wtsc.DefaultApp.ExportPlan(plan)
+	}
+	if err := wtsc.WriteTxBundle(*out, bundle); err != nil {
+		wtsc.Logger.Fatal().Err(err).Str("path", *out).Msg("failed to write bundle")
+	}
+
+	printBundle(bundle)
+	if *unsigned {
+		fmt.Printf("%d unsigned txs written to %s\n", len(bundle.Txs), *out)
+		return
+	}
+	fmt.Printf("%d signed txs written to %s\n", len(bundle.Txs), *out)
+}
+
+// Assemble adds the signatures made by an external tool to an unsigned bundle written by `wtsc export -unsigned`
+// and writes the signed bundle for `wtsc broadcast`. The signatures (hex) are the `signature` of each tx of the
+// bundle or, with -signatures, a json object of address to signature. It needs neither keys nor network, and it
+// exits 1 if any tx has no valid signature.
+//
+//	wtsc assemble [-signatures file] [-out stake_txs.json] <file>
+func Assemble(args []string) {
+	fs := flag.NewFlagSet("assemble", flag.ExitOnError)
+	signaturesFile := fs.String("signatures", "", "json file of address to signature (hex)")
+	out := fs.String("out", "stake_txs.json", "signed bundle file to write")
+	_ = fs.Parse(args)
+
+	commandLogger()
+
+	if fs.NArg() != 1 {
+		_, _ = fmt.Fprintln(os.Stderr, "usage: wtsc assemble [-signatures file] [-out file] <file>")
+		os.Exit(2)
+	}
+	if fs.Arg(0) == *out {
+		_, _ = fmt.Fprintln(os.Stderr, "-out must not be the unsigned bundle")
+		os.Exit(2)
+	}
+
+	unsignedBundle, err := wtsc.ReadTxBundle(fs.Arg(0))
+	if err != nil {
+		wtsc.Logger.Fatal().Err(err).Msg("failed to read bundle")
+	}
+
+	signatures := make(map[string]string)
+	if !wtsc.IsEmptyString(*signaturesFile) {
+		bz, e := os.ReadFile(*signaturesFile)
+		if e != nil {
+			wtsc.Logger.Fatal().Err(e).Str("path", *signaturesFile).Msg("failed to read signatures")
+		}
+		if e = json.Unmarshal(bz, &signatures); e != nil {
+			wtsc.Logger.Fatal().Err(e).Str("path", *signaturesFile).Msg("invalid signatures file")
+		}
+	}
+
+	bundle, err := wtsc.AssembleBundle(unsignedBundle, signatures)
+	if err != nil {
+		wtsc.Logger.Fatal().Err(err).Msg("failed to assemble bundle")
+	}
+	if e := wtsc.WriteTxBundle(*out, bundle); e != nil {
+		wtsc.Logger.Fatal().Err(e).Str("path", *out).Msg("failed to write bundle")
+	}
+
+	printBundle(bundle)
+	fmt.Printf("%d signed txs written to %s\n", len(bundle.Txs), *out)
+	if len(bundle.Txs) != len(unsignedBundle.Txs) {
+		os.Exit(1)
+	}
+}
+
+// printBundle prints the txs of the bundle, and the skipped nodes on stderr
+func printBundle(bundle *wtsc.TxBundle) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	if bundle.Unsigned {
+		_, _ = fmt.Fprintln(w, "ADDRESS\tCHAINS\tPUBLIC_KEY")
+	} else {
+		_, _ = fmt.Fprintln(w, "ADDRESS\tCHAINS\tHASH")
+	}
+	for _, tx := range bundle.Txs {
+		last := tx.Decoded.Hash
+		if bundle.Unsigned {
+			last = tx.Decoded.PublicKey
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\n", tx.Address, strings.Join(tx.Chains, ","), last)
+	}
+	_ = w.Flush()
+	for address, reason := range bundle.Skipped {
+		_, _ = fmt.Fprintf(os.Stderr, "skipped %s: %s\n", address, reason)
+	}
+}
+
+// Broadcast submits the txs of a bundle written by `wtsc export` through pocket_rpc and tracks them. Like
+// `wtsc once`, it prints the json summary of the run and exits with its code.
+//
+//	wtsc broadcast <file>
+func Broadcast(args []string) {
+	fs := flag.NewFlagSet("broadcast", flag.ExitOnError)
+	_ = fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		_, _ = fmt.Fprintln(os.Stderr, "usage: wtsc broadcast <file>")
+		os.Exit(2)
+	}
+
+	// keep stdout for the summary
+	commandLogger()
+
+	Setup()
+
+	bundle, err := wtsc.ReadTxBundle(fs.Arg(0))
+	if err != nil {
+		wtsc.Logger.Fatal().Err(err).Msg("failed to read bundle")
+	}
+
+	run, err := wtsc.DefaultApp.BroadcastBundle(bundle)
+	if err != nil {
+		wtsc.Logger.Fatal().Err(err).Msg("failed to broadcast bundle")
+	}
+
+	// let the workers and the notifications finish before exit
+	wtsc.StopWorkers()
+	wtsc.WaitNotifications()
+
+	summary := wtsc.NewRunSummary(run)
+	printJson(summary)
+	os.Exit(summary.ExitCode)
+}
+
+// isFlagSet reports if the flag was given on the command line
+func isFlagSet(fs *flag.FlagSet, name string) bool {
+	set := false
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
+func readPlanFile(path string) *wtsc.Plan {
+	var bz []byte
+	var err error
+	if path == "-" {
+		bz, err = io.ReadAll(os.Stdin)
+	} else {
+		bz, err = os.ReadFile(path)
+	}
+	if err != nil {
+		wtsc.Logger.Fatal().Err(err).Str("path", path).Msg("failed to read plan")
+	}
+
+	plan := &wtsc.Plan{}
+	if e := json.Unmarshal(bz, plan); e != nil {
+		wtsc.Logger.Fatal().Err(e).Str("path", path).Msg("invalid plan file")
+	}
+	return plan
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"flag"
+	"github.com/pokt-scan/wtsc/wtsc"
+	"os"
+)
+
+// Once runs a single evaluation and exits, to be used from a kubernetes CronJob or a systemd timer instead of the
+// daemon. The summary of the run is printed as json on stdout (logs go to stderr) and the exit code tells what
+// happened: 0 nothing to do or applied, 3 config error, 4 what-to-stake failed, 5 some stake tx failed.
+//
+//	wtsc once
+func Once(args []string) {
+	fs := flag.NewFlagSet("once", flag.ExitOnError)
+	_ = fs.Parse(args)
+
+	// keep stdout for the summary
+	wtsc.LogOutput = os.Stderr
+	wtsc.Logger = wtsc.GetDefaultLogger()
+
+	// Setup stops the process on a wrong config, check it first to report it
+	if _, err := wtsc.ReadConfig(wtsc.GetConfigFilePath()); err != nil {
+		wtsc.Logger.Error().Err(err).Msg("failed to load config file")
+		printJson(wtsc.NewConfigErrorSummary(err))
+		os.Exit(wtsc.ExitCodeConfigError)
+	}
+
+	// a signer that could not be loaded (wrong passphrase, unreachable remote signer) is a config error too
+	if err := TrySetup(); err != nil {
+		wtsc.Logger.Error().Err(err).Msg("failed to load signers")
+		printJson(wtsc.NewConfigErrorSummary(err))
+		os.Exit(wtsc.ExitCodeConfigError)
+	}
+
+	run := wtsc.DefaultApp.Evaluate()
+
+	// let the workers and the notifications finish before exit
+	wtsc.StopWorkers()
+	wtsc.WaitNotifications()
+
+	summary := wtsc.NewRunSummary(run)
+	printJson(summary)
+	os.Exit(summary.ExitCode)
+}
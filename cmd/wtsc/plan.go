@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/pokt-scan/wtsc/wtsc"
+	"os"
+	"text/tabwriter"
+	"time"
+)
+
+// PlanCmd calls what-to-stake and prints the plan. When the history is configured, a plan with changes is
+// persisted as pending, so it can be applied later with `wtsc apply <plan-id>`.
+//
+//	wtsc plan [-list] [-limit N]
+func PlanCmd(args []string) {
+	fs := flag.NewFlagSet("plan", flag.ExitOnError)
+	list := fs.Bool("list", false, "list the latest plans instead of create a new one")
+	limit := fs.Int("limit", 20, "max amount of plans to list (0 means all)")
+	_ = fs.Parse(args)
+
+	// keep stdout for the plan, so it could be written to a file for `wtsc export`
+	commandLogger()
+	Setup()
+
+	if *list {
+		listPlans(*limit)
+		return
+	}
+
+	plan := newPlan()
+
+	if wtsc.DefaultApp.History() != nil && len(plan.ChangedNodes()) > 0 {
+		if e := wtsc.DefaultApp.ProposePlan(plan, ""); e != nil {
+			wtsc.Logger.Fatal().Err(e).Msg("failed to save stake plan")
+		}
+	}
+
+	printJson(plan)
+}
+
+// newPlan calls what-to-stake and compares it with the on-chain state, like `wtsc plan`
+func newPlan() *wtsc.Plan {
+	cfg := wtsc.GetConfig()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.MaxTimeout)*time.Millisecond)
+	defer cancel()
+
+	resp, err := wtsc.DefaultApp.Wts.GetWhatToStake(ctx, wtsc.NewWtsInput(cfg))
+	if err != nil {
+		wtsc.Logger.Fatal().Err(err).Str("service", cfg.POKTscanApi).Msg("failed to call what to stake service")
+	}
+
+	planCtx, planCancel := context.WithTimeout(context.Background(), time.Duration(cfg.MaxTimeout)*time.Millisecond)
+	defer planCancel()
+
+	return wtsc.DefaultApp.BuildPlan(planCtx, resp)
+}
+
+// Apply approves a pending plan and submits its stake transactions.
+//
+//	wtsc apply <plan-id>
+func Apply(args []string) {
+	fs := flag.NewFlagSet("apply", flag.ExitOnError)
+	_ = fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		_, _ = fmt.Fprintln(os.Stderr, "usage: wtsc apply <plan-id>")
+		os.Exit(2)
+	}
+
+	Setup()
+
+	run, err := wtsc.DefaultApp.ApplyPlan(fs.Arg(0))
+	if err != nil {
+		wtsc.Logger.Fatal().Err(err).Str("plan_id", fs.Arg(0)).Msg("failed to apply plan")
+	}
+
+	// let the workers finish before exit
+	wtsc.StopWorkers()
+	wtsc.WaitNotifications()
+
+	printJson(run)
+}
+
+func listPlans(limit int) {
+	history := wtsc.DefaultApp.History()
+	if history == nil {
+		wtsc.Logger.Fatal().Msg("history_path is not configured")
+	}
+
+	plans, err := history.ListPlans(limit)
+	if err != nil {
+		wtsc.Logger.Fatal().Err(err).Msg("failed to list plans")
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "ID\tCREATED\tSTATUS\tEXPIRES\tCHANGES\tGAIN_CHANGE_%")
+	for _, plan := range plans {
+		status := plan.Status
+		if status == wtsc.PlanStatusPending && plan.IsExpired(time.Now()) {
+			status = wtsc.PlanStatusExpired
+		}
+		expires := "-"
+		if plan.ExpiresAt != nil {
+			expires = plan.ExpiresAt.Local().Format(time.DateTime)
+		}
+		_, _ = fmt.Fprintf(
+			w, "%s\t%s\t%s\t%s\t%d\t%.2f\n",
+			plan.ID,
+			plan.CreatedAt.Local().Format(time.DateTime),
+			status,
+			expires,
+			len(plan.ChangedNodes()),
+			plan.GainChangePercent,
+		)
+	}
+	_ = w.Flush()
+}
+
+func printJson(v any) {
+	bz, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		wtsc.Logger.Fatal().Err(err).Msg("failed to marshal output")
+	}
+
+	fmt.Println(string(bz))
+}
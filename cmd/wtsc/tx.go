@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bufio"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"github.com/pokt-scan/wtsc/wtsc"
+	"io"
+	"os"
+	"strings"
+)
+
+// Tx audits the stake txs encoding.
+//
+//	wtsc tx decode [-network-id testnet] <hex|->
+func Tx(args []string) {
+	if len(args) == 0 {
+		_, _ = fmt.Fprintln(os.Stderr, "usage: wtsc tx decode [-network-id id] <hex|->")
+		os.Exit(2)
+	}
+
+	switch args[0] {
+	case "decode":
+		TxDecode(args[1:])
+	default:
+		_, _ = fmt.Fprintf(os.Stderr, "unknown tx command %q\n", args[0])
+		os.Exit(2)
+	}
+}
+
+// TxDecode pretty-prints a raw stake tx (hex, `-` reads it from stdin), so what would be broadcast could be audited.
+// With -network-id the signature is verified too.
+func TxDecode(args []string) {
+	fs := flag.NewFlagSet("tx decode", flag.ExitOnError)
+	networkID := fs.String("network-id", "", "verify the signature for this network id (mainnet or testnet)")
+	_ = fs.Parse(args)
+
+	commandLogger()
+
+	if fs.NArg() != 1 {
+		_, _ = fmt.Fprintln(os.Stderr, "usage: wtsc tx decode [-network-id id] <hex|->")
+		os.Exit(2)
+	}
+
+	rawHex := fs.Arg(0)
+	if rawHex == "-" {
+		bz, err := io.ReadAll(bufio.NewReader(os.Stdin))
+		if err != nil {
+			wtsc.Logger.Fatal().Err(err).Msg("failed to read stdin")
+		}
+		rawHex = string(bz)
+	}
+
+	txBytes, err := hex.DecodeString(strings.TrimSpace(rawHex))
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "invalid hex: %s\n", err)
+		os.Exit(1)
+	}
+
+	decoded, err := wtsc.DecodeStakeTx(txBytes, *networkID)
+	if err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	printJson(decoded)
+	if decoded.SignatureValid != nil && !*decoded.SignatureValid {
+		os.Exit(1)
+	}
+}
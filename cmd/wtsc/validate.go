@@ -0,0 +1,45 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/pokt-scan/wtsc/wtsc"
+	"os"
+)
+
+// Validate checks the config file without starting wtsc and prints every problem found on it.
+// It exits with 1 when the config is invalid, warnings (like unknown keys) does not fail it.
+//
+//	wtsc validate [file]
+func Validate(args []string) {
+	fs := flag.NewFlagSet("validate", flag.ExitOnError)
+	_ = fs.Parse(args)
+
+	commandLogger()
+
+	// always resolve it, so PROJECT_ROOT is used for the relative paths of the config
+	configPath := wtsc.GetConfigFilePath()
+	if fs.NArg() > 0 {
+		configPath = fs.Arg(0)
+	}
+
+	report, err := wtsc.ValidateConfigFile(configPath)
+	if err != nil {
+		fmt.Printf("%s: %s\n", configPath, err)
+		os.Exit(1)
+	}
+
+	for _, warning := range report.Warnings {
+		fmt.Printf("warning: %s\n", warning)
+	}
+	for _, e := range report.Errors {
+		fmt.Printf("error: %s\n", e)
+	}
+
+	if !report.Valid() {
+		fmt.Printf("%s is invalid: %d errors, %d warnings\n", report.Path, len(report.Errors), len(report.Warnings))
+		os.Exit(1)
+	}
+
+	fmt.Printf("%s is valid (%d warnings)\n", report.Path, len(report.Warnings))
+}
@@ -3,17 +3,23 @@ module github.com/pokt-scan/wtsc
 go 1.21
 
 require (
+	github.com/BurntSushi/toml v1.2.0
 	github.com/Khan/genqlient v0.7.0
 	github.com/alitto/pond v1.9.1
+	github.com/fsnotify/fsnotify v1.4.9
 	github.com/hashicorp/go-cleanhttp v0.5.2
 	github.com/hashicorp/go-retryablehttp v0.7.7
 	github.com/pokt-foundation/pocket-go v0.21.0
 	github.com/pokt-network/pocket-core v0.0.0-20240814175146-7f936ff73532
+	github.com/prometheus/client_golang v1.11.0
+	github.com/prometheus/client_model v0.2.0
 	github.com/puzpuzpuz/xsync v1.5.2
 	github.com/robfig/cron/v3 v3.0.1
 	github.com/rs/zerolog v1.33.0
 	github.com/suessflorian/gqlfetch v0.6.0
 	github.com/tendermint/tendermint v0.33.7
+	go.etcd.io/bbolt v1.3.10
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 replace github.com/tendermint/tendermint => github.com/pokt-network/tendermint v0.32.11-0.20230426215212-59310158d3e9
@@ -55,8 +61,6 @@ require (
 	github.com/pkg/errors v0.9.1 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
 	github.com/pokt-foundation/utils-go v0.7.0 // indirect
-	github.com/prometheus/client_golang v1.11.0 // indirect
-	github.com/prometheus/client_model v0.2.0 // indirect
 	github.com/prometheus/common v0.30.0 // indirect
 	github.com/prometheus/procfs v0.7.3 // indirect
 	github.com/regen-network/cosmos-proto v0.3.0 // indirect
@@ -70,7 +74,6 @@ require (
 	github.com/vektah/gqlparser/v2 v2.5.11 // indirect
 	github.com/willf/bitset v1.1.10 // indirect
 	github.com/willf/bloom v2.0.3+incompatible // indirect
-	go.etcd.io/bbolt v1.3.3 // indirect
 	golang.org/x/crypto v0.19.0 // indirect
 	golang.org/x/exp v0.0.0-20230131160201-f062dba9d201 // indirect
 	golang.org/x/mod v0.15.0 // indirect
@@ -82,5 +85,4 @@ require (
 	google.golang.org/grpc v1.55.0 // indirect
 	google.golang.org/protobuf v1.30.0 // indirect
 	gopkg.in/yaml.v2 v2.4.0 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
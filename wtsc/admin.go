@@ -0,0 +1,179 @@
+package wtsc
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// NewAdminServer starts the optional admin api listener. Empty address disables it.
+func NewAdminServer(address, token string) {
+	if IsEmptyString(address) {
+		Logger.Info().Msg("admin api is disabled")
+		return
+	}
+
+	Logger.Info().Str("address", address).Msg("preparing admin api")
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/run", AdminRunHandler)
+	mux.HandleFunc("/pause", AdminPauseHandler)
+	mux.HandleFunc("/resume", AdminResumeHandler)
+	mux.HandleFunc("/dry-run", AdminDryRunHandler)
+	mux.HandleFunc("/plans", AdminPlansHandler)
+	mux.HandleFunc("/apply", AdminApplyHandler)
+
+	AdminServer = startServer(address, adminAuth(token, mux))
+}
+
+// StopAdminServer gracefully stops the admin api listener if it is running
+func StopAdminServer() {
+	stopServer(AdminServer)
+	AdminServer = nil
+}
+
+// adminAuth only allows POST requests with the admin token as bearer
+func adminAuth(token string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeJson(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+			return
+		}
+
+		// a bare token, or any other scheme, is not accepted
+		reqToken, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(reqToken), []byte(token)) != 1 {
+			Logger.Warn().Str("remote", r.RemoteAddr).Str("path", r.URL.Path).Msg("unauthorized admin api request")
+			writeJson(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// AdminRunHandler triggers an evaluation right now, without wait for the schedule
+func AdminRunHandler(w http.ResponseWriter, _ *http.Request) {
+	if DefaultApp.State.Running() {
+		writeJson(w, http.StatusConflict, map[string]string{"error": "an evaluation is already running"})
+		return
+	}
+
+	Logger.Info().Msg("evaluation triggered by admin api")
+	go evaluationJob()
+
+	writeJson(w, http.StatusAccepted, map[string]string{"status": "evaluation triggered"})
+}
+
+// AdminPauseHandler keeps the scheduled evaluations running but without submit stake transactions
+func AdminPauseHandler(w http.ResponseWriter, _ *http.Request) {
+	DefaultApp.State.SetPaused(true)
+	Logger.Warn().Msg("stake transactions paused by admin api")
+	writeJson(w, http.StatusOK, map[string]bool{"paused": true})
+}
+
+// AdminResumeHandler allows the evaluations to submit stake transactions again
+func AdminResumeHandler(w http.ResponseWriter, _ *http.Request) {
+	DefaultApp.State.SetPaused(false)
+	Logger.Warn().Msg("stake transactions resumed by admin api")
+	writeJson(w, http.StatusOK, map[string]bool{"paused": false})
+}
+
+// AdminDryRunHandler calls what-to-stake and returns the plan without touching chain state
+func AdminDryRunHandler(w http.ResponseWriter, r *http.Request) {
+	cfg := GetConfig()
+	ctx, cancel := context.WithTimeout(r.Context(), time.Duration(cfg.MaxTimeout)*time.Millisecond)
+	defer cancel()
+
+	resp, err := DefaultApp.Wts.GetWhatToStake(ctx, NewWtsInput(cfg))
+	if err != nil {
+		Logger.Error().Err(err).Str("service", cfg.POKTscanApi).Msg("failed to call what to stake service")
+		writeJson(w, http.StatusBadGateway, map[string]string{"error": err.Error()})
+		return
+	}
+
+	// reading nodes has its own time budget
+	planCtx, planCancel := context.WithTimeout(r.Context(), time.Duration(cfg.MaxTimeout)*time.Millisecond)
+	defer planCancel()
+
+	writeJson(w, http.StatusOK, DefaultApp.BuildPlan(planCtx, resp))
+}
+
+// AdminPlansHandler lists the latest plans, use ?status=pending to filter them
+func AdminPlansHandler(w http.ResponseWriter, r *http.Request) {
+	history := DefaultApp.History()
+	if history == nil {
+		writeJson(w, http.StatusNotFound, map[string]string{"error": ErrHistoryDisabled.Error()})
+		return
+	}
+
+	plans, err := history.ListPlans(100)
+	if err != nil {
+		Logger.Error().Err(err).Msg("failed to list plans")
+		writeJson(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+
+	status := PlanStatus(r.URL.Query().Get("status"))
+	filtered := make([]*Plan, 0, len(plans))
+	for _, plan := range plans {
+		if plan.Status == PlanStatusPending && plan.IsExpired(time.Now()) {
+			plan.Status = PlanStatusExpired
+		}
+		if status == "" || plan.Status == status {
+			filtered = append(filtered, plan)
+		}
+	}
+
+	writeJson(w, http.StatusOK, filtered)
+}
+
+// AdminApplyHandler approves the plan in ?id= and submits its stake txs in background
+func AdminApplyHandler(w http.ResponseWriter, r *http.Request) {
+	id := r.URL.Query().Get("id")
+	if IsEmptyString(id) {
+		writeJson(w, http.StatusBadRequest, map[string]string{"error": "missing plan id"})
+		return
+	}
+
+	history := DefaultApp.History()
+	if history == nil {
+		writeJson(w, http.StatusNotFound, map[string]string{"error": ErrHistoryDisabled.Error()})
+		return
+	}
+
+	// check it before answer, so the caller knows the plan will be applied
+	plan, err := history.GetPlan(id)
+	if errors.Is(err, ErrRecordNotFound) {
+		writeJson(w, http.StatusNotFound, map[string]string{"error": "plan not found"})
+		return
+	}
+	if err != nil {
+		writeJson(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	if plan.Status != PlanStatusPending || plan.IsExpired(time.Now()) {
+		writeJson(w, http.StatusConflict, map[string]string{"error": ErrPlanNotPending.Error()})
+		return
+	}
+	if GetConfig().DryMode {
+		writeJson(w, http.StatusConflict, map[string]string{"error": ErrDryMode.Error()})
+		return
+	}
+	if DefaultApp.State.Running() {
+		writeJson(w, http.StatusConflict, map[string]string{"error": ErrEvaluationRunning.Error()})
+		return
+	}
+
+	Logger.Info().Str("plan_id", id).Msg("plan approved by admin api")
+	go func() {
+		if _, e := DefaultApp.ApplyPlan(id); e != nil {
+			Logger.Error().Err(e).Str("plan_id", id).Msg("failed to apply plan")
+		}
+	}()
+
+	writeJson(w, http.StatusAccepted, map[string]string{"status": "plan approved"})
+}
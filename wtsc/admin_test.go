@@ -0,0 +1,42 @@
+package wtsc
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAdminAuth(t *testing.T) {
+	const token = "0123456789abcdef"
+	handler := adminAuth(token, http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	cases := []struct {
+		name          string
+		method        string
+		authorization string
+		code          int
+	}{
+		{name: "bearer", method: http.MethodPost, authorization: "Bearer " + token, code: http.StatusNoContent},
+		{name: "bare token", method: http.MethodPost, authorization: token, code: http.StatusUnauthorized},
+		{name: "other scheme", method: http.MethodPost, authorization: "Basic " + token, code: http.StatusUnauthorized},
+		{name: "wrong token", method: http.MethodPost, authorization: "Bearer " + token + "0", code: http.StatusUnauthorized},
+		{name: "missing", method: http.MethodPost, code: http.StatusUnauthorized},
+		{name: "get", method: http.MethodGet, authorization: "Bearer " + token, code: http.StatusMethodNotAllowed},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			req := httptest.NewRequest(c.method, "/run", nil)
+			if c.authorization != "" {
+				req.Header.Set("Authorization", c.authorization)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+			if w.Code != c.code {
+				t.Fatalf("got %d, expected %d", w.Code, c.code)
+			}
+		})
+	}
+}
@@ -1,18 +1,369 @@
 package wtsc
 
-import "os"
+import (
+	"context"
+	"github.com/Khan/genqlient/graphql"
+	"github.com/alitto/pond"
+	"github.com/hashicorp/go-retryablehttp"
+	pocketGoProvider "github.com/pokt-foundation/pocket-go/provider"
+	"github.com/pokt-scan/wtsc/wtsc/generated"
+	"github.com/puzpuzpuz/xsync"
+	"github.com/rs/zerolog"
+	"math/big"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// WtsClient calls the what-to-stake service
+type WtsClient interface {
+	GetWhatToStake(ctx context.Context, input generated.WtsProcessRequestInput) (*generated.GetWhatToStakeResponse, error)
+}
+
+// PocketRpc is the subset of the pocket rpc used to read the nodes and to submit and track the stake txs.
+// *pocketGoProvider.Provider implements it.
+type PocketRpc interface {
+	GetNodeWithCtx(ctx context.Context, address string, options *pocketGoProvider.GetNodeOptions) (*pocketGoProvider.GetNodeOutput, error)
+	GetTransactionWithCtx(ctx context.Context, hash string, options *pocketGoProvider.GetTransactionOptions) (*pocketGoProvider.GetTransactionOutput, error)
+	GetBlockHeightWithCtx(ctx context.Context) (int, error)
+	GetBalanceWithCtx(ctx context.Context, address string, options *pocketGoProvider.GetBalanceOptions) (*big.Int, error)
+	SendTransactionWithCtx(ctx context.Context, input *pocketGoProvider.SendTransactionInput) (*pocketGoProvider.SendTransactionOutput, error)
+}
+
+// SignerStore returns the signer of a servicer address. *xsync.MapOf[string, Signer] implements it.
+type SignerStore interface {
+	Load(address string) (Signer, bool)
+	Size() int
+}
+
+// Clock is the source of time of runs and plans
+type Clock interface {
+	Now() time.Time
+}
+
+// Workers runs the servicer tasks in parallel. *pond.WorkerPool implements it.
+type Workers interface {
+	Group() *pond.TaskGroup
+}
+
+// App owns the dependencies of an evaluation, so wtsc could be embedded as a library and tested without network.
+// The config is a function because it could be replaced while the app is running (hot reload), each operation
+// takes a single snapshot at start.
+type App struct {
+	Config  func() *Config
+	Logger  *zerolog.Logger
+	Wts     WtsClient
+	Rpc     PocketRpc
+	Signers SignerStore
+	Clock   Clock
+	Workers Workers
+	// Entropy is the nonce of the stake txs
+	Entropy EntropySource
+	// State is shared by the copies of the app, so its runs never overlap
+	State *RunState
+	// History returns the store of runs and plans, nil when the history is disabled
+	History func() *HistoryStore
+	// Notify delivers a notification to the channels of cfg
+	Notify func(cfg *Config, n *Notification)
+	// Observe updates the metrics with a finished run
+	Observe func(run *RunRecord)
+	// Runtime returns the dependencies to use instead of the ones of the app and a func to release them, see pin.
+	// It is nil when the app owns its dependencies.
+	Runtime func() (*Runtime, func())
+}
+
+// RunState is the state of the runs of an app: a single one (evaluation, plan apply or broadcast) runs at a time,
+// stake txs could be paused and the last run is kept for the status.
+type RunState struct {
+	running atomic.Bool
+	paused  atomic.Bool
+	last    atomic.Pointer[RunRecord]
+}
+
+// TryStart marks a run as started, it returns false when another one is running. Call Done once it finishes.
+func (s *RunState) TryStart() bool {
+	return s.running.CompareAndSwap(false, true)
+}
+
+func (s *RunState) Done() {
+	s.running.Store(false)
+}
+
+func (s *RunState) Running() bool {
+	return s.running.Load()
+}
+
+func (s *RunState) Paused() bool {
+	return s.paused.Load()
+}
+
+// SetPaused keeps the evaluations running but without submit stake txs
+func (s *RunState) SetPaused(paused bool) {
+	s.paused.Store(paused)
+}
+
+// LastRun returns the last finished run, nil before the first one
+func (s *RunState) LastRun() *RunRecord {
+	return s.last.Load()
+}
+
+// DefaultApp is wired to the process runtime, which is replaced by ReloadConfig. Its runs pin the runtime in use
+// when they start, so a reload never changes the dependencies of a running one.
+var DefaultApp = &App{
+	Config:  GetConfig,
+	Logger:  &Logger,
+	Wts:     currentWts{},
+	Rpc:     currentPocketRpc{},
+	Signers: currentSigners{},
+	Clock:   SystemClock{},
+	Workers: currentWorkers{},
+	Entropy: RandomEntropy,
+	State:   &RunState{},
+	History: currentHistory,
+	Notify:  NotifyWith,
+	Observe: ObserveRun,
+	Runtime: AcquireRuntime,
+}
+
+// pin returns a copy of the app that uses the same config snapshot and dependencies during a whole run, even if
+// they are reloaded meanwhile. Call release once the run (including the tracking of its txs) is done.
+func (app *App) pin() (*App, func()) {
+	p := *app
+	release := func() {}
+	var cfg *Config
+	if app.Runtime != nil {
+		if rt, rel := app.Runtime(); rt != nil {
+			// the config the dependencies were built for, published with them
+			cfg = rt.Config
+			p.Wts = rt.Wts
+			p.Rpc = rt.Rpc
+			p.Signers = rt.Signers
+			p.Workers = rt.Workers
+			history := rt.History
+			p.History = func() *HistoryStore { return history }
+			release = rel
+		}
+		// the copy is pinned already, its operations must not pin again
+		p.Runtime = nil
+	}
+	if cfg == nil {
+		cfg = app.Config()
+	}
+	p.Config = func() *Config { return cfg }
+	if app.Logger != nil {
+		logger := *app.Logger
+		p.Logger = &logger
+	}
+	return &p, release
+}
+
+// Runtime holds the config snapshot and the process dependencies built from it, the ones that a reload could
+// replace. A published runtime is never modified: a reload publishes a new one, so a run that keeps it never sees
+// the config or a dependency change.
+type Runtime struct {
+	Config     *Config
+	HttpClient *retryablehttp.Client
+	Wts        WtsClient
+	Rpc        PocketRpc
+	Signers    *xsync.MapOf[string, Signer]
+	Workers    *pond.WorkerPool
+	// History is nil when the history is disabled
+	History *HistoryStore
+	// pool counts the users of Workers, it is shared by the runtimes with the same pool
+	pool *poolUsers
+}
+
+var currentRuntime atomic.Pointer[Runtime]
+
+// GetRuntime returns the runtime in use, nil before Init and without dependencies before Setup. Runs use
+// AcquireRuntime instead.
+func GetRuntime() *Runtime {
+	return currentRuntime.Load()
+}
+
+// SetRuntime publishes rt and its config in one swap, a new config gets its version and hash. When its worker pool
+// is not the one in use, the old pool is stopped once the runs that use it are done. It is called on setup and by
+// ReloadConfig, never concurrently.
+func SetRuntime(rt *Runtime) {
+	old := currentRuntime.Load()
+	if rt.Config != nil && (old == nil || old.Config != rt.Config) {
+		stampConfig(rt.Config)
+	}
+	if old != nil && old.Workers == rt.Workers {
+		rt.pool = old.pool
+	} else {
+		rt.pool = &poolUsers{pool: rt.Workers}
+	}
+
+	currentRuntime.Store(rt)
+	if old != nil && old.pool != rt.pool {
+		old.pool.retire()
+	}
+}
+
+// AcquireRuntime returns the runtime in use and keeps its worker pool running until release is called, even if a
+// reload replaces it meanwhile
+func AcquireRuntime() (*Runtime, func()) {
+	for {
+		rt := currentRuntime.Load()
+		if rt == nil {
+			return nil, func() {}
+		}
+		if rt.pool.acquire() {
+			var once sync.Once
+			return rt, func() { once.Do(rt.pool.release) }
+		}
+		// its pool was replaced meanwhile, the new runtime is published already
+	}
+}
+
+// StopWorkers stops the worker pool in use and waits for its tasks
+func StopWorkers() {
+	if rt := GetRuntime(); rt != nil && rt.Workers != nil {
+		rt.Workers.StopAndWait()
+	}
+}
+
+// poolUsers counts the users of a worker pool, once it is replaced (retired) it is stopped when the last one is done
+type poolUsers struct {
+	pool    *pond.WorkerPool
+	mu      sync.Mutex
+	users   int
+	retired bool
+}
+
+func (pu *poolUsers) acquire() bool {
+	pu.mu.Lock()
+	defer pu.mu.Unlock()
+	if pu.retired {
+		return false
+	}
+	pu.users++
+	return true
+}
+
+func (pu *poolUsers) release() {
+	pu.mu.Lock()
+	pu.users--
+	stop := pu.retired && pu.users == 0
+	pu.mu.Unlock()
+	if stop {
+		pu.stop()
+	}
+}
+
+func (pu *poolUsers) retire() {
+	pu.mu.Lock()
+	pu.retired = true
+	stop := pu.users == 0
+	pu.mu.Unlock()
+	if stop {
+		pu.stop()
+	}
+}
+
+func (pu *poolUsers) stop() {
+	if pu.pool == nil {
+		return
+	}
+	Logger.Debug().Msg("stopping the replaced worker pool")
+	pu.pool.StopAndWait()
+}
+
+// POKTscanWtsClient calls what-to-stake with a POKTscan api client
+type POKTscanWtsClient struct {
+	Client graphql.Client
+}
+
+func (c POKTscanWtsClient) GetWhatToStake(ctx context.Context, input generated.WtsProcessRequestInput) (*generated.GetWhatToStakeResponse, error) {
+	return generated.GetWhatToStake(ctx, c.Client, input)
+}
+
+type SystemClock struct{}
+
+func (SystemClock) Now() time.Time {
+	return time.Now()
+}
+
+// currentWts delegates to the POKTscan client of the runtime in use
+type currentWts struct{}
+
+func (currentWts) GetWhatToStake(ctx context.Context, input generated.WtsProcessRequestInput) (*generated.GetWhatToStakeResponse, error) {
+	return GetRuntime().Wts.GetWhatToStake(ctx, input)
+}
+
+// currentPocketRpc delegates to the pocket rpc of the runtime in use
+type currentPocketRpc struct{}
+
+func (currentPocketRpc) GetNodeWithCtx(ctx context.Context, address string, options *pocketGoProvider.GetNodeOptions) (*pocketGoProvider.GetNodeOutput, error) {
+	return GetRuntime().Rpc.GetNodeWithCtx(ctx, address, options)
+}
+
+func (currentPocketRpc) GetTransactionWithCtx(ctx context.Context, hash string, options *pocketGoProvider.GetTransactionOptions) (*pocketGoProvider.GetTransactionOutput, error) {
+	return GetRuntime().Rpc.GetTransactionWithCtx(ctx, hash, options)
+}
+
+func (currentPocketRpc) GetBlockHeightWithCtx(ctx context.Context) (int, error) {
+	return GetRuntime().Rpc.GetBlockHeightWithCtx(ctx)
+}
+
+func (currentPocketRpc) GetBalanceWithCtx(ctx context.Context, address string, options *pocketGoProvider.GetBalanceOptions) (*big.Int, error) {
+	return GetRuntime().Rpc.GetBalanceWithCtx(ctx, address, options)
+}
+
+func (currentPocketRpc) SendTransactionWithCtx(ctx context.Context, input *pocketGoProvider.SendTransactionInput) (*pocketGoProvider.SendTransactionOutput, error) {
+	return GetRuntime().Rpc.SendTransactionWithCtx(ctx, input)
+}
+
+// currentSigners delegates to the signers of the runtime in use
+type currentSigners struct{}
+
+func (currentSigners) Load(address string) (Signer, bool) {
+	return GetRuntime().Signers.Load(address)
+}
+
+func (currentSigners) Size() int {
+	return GetRuntime().Signers.Size()
+}
+
+// currentHistory returns the history of the runtime in use, nil when it is disabled
+func currentHistory() *HistoryStore {
+	if rt := GetRuntime(); rt != nil {
+		return rt.History
+	}
+	return nil
+}
+
+// notify sends the notification with the channels of the app config
+func (app *App) notify(n *Notification) {
+	app.Notify(app.Config(), n)
+}
+
+// currentWorkers delegates to the worker pool of the runtime in use. Runs pin the pool instead, since a replaced
+// one is stopped.
+type currentWorkers struct{}
+
+func (currentWorkers) Group() *pond.TaskGroup {
+	return GetRuntime().Workers.Group()
+}
+
+// GetVersion returns the version of the VERSION env var, which is set on the docker image
+func GetVersion() string {
+	if version := os.Getenv("VERSION"); !IsEmptyString(version) {
+		return version
+	}
+	return "0.0.0"
+}
 
 func Init() {
 	// define default logger to use before load config and override it
 	Logger = GetDefaultLogger()
 
-	version := os.Getenv("VERSION")
-	if version == "" {
-		version = "0.0.0"
-	}
-	Logger.Info().Str("version", version).Msg("initializing wtsc")
+	Version = GetVersion()
+	Logger.Info().Str("version", Version).Msg("initializing wtsc")
 
 	cfg := LoadConfig()
 
-	AppConfig = cfg
+	SetConfig(cfg)
 }
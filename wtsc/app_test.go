@@ -0,0 +1,97 @@
+package wtsc
+
+import (
+	"context"
+	"github.com/alitto/pond"
+	"github.com/pokt-scan/wtsc/wtsc/generated"
+	"testing"
+	"time"
+)
+
+// blockingWts answers what-to-stake with resp, but only once release is closed. called is closed on the first call.
+type blockingWts struct {
+	resp    *generated.GetWhatToStakeResponse
+	called  chan struct{}
+	release chan struct{}
+}
+
+func (b *blockingWts) GetWhatToStake(_ context.Context, _ generated.WtsProcessRequestInput) (*generated.GetWhatToStakeResponse, error) {
+	close(b.called)
+	<-b.release
+	return b.resp, nil
+}
+
+// a reload during an evaluation must not change the dependencies of the run, run it with -race
+func TestReloadDuringEvaluateKeepsPinnedRuntime(t *testing.T) {
+	configPath, cfg := useTestConfigFile(t, "http://127.0.0.1:1", "old.db", 1, testPrivateKey("old"))
+
+	oldSigner := newTestSigner(t, "old")
+	rpc := newFakeRpc()
+	rpc.addNode(oldSigner, []string{"0001"})
+	oldHistory := NewHistoryStore(cfg.HistoryPath)
+	workers := pond.New(1, 0)
+	wts := &blockingWts{
+		resp: &generated.GetWhatToStakeResponse{GetWhatToStake: generated.GetWhatToStakeGetWhatToStakeWtsOptimizationResponse{
+			Do_update: true,
+			Servicers: []generated.GetWhatToStakeGetWhatToStakeWtsOptimizationResponseServicersWtsStakeNode{
+				{Address: oldSigner.GetAddress(), Services: []string{"0021"}},
+			},
+		}},
+		called:  make(chan struct{}),
+		release: make(chan struct{}),
+	}
+	SetRuntime(&Runtime{Config: cfg, Wts: wts, Rpc: rpc, Signers: newTestSigners(oldSigner), Workers: workers, History: oldHistory})
+
+	app := *DefaultApp
+	app.State = &RunState{}
+	app.Notify = (&notificationRecorder{}).Notify
+	app.Observe = func(*RunRecord) {}
+
+	done := make(chan *RunRecord)
+	go func() { done <- app.Evaluate() }()
+	<-wts.called
+
+	// every dependency of the runtime changes while the run waits for what-to-stake
+	writeTestConfig(t, configPath, "http://127.0.0.2:1", "new.db", 2, testPrivateKey("new"))
+	if err := ReloadConfig(); err != nil {
+		t.Fatal(err)
+	}
+	if workers.Stopped() {
+		t.Fatal("the worker pool of the running evaluation was stopped")
+	}
+	close(wts.release)
+
+	var run *RunRecord
+	select {
+	case run = <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("evaluation did not finish")
+	}
+
+	if run.Outcome != RunOutcomeCompleted || run.ConfigVersion != cfg.Version {
+		t.Fatalf("got %s with config %d, expected %s with config %d", run.Outcome, run.ConfigVersion, RunOutcomeCompleted, cfg.Version)
+	}
+	if len(run.Results) != 1 || run.Results[0].Status != TxStatusSubmitted {
+		t.Fatalf("unexpected results %+v", run.Results)
+	}
+	if rpc.sentTxs() != 1 {
+		t.Fatalf("sent %d txs through the rpc of the run, expected 1", rpc.sentTxs())
+	}
+	if _, err := oldHistory.GetRun(run.ID); err != nil {
+		t.Fatalf("run not saved on the history of the run: %s", err)
+	}
+	if !workers.Stopped() {
+		t.Fatal("the replaced worker pool was not stopped after the run")
+	}
+
+	rt := GetRuntime()
+	if rt.Workers == workers || rt.Rpc == PocketRpc(rpc) || rt.History == oldHistory {
+		t.Fatal("the reload did not replace the runtime")
+	}
+	if _, ok := rt.Signers.Load(newTestSigner(t, "new").GetAddress()); !ok || rt.Signers.Size() != 1 {
+		t.Fatal("the reload did not replace the signers")
+	}
+	if GetConfig().MaxWorkers != 2 {
+		t.Fatalf("got %d max workers, expected the reloaded 2", GetConfig().MaxWorkers)
+	}
+}
@@ -0,0 +1,52 @@
+package wtsc
+
+import (
+	"context"
+	"fmt"
+	pocketGoProvider "github.com/pokt-foundation/pocket-go/provider"
+	"math/big"
+)
+
+// checkBalance is the pre-flight of a stake tx, the signer account pays the tx_fee. It returns false (and updates
+// the result) when the fee can not be paid, and alerts when the balance left after this tx could pay less than
+// lowBalanceRestakes restakes.
+func (app *App) checkBalance(ctx context.Context, address string, txFee int64, lowBalanceRestakes uint, result *ServicerResult) bool {
+	balance, err := app.Rpc.GetBalanceWithCtx(ctx, address, &pocketGoProvider.GetBalanceOptions{Height: 0})
+	if err != nil {
+		app.Logger.Error().Err(err).Str("address", address).Msg("failed to get account balance")
+		result.Error = err.Error()
+		return false
+	}
+
+	balanceFloat, _ := new(big.Float).SetInt(balance).Float64()
+	servicerBalanceMetric.WithLabelValues(address).Set(balanceFloat)
+
+	fee := big.NewInt(txFee)
+	if balance.Cmp(fee) < 0 {
+		app.Logger.Error().Str("address", address).Str("balance", balance.String()).Int64("tx_fee", txFee).Msg("account can not pay the tx fee, skipping stake")
+		result.Status = TxStatusInsufficientFunds
+		result.Error = fmt.Sprintf("balance of %s upokt can not pay tx_fee of %d upokt", balance, txFee)
+		return false
+	}
+
+	if lowBalanceRestakes == 0 || txFee <= 0 {
+		return true
+	}
+
+	left := new(big.Int).Sub(balance, fee)
+	restakesLeft := new(big.Int).Div(left, fee)
+	if restakesLeft.Cmp(big.NewInt(int64(lowBalanceRestakes))) < 0 {
+		app.Logger.Warn().
+			Str("address", address).
+			Str("balance", balance.String()).
+			Str("restakes_left", restakesLeft.String()).
+			Msg("account balance is running low")
+		n := NewNotification(EventLowBalance, fmt.Sprintf("balance of %s could pay %s more restakes", address, restakesLeft))
+		n.Fields["address"] = address
+		n.Fields["balance"] = balance.String()
+		n.Fields["restakes_left"] = restakesLeft.String()
+		app.notify(n)
+	}
+
+	return true
+}
@@ -0,0 +1,78 @@
+package wtsc
+
+import (
+	"errors"
+	"github.com/pokt-scan/wtsc/wtsc/generated"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"reflect"
+	"testing"
+)
+
+func TestStakeServicerChecksBalance(t *testing.T) {
+	cases := []struct {
+		name               string
+		balance            int64
+		balanceErr         error
+		lowBalanceRestakes uint
+		status             TxStatus
+		events             []NotificationEvent
+	}{
+		{name: "enough balance", balance: 1000000, lowBalanceRestakes: 3, status: TxStatusSubmitted},
+		{name: "no alert configured", balance: 10000, status: TxStatusSubmitted},
+		{name: "exact fee", balance: 10000, lowBalanceRestakes: 1, status: TxStatusSubmitted, events: []NotificationEvent{EventLowBalance}},
+		{name: "low balance", balance: 39999, lowBalanceRestakes: 3, status: TxStatusSubmitted, events: []NotificationEvent{EventLowBalance}},
+		{name: "restakes left as configured", balance: 40000, lowBalanceRestakes: 3, status: TxStatusSubmitted},
+		{name: "insufficient funds", balance: 9999, lowBalanceRestakes: 3, status: TxStatusInsufficientFunds, events: []NotificationEvent{EventTxFailed}},
+		{name: "balance not available", balanceErr: errors.New("rpc down"), lowBalanceRestakes: 3, status: TxStatusFailed, events: []NotificationEvent{EventTxFailed}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			signer := newTestSigner(t, "0")
+			rpc := newFakeRpc()
+			rpc.addNode(signer, []string{"0001"})
+			rpc.balance = c.balance
+			rpc.balanceErr = c.balanceErr
+			cfg := &Config{NetworkID: "testnet", TxFee: "10000", MaxWorkers: 1, LowBalanceRestakes: c.lowBalanceRestakes}
+			app := newTestApp(t, cfg, rpc, newTestSigners(signer))
+			notifications := &notificationRecorder{}
+			app.Notify = notifications.Notify
+
+			results := app.submitStakes([]generated.GetWhatToStakeGetWhatToStakeWtsOptimizationResponseServicersWtsStakeNode{
+				{Address: signer.GetAddress(), Services: []string{"0021"}},
+			})
+			if len(results) != 1 || results[0].Status != c.status {
+				t.Fatalf("got %+v, expected %s", results, c.status)
+			}
+			if c.status != TxStatusSubmitted && results[0].Error == "" {
+				t.Fatal("the result does not tell why the stake was skipped")
+			}
+
+			sent := 0
+			if c.status == TxStatusSubmitted {
+				sent = 1
+			}
+			if rpc.sentTxs() != sent {
+				t.Fatalf("sent %d txs, expected %d", rpc.sentTxs(), sent)
+			}
+			if events := notifications.Events(); !reflect.DeepEqual(events, c.events) {
+				t.Fatalf("got %v notifications, expected %v", events, c.events)
+			}
+			if c.balanceErr == nil {
+				if balance := testutil.ToFloat64(servicerBalanceMetric.WithLabelValues(signer.GetAddress())); balance != float64(c.balance) {
+					t.Fatalf("got a balance metric of %f, expected %d", balance, c.balance)
+				}
+			}
+		})
+	}
+}
+
+func TestInsufficientFundsFailsTheRun(t *testing.T) {
+	summary := NewRunSummary(&RunRecord{Outcome: RunOutcomeCompleted, Results: []*ServicerResult{
+		{Address: "a", Status: TxStatusSubmitted},
+		{Address: "b", Status: TxStatusInsufficientFunds},
+	}})
+	if summary.ExitCode == ExitCodeOK {
+		t.Fatal("a stake without funds did not fail the run")
+	}
+}
@@ -1,12 +1,17 @@
 package wtsc
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
-	"github.com/robfig/cron/v3"
-	"github.com/rs/zerolog"
-	"io"
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
 )
 
 type UpdateKeys struct {
@@ -16,6 +21,11 @@ type UpdateKeys struct {
 var (
 	ProjectRoot    string
 	ConfigFilePath string
+
+	configVersion atomic.Uint64
+	reloadMu      sync.Mutex
+	// configFilePathOverride is set by SetConfigFilePath
+	configFilePathOverride string
 )
 
 func (up *UpdateKeys) Add(key string) {
@@ -33,9 +43,10 @@ func (up *UpdateKeys) Values() []string {
 	return up.s
 }
 
-// GetConfigFilePath returns the file path of the config.json file in the current working directory.
+// GetConfigFilePath returns the file path of the config file in the current working directory.
 // If there is an error while getting the working directory, it returns an empty string.
-// The file path is created by joining the working directory path and the file name "config.json".
+// The file path is created by joining the working directory path (or PROJECT_ROOT) and the file name of CONFIG_FILE,
+// which defaults to "config.json" (or "config.yaml", "config.yml", "config.toml" when there is no json one).
 func GetConfigFilePath() string {
 	// Check if the environment variable is set
 	ProjectRoot = os.Getenv("PROJECT_ROOT")
@@ -48,9 +59,14 @@ func GetConfigFilePath() string {
 		}
 	}
 
+	if !IsEmptyString(configFilePathOverride) {
+		ConfigFilePath = configFilePathOverride
+		return ConfigFilePath
+	}
+
 	fileName := os.Getenv("CONFIG_FILE")
 	if IsEmptyString(fileName) {
-		fileName = "config.json"
+		fileName = defaultConfigFileName(ProjectRoot)
 	}
 
 	// Create a path to the file in the working directory
@@ -59,137 +75,155 @@ func GetConfigFilePath() string {
 	return ConfigFilePath
 }
 
-// ValidateConfig validates the provided configuration struct.
-func ValidateConfig(cfg *Config) (valid bool, errors []string) {
-	Logger.Info().Msg("validating config file")
-
-	if !IsValidHttpURI(cfg.POKTscanApi) {
-		errors = append(errors, "poktscan_api")
-	}
-
-	if IsEmptyString(cfg.POKTscanApiToken) {
-		errors = append(errors, "poktscan_api_token")
+// SetConfigFilePath sets the config file (i.e. from the --config flag), it takes precedence over CONFIG_FILE.
+// PROJECT_ROOT (or the working directory) is still used to resolve the relative paths inside the config.
+func SetConfigFilePath(path string) {
+	if absPath, err := filepath.Abs(path); err == nil {
+		path = absPath
 	}
+	configFilePathOverride = path
+}
 
-	if cfg.NetworkID != "mainnet" && cfg.NetworkID != "testnet" {
-		errors = append(errors, "network_id")
+// defaultConfigFileName returns config.json, unless it does not exist and there is a yaml or toml one
+func defaultConfigFileName(dir string) string {
+	for _, fileName := range []string{"config.json", "config.yaml", "config.yml", "config.toml"} {
+		if _, err := os.Stat(filepath.Join(dir, fileName)); err == nil {
+			return fileName
+		}
 	}
+	return "config.json"
+}
 
-	if fee, err := cfg.TxFee.Int64(); err != nil || fee <= 0 {
-		errors = append(errors, "tx_fee")
-	}
+// ValidateConfig validates the provided configuration struct, errors are the keys of the invalid values.
+// Use CheckConfig to know what is wrong with each one.
+func ValidateConfig(cfg *Config) (valid bool, errors []string) {
+	Logger.Info().Msg("validating config file")
 
-	if !IsValidDomain(cfg.Domain) {
-		errors = append(errors, "domain")
+	for _, err := range CheckConfig(cfg) {
+		errors = append(errors, err.Key)
 	}
 
-	if !IsValidChainPool(cfg.ServicePool) {
-		errors = append(errors, "service_pool")
-	}
+	valid = len(errors) == 0
 
-	if !IsValidServicerList(cfg.ServicerKeys, cfg.DryMode) {
-		errors = append(errors, "servicer_keys")
-	}
+	return
+}
 
-	if cfg.StakeWeight < 1 || cfg.StakeWeight > 4 {
-		errors = append(errors, "stake_weight")
+// GetConfig returns the current config snapshot. It must be treated as read-only, keep the same reference during
+// a whole operation to avoid mixing two config versions.
+func GetConfig() *Config {
+	if rt := GetRuntime(); rt != nil {
+		return rt.Config
 	}
+	return nil
+}
 
-	if cfg.MinIncreasePercent <= 1 && cfg.MinIncreasePercent > 100 {
-		errors = append(errors, "min_increase_percent")
+// SetConfig publishes a new config snapshot with the dependencies in use
+func SetConfig(cfg *Config) {
+	next := Runtime{}
+	if rt := GetRuntime(); rt != nil {
+		next = *rt
 	}
+	next.Config = cfg
+	SetRuntime(&next)
+}
 
-	if !IsValidMinServiceStake(cfg.MinServiceStake) {
-		errors = append(errors, "min_service_stake")
-	}
+// stampConfig assigns the version and hash of a new config snapshot
+func stampConfig(cfg *Config) {
+	cfg.Version = configVersion.Add(1)
+	cfg.Hash = HashConfig(cfg)
+	Logger.Debug().Uint64("config_version", cfg.Version).Str("config_hash", cfg.Hash).Msg("config snapshot updated")
+}
 
-	if cfg.TimePeriod < 6 || cfg.TimePeriod > 48 {
-		errors = append(errors, "time_period")
+// HashConfig returns the sha256 of the config values, it identifies a config no matter the process that loads it
+func HashConfig(cfg *Config) string {
+	bz, err := json.Marshal(cfg)
+	if err != nil {
+		// a config that was unmarshalled is always able to be marshalled
+		Logger.Error().Err(err).Msg("failed to marshal config")
+		return ""
 	}
+	sum := sha256.Sum256(bz)
+	return hex.EncodeToString(sum[:])
+}
 
-	if !IsEmptyString(cfg.ResultsPath) && !IsWritableDirectory(filepath.Join(ProjectRoot, cfg.ResultsPath)) {
-		// empty string disable the feature so it's ok been empty
-		errors = append(errors, "results_path")
-	}
+// ErrInvalidConfig is returned when the config file has values that do not pass the validation
+var ErrInvalidConfig = errors.New("config file contains errors")
 
-	if _, err := zerolog.ParseLevel(cfg.LogLevel); err != nil {
-		errors = append(errors, "log_level")
-	}
+// ReadConfig reads and validates the config file. Unlike LoadConfig, it never stops the process.
+func ReadConfig(configPath string) (*Config, error) {
+	cfg := Config{}
+	Logger.Info().Str("path", configPath).Msg("reading config file")
 
-	if cfg.LogFormat != LogTextFormat && cfg.LogFormat != LogJsonFormat {
-		errors = append(errors, "log_format")
+	bz, err := os.ReadFile(configPath)
+	if err != nil {
+		return nil, err
 	}
 
-	if _, err := cron.ParseStandard(cfg.Schedule); err != nil {
-		errors = append(errors, "schedule")
+	format := ConfigFormat(configPath)
+	if err = DecodeConfig(bz, format, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal %s config file: %w", format, err)
 	}
 
-	if cfg.MaxWorkers <= 0 {
-		errors = append(errors, "max_workers")
+	// typos are ignored by the decoder, so at least let the user know
+	if unknown, e := UnknownConfigKeys(bz, format); e == nil && len(unknown) > 0 {
+		Logger.Warn().Strs("keys", unknown).Msg("config file has unknown keys, they are ignored")
 	}
 
-	if !IsValidHttpURI(cfg.PocketRPC) {
-		errors = append(errors, "pocket_rpc")
+	// env vars are applied on top of the file, so secrets could come from the environment
+	overridden, err := ApplyEnvOverrides(&cfg)
+	if err != nil {
+		return nil, err
 	}
-
-	if cfg.MaxRetries < 0 {
-		errors = append(errors, "max_retries")
+	if len(overridden) > 0 {
+		Logger.Info().Strs("keys", overridden).Msg("config keys overridden by env vars")
 	}
 
-	// less than 1s is not allowed
-	if cfg.MaxTimeout < 1000 {
-		errors = append(errors, "max_retries")
+	Logger.Info().Msg("validating config file")
+	if errs := CheckConfig(&cfg); len(errs) > 0 {
+		messages := make([]string, 0, len(errs))
+		for _, e := range errs {
+			messages = append(messages, e.Error())
+		}
+		return nil, fmt.Errorf("%w: %s", ErrInvalidConfig, strings.Join(messages, "; "))
 	}
 
-	valid = len(errors) == 0
-
-	return
+	return &cfg, nil
 }
 
 // LoadConfig loads and returns the configuration by reading the `configPath` file.
+// It is used at startup, so any error stops the process.
 func LoadConfig() *Config {
-	cfg := Config{}
-	var configPath = GetConfigFilePath()
-	Logger.Info().Str("path", configPath).Msg("reading config file")
-	var jsonFile *os.File
-	var bz []byte
-	if _, err := os.Stat(configPath); err != nil && os.IsNotExist(err) {
-		Logger.Fatal().Str("path", configPath).Msg("config file not found")
-	}
-	// reopen the file to read into the variable
-	jsonFile, err := os.OpenFile(configPath, os.O_RDONLY, os.ModePerm)
-	if err != nil {
-		Logger.Fatal().Str("path", configPath).Err(err).Msg("failed to open config file")
-	}
-	bz, err = io.ReadAll(jsonFile)
-	if err != nil {
-		Logger.Fatal().Str("path", configPath).Err(err).Msg("failed to read config file")
-	}
-	err = jsonFile.Close()
+	configPath := GetConfigFilePath()
+	cfg, err := ReadConfig(configPath)
 	if err != nil {
-		Logger.Fatal().Str("path", configPath).Err(err).Msg("failed to close config file")
+		Logger.Fatal().Str("path", configPath).Err(err).Msg("failed to load config file")
 	}
-	err = json.Unmarshal(bz, &cfg)
-	if err != nil {
-		Logger.Fatal().Str("path", configPath).Err(err).Msg("failed to unmarshal config file")
-	}
-
-	if valid, wrongKeys := ValidateConfig(&cfg); !valid {
-		Logger.Fatal().
-			Str("path", GetConfigFilePath()).
-			Int("count", len(wrongKeys)).
-			Strs("keys", wrongKeys).
-			Msg("loaded config.json contains errors")
-	}
-
-	return &cfg
+	return cfg
 }
 
 // ReloadConfig updates the provided configuration based on changes detected in a new configuration.
-func ReloadConfig() {
+// An invalid config file is rejected and the last good config keeps running.
+func ReloadConfig() error {
+	// reloads could be triggered at the same time by the file watcher, SIGHUP and the fallback polling
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+
 	Logger.Info().Msg("looking for changes on config file")
 
-	newCfg := LoadConfig()
+	configPath := GetConfigFilePath()
+	newCfg, err := ReadConfig(configPath)
+	if err != nil {
+		Logger.Error().Str("path", configPath).Err(err).Msg("config file rejected, keeping the last good config")
+		configReloadsMetric.WithLabelValues("rejected").Inc()
+		notifyReloadFailed(err, "config file rejected, keeping the last good config")
+		return err
+	}
+
+	// changes are applied to copies that are published together at the end, so running evaluations keep their
+	// snapshot and every dependency is built for the config it is published with
+	current := GetRuntime()
+	next := *current.Config
+	nextRt := *current
 
 	var updatePOKTscanClient bool
 	var updatePocketProvider bool
@@ -198,184 +232,330 @@ func ReloadConfig() {
 	var updateLogger bool
 	var updateSigners bool
 	var updateSchedule bool
+	var updateHttpServer bool
+	var updateAdminServer bool
 
 	uk := UpdateKeys{}
 
-	if AppConfig.DryMode != newCfg.DryMode {
+	if next.DryMode != newCfg.DryMode {
 		uk.Add("dry_mode")
-		AppConfig.DryMode = newCfg.DryMode
+		next.DryMode = newCfg.DryMode
 	}
 
-	if AppConfig.POKTscanApi != newCfg.POKTscanApi {
+	if next.POKTscanApi != newCfg.POKTscanApi {
 		uk.Add("poktscan_api")
 		updatePOKTscanClient = true
 	}
 
-	if AppConfig.POKTscanApiToken != newCfg.POKTscanApiToken {
+	if next.POKTscanApiToken != newCfg.POKTscanApiToken {
 		uk.Add("poktscan_api_token")
 		updatePOKTscanClient = true
 		updateHttpClient = true
 	}
 
-	if AppConfig.NetworkID != newCfg.NetworkID {
+	if next.NetworkID != newCfg.NetworkID {
 		uk.Add("network_id")
-		AppConfig.NetworkID = newCfg.NetworkID
+		next.NetworkID = newCfg.NetworkID
 	}
 
-	if AppConfig.TxMemo != newCfg.TxMemo {
+	if next.TxMemo != newCfg.TxMemo {
 		uk.Add("tx_memo")
-		AppConfig.TxMemo = newCfg.TxMemo
+		next.TxMemo = newCfg.TxMemo
 	}
 
-	if AppConfig.TxFee != newCfg.TxFee {
+	if next.TxFee != newCfg.TxFee {
 		uk.Add("tx_fee")
-		AppConfig.TxFee = newCfg.TxFee
+		next.TxFee = newCfg.TxFee
 	}
 
-	if AppConfig.Domain != newCfg.Domain {
+	if next.Domain != newCfg.Domain {
 		uk.Add("domain")
-		AppConfig.Domain = newCfg.Domain
+		next.Domain = newCfg.Domain
 	}
 
-	if diff := GetStrSliceDiff(AppConfig.ServicePool, newCfg.ServicePool); len(diff) > 0 {
+	if diff := GetStrSliceDiff(next.ServicePool, newCfg.ServicePool); len(diff) > 0 {
 		uk.Add("service_pool")
-		AppConfig.ServicePool = newCfg.ServicePool
+		next.ServicePool = newCfg.ServicePool
 	}
 
-	if diff := GetStrSliceDiff(AppConfig.ServicerKeys, newCfg.ServicerKeys); len(diff) > 0 {
+	if diff := GetStrSliceDiff(next.ServicerKeys, newCfg.ServicerKeys); len(diff) > 0 || len(next.ServicerKeys) != len(newCfg.ServicerKeys) {
 		uk.Add("servicer_keys")
 		updateSigners = true
 	}
 
-	if AppConfig.StakeWeight != newCfg.StakeWeight {
+	if diff := GetStrSliceDiff(next.ServicerAddresses, newCfg.ServicerAddresses); len(diff) > 0 || len(next.ServicerAddresses) != len(newCfg.ServicerAddresses) {
+		uk.Add("servicer_addresses")
+		next.ServicerAddresses = newCfg.ServicerAddresses
+	}
+
+	if diff := GetStrSliceDiff(next.ServicerKeyFiles, newCfg.ServicerKeyFiles); len(diff) > 0 || len(next.ServicerKeyFiles) != len(newCfg.ServicerKeyFiles) {
+		uk.Add("servicer_keyfiles")
+		updateSigners = true
+	}
+
+	if next.ServicerKeyFilesDir != newCfg.ServicerKeyFilesDir {
+		uk.Add("servicer_keyfiles_dir")
+		updateSigners = true
+	}
+
+	if next.RemoteSignerURL != newCfg.RemoteSignerURL {
+		uk.Add("remote_signer_url")
+		updateSigners = true
+	}
+
+	if next.RemoteSignerToken != newCfg.RemoteSignerToken {
+		uk.Add("remote_signer_token")
+		updateSigners = true
+	}
+
+	if next.KeyFilePassphraseFile != newCfg.KeyFilePassphraseFile {
+		uk.Add("keyfile_passphrase_file")
+		updateSigners = true
+	}
+
+	if next.StakeWeight != newCfg.StakeWeight {
 		uk.Add("stake_weight")
-		AppConfig.StakeWeight = newCfg.StakeWeight
+		next.StakeWeight = newCfg.StakeWeight
 	}
 
-	if AppConfig.MinIncreasePercent != newCfg.MinIncreasePercent {
+	if next.MinIncreasePercent != newCfg.MinIncreasePercent {
 		uk.Add("min_increase_percent")
-		AppConfig.MinIncreasePercent = newCfg.MinIncreasePercent
+		next.MinIncreasePercent = newCfg.MinIncreasePercent
 	}
 
-	if updated, removed, added := GetServiceStakeSliceDiff(AppConfig.MinServiceStake, newCfg.MinServiceStake); len(updated) > 0 || len(removed) > 0 || len(added) > 0 {
+	if updated, removed, added := GetServiceStakeSliceDiff(next.MinServiceStake, newCfg.MinServiceStake); len(updated) > 0 || len(removed) > 0 || len(added) > 0 {
 		uk.Add("min_service_stake")
-		AppConfig.MinServiceStake = newCfg.MinServiceStake
+		next.MinServiceStake = newCfg.MinServiceStake
 	}
 
-	if AppConfig.TimePeriod != newCfg.TimePeriod {
+	if next.TimePeriod != newCfg.TimePeriod {
 		uk.Add("time_period")
-		AppConfig.TimePeriod = newCfg.TimePeriod
+		next.TimePeriod = newCfg.TimePeriod
 	}
 
-	if AppConfig.ResultsPath != newCfg.ResultsPath {
+	if next.ResultsPath != newCfg.ResultsPath {
 		uk.Add("results_path")
-		AppConfig.ResultsPath = newCfg.ResultsPath
+		next.ResultsPath = newCfg.ResultsPath
 	}
 
-	if AppConfig.LogLevel != newCfg.LogLevel {
+	if next.HistoryPath != newCfg.HistoryPath {
+		uk.Add("history_path")
+		nextRt.History = NewHistoryStore(newCfg.HistoryPath)
+		next.HistoryPath = newCfg.HistoryPath
+	}
+
+	if next.LogLevel != newCfg.LogLevel {
 		uk.Add("log_level")
 		updateLogger = true
 	}
 
-	if AppConfig.LogFormat != newCfg.LogFormat {
+	if next.LogFormat != newCfg.LogFormat {
 		uk.Add("log_format")
 		updateLogger = true
 	}
 
-	if AppConfig.Schedule != newCfg.Schedule {
+	if next.Schedule != newCfg.Schedule {
 		uk.Add("schedule")
 		updateSchedule = true
 	}
 
-	if AppConfig.MaxWorkers != newCfg.MaxWorkers {
+	if next.MaxWorkers != newCfg.MaxWorkers {
 		uk.Add("max_workers")
 		updateWorker = true
 	}
 
-	if AppConfig.PocketRPC != newCfg.PocketRPC {
+	if next.PocketRPC != newCfg.PocketRPC {
 		uk.Add("pocket_rpc")
 		updatePocketProvider = true
 	}
 
-	if AppConfig.MaxRetries != newCfg.MaxRetries {
+	if next.MaxRetries != newCfg.MaxRetries {
 		uk.Add("max_retries")
 		updateHttpClient = true
+		updatePocketProvider = true
 	}
 
-	if AppConfig.MaxTimeout != newCfg.MaxTimeout {
+	if next.MaxTimeout != newCfg.MaxTimeout {
 		uk.Add("max_timeout")
 		updateHttpClient = true
+		updatePocketProvider = true
+	}
+
+	if next.HttpAddress != newCfg.HttpAddress {
+		uk.Add("http_address")
+		updateHttpServer = true
+	}
+
+	if next.AdminAddress != newCfg.AdminAddress {
+		uk.Add("admin_address")
+		updateAdminServer = true
+	}
+
+	if next.AdminToken != newCfg.AdminToken {
+		uk.Add("admin_token")
+		updateAdminServer = true
+	}
+
+	if !reflect.DeepEqual(next.Webhooks, newCfg.Webhooks) {
+		uk.Add("webhooks")
+		next.Webhooks = newCfg.Webhooks
+	}
+
+	if !reflect.DeepEqual(next.Telegram, newCfg.Telegram) {
+		uk.Add("telegram")
+		next.Telegram = newCfg.Telegram
+	}
+
+	if !reflect.DeepEqual(next.Email, newCfg.Email) {
+		uk.Add("email")
+		next.Email = newCfg.Email
+	}
+
+	if next.DigestSchedule != newCfg.DigestSchedule {
+		uk.Add("digest_schedule")
+		// the digest is scheduled along with the evaluation job
+		updateSchedule = true
+	}
+
+	if next.RequireApproval != newCfg.RequireApproval {
+		uk.Add("require_approval")
+		next.RequireApproval = newCfg.RequireApproval
+	}
+
+	if next.ApprovalMinNodes != newCfg.ApprovalMinNodes {
+		uk.Add("approval_min_nodes")
+		next.ApprovalMinNodes = newCfg.ApprovalMinNodes
+	}
+
+	if next.PlanTTL != newCfg.PlanTTL {
+		uk.Add("plan_ttl")
+		next.PlanTTL = newCfg.PlanTTL
+	}
+
+	if next.ConfirmationBlocks != newCfg.ConfirmationBlocks {
+		uk.Add("confirmation_blocks")
+		next.ConfirmationBlocks = newCfg.ConfirmationBlocks
+	}
+
+	if next.ConfirmationTimeout != newCfg.ConfirmationTimeout {
+		uk.Add("confirmation_timeout")
+		next.ConfirmationTimeout = newCfg.ConfirmationTimeout
+	}
+
+	if next.ConfirmationPollInterval != newCfg.ConfirmationPollInterval {
+		uk.Add("confirmation_poll_interval")
+		next.ConfirmationPollInterval = newCfg.ConfirmationPollInterval
+	}
+
+	if next.LowBalanceRestakes != newCfg.LowBalanceRestakes {
+		uk.Add("low_balance_restakes")
+		next.LowBalanceRestakes = newCfg.LowBalanceRestakes
 	}
 
 	if uk.Size() == 0 {
 		Logger.Debug().Msg("config file look the same as before.")
-		return
+		configReloadsMetric.WithLabelValues("unchanged").Inc()
+		return nil
 	}
 
+	// publish the snapshot no matter how the update of each element ends, values are only assigned when they succeed
+	defer func() {
+		nextRt.Config = &next
+		SetRuntime(&nextRt)
+	}()
+
+	configReloadsMetric.WithLabelValues("changed").Inc()
+
 	Logger.Info().Strs("changed_keys", uk.Values()).Msg("changes are detected on config file, proceeding to update elements")
 
 	if updateSigners {
-		if index, err := UpdateServicers(AppConfig.ServicerKeys); err != nil {
+		if signers, err := LoadSigners(newCfg); err != nil {
 			// update on the fly, no other config modify it
-			Logger.Error().Err(err).Int("index", index).Msg("error updating signers")
+			Logger.Error().Err(err).Msg("error updating signers")
+			notifyReloadFailed(err, "unable to update signers")
 		} else {
+			nextRt.Signers = UpdateServicers(current.Signers, signers)
 			// update all the props that could trigger it
-			AppConfig.ServicerKeys = newCfg.ServicerKeys
+			next.ServicerKeys = newCfg.ServicerKeys
+			next.ServicerKeyFiles = newCfg.ServicerKeyFiles
+			next.ServicerKeyFilesDir = newCfg.ServicerKeyFilesDir
+			next.RemoteSignerURL = newCfg.RemoteSignerURL
+			next.RemoteSignerToken = newCfg.RemoteSignerToken
+			next.KeyFilePassphraseFile = newCfg.KeyFilePassphraseFile
 		}
 	}
 
 	if updateWorker {
 		Logger.Info().Msg("updating worker pool")
-		waitingTasks := WorkerPool.WaitingTasks()
-		if waitingTasks > 0 {
-			Logger.Error().Uint64("waiting_tasks", waitingTasks).Msg("unable to update worker pool due to it has waiting tasks. will be retried on next round.")
-		} else {
-			WorkerPool.StopAndWait()
-			// max capacity will always be the same of servicer keys
-			NewWorker(newCfg.MaxWorkers, uint(len(newCfg.ServicerKeys)))
-			// update all the props that could trigger it
-			AppConfig.MaxWorkers = newCfg.MaxWorkers
-		}
+		// max capacity will always be the same of servicers, the replaced pool is stopped once the runs that use
+		// it are done
+		nextRt.Workers = NewWorker(newCfg.MaxWorkers, uint(nextRt.Signers.Size()))
+		next.MaxWorkers = newCfg.MaxWorkers
 	}
 
 	if updateSchedule {
 		Logger.Info().Msg("updating schedule")
-		err := ReSchedule(newCfg.Schedule) // update on the fly, no other config modify it
+		err := ReSchedule(newCfg.Schedule, newCfg.DigestSchedule) // update on the fly, no other config modify it
 		if err != nil {
 			Logger.Error().Err(err).Msg("failed to reschedule. check your schedule config.")
+			notifyReloadFailed(err, "unable to update schedule")
 		} else {
-			// assign to AppConfig reference the new value.
-			AppConfig.Schedule = newCfg.Schedule
+			// assign to the next snapshot the new values.
+			next.Schedule = newCfg.Schedule
+			next.DigestSchedule = newCfg.DigestSchedule
 		}
 	}
 
 	if updateLogger {
 		Logger.Info().Msg("updating logger")
 		ConfigLogger(newCfg.LogLevel, newCfg.LogFormat)
-		AppConfig.LogFormat = newCfg.LogFormat
-		AppConfig.LogLevel = newCfg.LogLevel
+		next.LogFormat = newCfg.LogFormat
+		next.LogLevel = newCfg.LogLevel
 	}
 
 	if updateHttpClient {
 		Logger.Info().Msg("updating http client")
-		NewHttpClient(newCfg.POKTscanApiToken, newCfg.MaxRetries, newCfg.MaxTimeout)
-		AppConfig.POKTscanApiToken = newCfg.POKTscanApiToken
-		AppConfig.MaxRetries = newCfg.MaxRetries
-		AppConfig.MaxTimeout = newCfg.MaxTimeout
+		nextRt.HttpClient = NewHttpClient(newCfg.POKTscanApiToken, newCfg.MaxRetries, newCfg.MaxTimeout)
+		next.POKTscanApiToken = newCfg.POKTscanApiToken
+		next.MaxRetries = newCfg.MaxRetries
+		next.MaxTimeout = newCfg.MaxTimeout
 	}
 
 	if updatePocketProvider {
 		Logger.Info().Msg("updating pocket rpc")
-		NewPocketRpcProvider(newCfg.PocketRPC, newCfg.MaxRetries, newCfg.MaxTimeout)
-		// update rpc url, but if retries or timeout was modified will be already update by previous if
-		AppConfig.PocketRPC = newCfg.PocketRPC
+		nextRt.Rpc = NewPocketRpcProvider(newCfg.PocketRPC, newCfg.MaxRetries, newCfg.MaxTimeout)
+		// retries and timeout are assigned by the http client update
+		next.PocketRPC = newCfg.PocketRPC
 	}
 
-	if updatePOKTscanClient {
+	if updateHttpServer {
+		Logger.Info().Msg("updating http server")
+		StopHttpServer()
+		NewHttpServer(newCfg.HttpAddress)
+		next.HttpAddress = newCfg.HttpAddress
+	}
+
+	if updateAdminServer {
+		Logger.Info().Msg("updating admin api")
+		StopAdminServer()
+		NewAdminServer(newCfg.AdminAddress, newCfg.AdminToken)
+		next.AdminAddress = newCfg.AdminAddress
+		next.AdminToken = newCfg.AdminToken
+	}
+
+	if updatePOKTscanClient || updateHttpClient {
 		Logger.Info().Msg("updating poktscan api")
-		// this one also update the basic client.
-		NewPOKTscanClient(newCfg.POKTscanApi)
+		// built on top of the http client, so it is replaced with it
+		nextRt.Wts = NewPOKTscanClient(newCfg.POKTscanApi, nextRt.HttpClient)
 		// update poktscan api url
-		AppConfig.POKTscanApi = newCfg.POKTscanApi
+		next.POKTscanApi = newCfg.POKTscanApi
 	}
+
+	return nil
+}
+
+func notifyReloadFailed(err error, message string) {
+	n := NewNotification(EventConfigReloadFailed, message)
+	n.Fields["error"] = err.Error()
+	Notify(n)
 }
@@ -0,0 +1,181 @@
+package wtsc
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"github.com/rs/zerolog"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// testPrivateKey is the key of newTestSigner(name)
+func testPrivateKey(name string) string {
+	seed := sha256.Sum256([]byte("wtsc-test-" + name))
+	return hex.EncodeToString(ed25519.NewKeyFromSeed(seed[:]))
+}
+
+// writeTestConfig writes a valid config file with the given pocket rpc, history, workers and keys
+func writeTestConfig(t *testing.T, path, pocketRpc, historyPath string, maxWorkers uint, keys ...string) {
+	t.Helper()
+	writeTestConfigWith(t, path, map[string]any{
+		"pocket_rpc":    pocketRpc,
+		"history_path":  historyPath,
+		"max_workers":   maxWorkers,
+		"servicer_keys": keys,
+	})
+}
+
+// writeTestConfigWith writes a valid config file, with the values of changes instead of the default ones
+func writeTestConfigWith(t *testing.T, path string, changes map[string]any) {
+	t.Helper()
+	cfg := map[string]any{
+		"poktscan_api":         "http://127.0.0.1:1/graphql",
+		"poktscan_api_token":   "test",
+		"network_id":           "testnet",
+		"tx_fee":               10000,
+		"domain":               "wtsc.test",
+		"service_pool":         []string{"0021"},
+		"servicer_keys":        []string{testPrivateKey("0")},
+		"stake_weight":         1,
+		"min_increase_percent": 5,
+		"min_service_stake":    []map[string]any{{"service": "0021", "min_node": 1}},
+		"time_period":          24,
+		"pocket_rpc":           "http://127.0.0.1:1",
+		"log_level":            "error",
+		"log_format":           LogTextFormat,
+		"schedule":             "@every 5m",
+		"max_workers":          1,
+		"max_retries":          0,
+		"max_timeout":          5000,
+	}
+	for key, value := range changes {
+		cfg[key] = value
+	}
+	bz, err := json.Marshal(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = os.WriteFile(path, bz, 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// useTestConfigFile writes a config file on a temp project root, like writeTestConfig, and returns its path and the
+// config read from it. The globals are restored at the end of the test.
+func useTestConfigFile(t *testing.T, pocketRpc, historyPath string, maxWorkers uint, keys ...string) (string, *Config) {
+	t.Helper()
+	oldLogger, oldLevel, oldRuntime, oldRoot, oldPath := Logger, zerolog.GlobalLevel(), GetRuntime(), ProjectRoot, configFilePathOverride
+	t.Cleanup(func() {
+		StopWorkers()
+		Logger = oldLogger
+		zerolog.SetGlobalLevel(oldLevel)
+		ProjectRoot = oldRoot
+		configFilePathOverride = oldPath
+		currentRuntime.Store(oldRuntime)
+	})
+	Logger = zerolog.Nop()
+
+	ProjectRoot = t.TempDir()
+	configPath := filepath.Join(ProjectRoot, "config.json")
+	SetConfigFilePath(configPath)
+	writeTestConfig(t, configPath, pocketRpc, historyPath, maxWorkers, keys...)
+	cfg, err := ReadConfig(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return configPath, cfg
+}
+
+// newConfigRuntime builds the runtime of cfg like the setup of the daemon
+func newConfigRuntime(t *testing.T, cfg *Config) *Runtime {
+	t.Helper()
+	signers, err := NewSignerMap(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	httpClient := NewHttpClient(cfg.POKTscanApiToken, cfg.MaxRetries, cfg.MaxTimeout)
+	return &Runtime{
+		Config:     cfg,
+		HttpClient: httpClient,
+		Wts:        NewPOKTscanClient(cfg.POKTscanApi, httpClient),
+		Rpc:        NewPocketRpcProvider(cfg.PocketRPC, cfg.MaxRetries, cfg.MaxTimeout),
+		Signers:    signers,
+		Workers:    NewWorker(cfg.MaxWorkers, uint(signers.Size())),
+		History:    NewHistoryStore(cfg.HistoryPath),
+	}
+}
+
+func TestReloadConfigPublishesDependenciesWithConfig(t *testing.T) {
+	configPath, cfg := useTestConfigFile(t, "http://127.0.0.1:1", "", 1, testPrivateKey("0"))
+	old := newConfigRuntime(t, cfg)
+	SetRuntime(old)
+
+	// the timeout is used by the http client, the poktscan client on top of it and the pocket rpc
+	writeTestConfigWith(t, configPath, map[string]any{"max_timeout": 7000, "max_workers": 3})
+	if err := ReloadConfig(); err != nil {
+		t.Fatal(err)
+	}
+
+	rt := GetRuntime()
+	if rt.Config.MaxTimeout != 7000 || rt.Config.MaxWorkers != 3 || rt.Config.Version <= cfg.Version {
+		t.Fatalf("got config %+v, expected the reloaded one", rt.Config)
+	}
+	if rt.HttpClient == old.HttpClient || rt.HttpClient.HTTPClient.Timeout.Milliseconds() != 7000 {
+		t.Fatal("the http client was not rebuilt with the new timeout")
+	}
+	if rt.Wts == old.Wts || rt.Rpc == old.Rpc {
+		t.Fatal("the clients on top of the timeout were not rebuilt")
+	}
+	if rt.Workers == old.Workers || !old.Workers.Stopped() || rt.Workers.MaxWorkers() != 3 {
+		t.Fatal("the worker pool was not replaced and the old one drained")
+	}
+	if rt.Signers != old.Signers {
+		t.Fatal("the signers were replaced without changes")
+	}
+	if GetConfig() != rt.Config {
+		t.Fatal("the config is not the one published with the runtime")
+	}
+}
+
+func TestReloadConfigUnchangedOrRejected(t *testing.T) {
+	configPath, cfg := useTestConfigFile(t, "http://127.0.0.1:1", "", 1, testPrivateKey("0"))
+	old := newConfigRuntime(t, cfg)
+	SetRuntime(old)
+
+	if err := ReloadConfig(); err != nil {
+		t.Fatal(err)
+	}
+	if GetRuntime() != old {
+		t.Fatal("an unchanged file published a new runtime")
+	}
+
+	writeTestConfigWith(t, configPath, map[string]any{"max_workers": 0, "pocket_rpc": "http://127.0.0.2:1"})
+	if err := ReloadConfig(); !errors.Is(err, ErrInvalidConfig) {
+		t.Fatalf("got %v, expected %s", err, ErrInvalidConfig)
+	}
+	if GetRuntime() != old || old.Workers.Stopped() {
+		t.Fatal("a rejected file changed the runtime")
+	}
+}
+
+func TestReloadConfigLogLevelKeepsLogger(t *testing.T) {
+	configPath, cfg := useTestConfigFile(t, "http://127.0.0.1:1", "", 1, testPrivateKey("0"))
+	SetRuntime(newConfigRuntime(t, cfg))
+	before := Logger.GetLevel()
+
+	writeTestConfigWith(t, configPath, map[string]any{"log_level": "debug"})
+	if err := ReloadConfig(); err != nil {
+		t.Fatal(err)
+	}
+	if zerolog.GlobalLevel() != zerolog.DebugLevel || GetConfig().LogLevel != "debug" {
+		t.Fatalf("got level %s, expected debug", zerolog.GlobalLevel())
+	}
+	// runs keep a copy of Logger, it must not be replaced under them
+	if Logger.GetLevel() != before {
+		t.Fatal("the logger was replaced by the reload")
+	}
+}
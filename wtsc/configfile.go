@@ -0,0 +1,153 @@
+package wtsc
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+const (
+	ConfigFormatJson = "json"
+	ConfigFormatYaml = "yaml"
+	ConfigFormatToml = "toml"
+
+	// ConfigEnvPrefix is the prefix of the env vars that override the config file, i.e. WTSC_DRY_MODE
+	ConfigEnvPrefix = "WTSC_"
+)
+
+// ConfigFormat detects the format of the config file by its extension, anything unknown is read as json
+func ConfigFormat(configPath string) string {
+	switch strings.ToLower(filepath.Ext(configPath)) {
+	case ".yaml", ".yml":
+		return ConfigFormatYaml
+	case ".toml":
+		return ConfigFormatToml
+	default:
+		return ConfigFormatJson
+	}
+}
+
+// ConfigJson converts the config file content on the given format to json.
+// YAML and TOML are converted to json, so every format uses the same keys (the json tags of Config).
+func ConfigJson(bz []byte, format string) ([]byte, error) {
+	var err error
+	values := make(map[string]any)
+
+	switch format {
+	case ConfigFormatYaml:
+		err = yaml.Unmarshal(bz, &values)
+	case ConfigFormatToml:
+		err = toml.Unmarshal(bz, &values)
+	default:
+		return bz, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(values)
+}
+
+// DecodeConfig decodes the config file content on the given format
+func DecodeConfig(bz []byte, format string, cfg *Config) error {
+	bz, err := ConfigJson(bz, format)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(bz, cfg)
+}
+
+// ConfigEnvName returns the env var that overrides a config key, i.e. poktscan_api_token -> WTSC_POKTSCAN_API_TOKEN
+func ConfigEnvName(key string) string {
+	return ConfigEnvPrefix + strings.ToUpper(key)
+}
+
+// ApplyEnvOverrides sets every config key that has a WTSC_* env var on top of the values of the config file.
+// Lists of strings could be comma separated or json, any other list or object must be json.
+func ApplyEnvOverrides(cfg *Config) (overridden []string, err error) {
+	v := reflect.ValueOf(cfg).Elem()
+	t := v.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		key := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+		if key == "" || key == "-" {
+			continue
+		}
+
+		envName := ConfigEnvName(key)
+		value, ok := os.LookupEnv(envName)
+		if !ok {
+			continue
+		}
+
+		if err = setConfigField(v.Field(i), value); err != nil {
+			return overridden, fmt.Errorf("invalid value of %s: %w", envName, err)
+		}
+		overridden = append(overridden, key)
+	}
+
+	return overridden, nil
+}
+
+func setConfigField(field reflect.Value, value string) error {
+	// json.Number is a string, but it should hold a valid number
+	if field.Type() == reflect.TypeOf(json.Number("")) {
+		if _, err := strconv.ParseFloat(value, 64); err != nil {
+			return err
+		}
+		field.SetString(value)
+		return nil
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(value, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetUint(n)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetInt(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(value, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetFloat(n)
+	case reflect.Slice:
+		if field.Type().Elem().Kind() == reflect.String && !strings.HasPrefix(strings.TrimSpace(value), "[") {
+			items := make([]string, 0)
+			for _, item := range strings.Split(value, ",") {
+				if item = strings.TrimSpace(item); item != "" {
+					items = append(items, item)
+				}
+			}
+			field.Set(reflect.ValueOf(items).Convert(field.Type()))
+			return nil
+		}
+		fallthrough
+	default:
+		return json.Unmarshal([]byte(value), field.Addr().Interface())
+	}
+
+	return nil
+}
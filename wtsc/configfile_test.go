@@ -0,0 +1,288 @@
+package wtsc
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestConfigFormat(t *testing.T) {
+	cases := map[string]string{
+		"config.json":          ConfigFormatJson,
+		"config.yaml":          ConfigFormatYaml,
+		"config.yml":           ConfigFormatYaml,
+		"/etc/wtsc/CONFIG.YML": ConfigFormatYaml,
+		"config.toml":          ConfigFormatToml,
+		"config.Toml":          ConfigFormatToml,
+		"config":               ConfigFormatJson,
+		"config.txt":           ConfigFormatJson,
+	}
+
+	for path, expect := range cases {
+		if got := ConfigFormat(path); got != expect {
+			t.Errorf("ConfigFormat(%s) = %s, expected %s", path, got, expect)
+		}
+	}
+}
+
+func TestApplyEnvOverrides(t *testing.T) {
+	cases := []struct {
+		name   string
+		env    map[string]string
+		expect func(cfg *Config) bool
+	}{
+		{
+			name:   "string",
+			env:    map[string]string{"WTSC_POKTSCAN_API_TOKEN": "secret"},
+			expect: func(cfg *Config) bool { return cfg.POKTscanApiToken == "secret" },
+		},
+		{
+			name:   "bool",
+			env:    map[string]string{"WTSC_DRY_MODE": "true"},
+			expect: func(cfg *Config) bool { return cfg.DryMode },
+		},
+		{
+			name:   "uint",
+			env:    map[string]string{"WTSC_MAX_WORKERS": "4"},
+			expect: func(cfg *Config) bool { return cfg.MaxWorkers == 4 },
+		},
+		{
+			name:   "float",
+			env:    map[string]string{"WTSC_MIN_INCREASE_PERCENT": "2.5"},
+			expect: func(cfg *Config) bool { return cfg.MinIncreasePercent == 2.5 },
+		},
+		{
+			name:   "json number",
+			env:    map[string]string{"WTSC_TX_FEE": "20000"},
+			expect: func(cfg *Config) bool { return cfg.TxFee == "20000" },
+		},
+		{
+			name: "comma list",
+			env:  map[string]string{"WTSC_SERVICE_POOL": " 0001, 0021,,0003 "},
+			expect: func(cfg *Config) bool {
+				return reflect.DeepEqual(cfg.ServicePool, []string{"0001", "0021", "0003"})
+			},
+		},
+		{
+			name: "json list",
+			env:  map[string]string{"WTSC_SERVICE_POOL": `["0001","0021"]`},
+			expect: func(cfg *Config) bool {
+				return reflect.DeepEqual(cfg.ServicePool, []string{"0001", "0021"})
+			},
+		},
+		{
+			name:   "empty list",
+			env:    map[string]string{"WTSC_SERVICE_POOL": ""},
+			expect: func(cfg *Config) bool { return cfg.ServicePool != nil && len(cfg.ServicePool) == 0 },
+		},
+		{
+			name: "json list of objects",
+			env:  map[string]string{"WTSC_MIN_SERVICE_STAKE": `[{"service":"0001","min_node":3}]`},
+			expect: func(cfg *Config) bool {
+				return reflect.DeepEqual(cfg.MinServiceStake, MinServiceStake{{Service: "0001", MinNode: 3}})
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			keys := make([]string, 0, len(c.env))
+			for name, value := range c.env {
+				t.Setenv(name, value)
+				keys = append(keys, strings.ToLower(strings.TrimPrefix(name, ConfigEnvPrefix)))
+			}
+
+			cfg := &Config{ServicePool: []string{"0040"}, TxFee: "10000", MaxWorkers: 1}
+			overridden, err := ApplyEnvOverrides(cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(overridden, keys) {
+				t.Fatalf("got %v overridden, expected %v", overridden, keys)
+			}
+			if !c.expect(cfg) {
+				t.Fatalf("unexpected config %+v", cfg)
+			}
+		})
+	}
+}
+
+func TestApplyEnvOverridesInvalid(t *testing.T) {
+	cases := map[string]string{
+		"WTSC_DRY_MODE":             "yes please",
+		"WTSC_MAX_WORKERS":          "-1",
+		"WTSC_TIME_PERIOD":          "a day",
+		"WTSC_MIN_INCREASE_PERCENT": "5%",
+		"WTSC_TX_FEE":               "ten thousand",
+		"WTSC_MIN_SERVICE_STAKE":    "0001:3",
+		"WTSC_SERVICE_POOL":         `["0001",`,
+	}
+
+	for name, value := range cases {
+		t.Run(name, func(t *testing.T) {
+			t.Setenv(name, value)
+
+			cfg := &Config{TxFee: "10000"}
+			if _, err := ApplyEnvOverrides(cfg); err == nil || !strings.Contains(err.Error(), name) {
+				t.Fatalf("got %v, expected an error about %s", err, name)
+			}
+		})
+	}
+}
+
+// the same config on every format
+var configFileFormats = map[string]string{
+	"config.json": `{
+  "network_id": "testnet",
+  "tx_fee": 10000,
+  "service_pool": ["0001", "0021"],
+  "min_increase_percent": 2.5,
+  "min_service_stake": [{"service": "0001", "min_node": 3}],
+  "dry_mode": true
+}`,
+	"config.yaml": `network_id: testnet
+tx_fee: 10000
+service_pool:
+  - "0001"
+  - "0021"
+min_increase_percent: 2.5
+min_service_stake:
+  - service: "0001"
+    min_node: 3
+dry_mode: true
+`,
+	"config.toml": `network_id = "testnet"
+tx_fee = 10000
+service_pool = ["0001", "0021"]
+min_increase_percent = 2.5
+dry_mode = true
+
+[[min_service_stake]]
+service = "0001"
+min_node = 3
+`,
+}
+
+func TestDecodeConfigFormats(t *testing.T) {
+	expect := Config{
+		NetworkID:          "testnet",
+		TxFee:              "10000",
+		ServicePool:        []string{"0001", "0021"},
+		MinIncreasePercent: 2.5,
+		MinServiceStake:    MinServiceStake{{Service: "0001", MinNode: 3}},
+		DryMode:            true,
+	}
+
+	for fileName, content := range configFileFormats {
+		t.Run(fileName, func(t *testing.T) {
+			cfg := Config{}
+			if err := DecodeConfig([]byte(content), ConfigFormat(fileName), &cfg); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(cfg, expect) {
+				t.Fatalf("got %+v, expected %+v", cfg, expect)
+			}
+		})
+	}
+}
+
+func TestDecodeConfigInvalid(t *testing.T) {
+	cases := map[string]string{
+		ConfigFormatJson: `{"network_id": `,
+		ConfigFormatYaml: "network_id: [testnet",
+		ConfigFormatToml: `network_id = `,
+	}
+
+	for format, content := range cases {
+		if err := DecodeConfig([]byte(content), format, &Config{}); err == nil {
+			t.Errorf("invalid %s config was decoded", format)
+		}
+	}
+}
+
+func TestReadConfigFormats(t *testing.T) {
+	useTestConfigFile(t, "http://127.0.0.1:1", "", 1, testPrivateKey("0"))
+
+	// the default valid config, written on every format
+	jsonPath := filepath.Join(ProjectRoot, "valid.json")
+	writeTestConfigWith(t, jsonPath, map[string]any{"max_workers": 3})
+	expect, err := ReadConfig(jsonPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bz, err := os.ReadFile(jsonPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	values := make(map[string]any)
+	if err = json.Unmarshal(bz, &values); err != nil {
+		t.Fatal(err)
+	}
+
+	yamlLines := make([]string, 0, len(values))
+	tomlLines := make([]string, 0, len(values))
+	for key, value := range values {
+		// json values are valid yaml and toml values, except the list of objects
+		if key == "min_service_stake" {
+			continue
+		}
+		v, e := json.Marshal(value)
+		if e != nil {
+			t.Fatal(e)
+		}
+		yamlLines = append(yamlLines, key+": "+string(v))
+		tomlLines = append(tomlLines, key+" = "+string(v))
+	}
+	yamlLines = append(yamlLines, "min_service_stake:", `  - service: "0021"`, "    min_node: 1")
+	tomlLines = append(tomlLines, "", "[[min_service_stake]]", `service = "0021"`, "min_node = 1")
+
+	files := map[string]string{
+		"valid.yaml": strings.Join(yamlLines, "\n"),
+		"valid.yml":  strings.Join(yamlLines, "\n"),
+		"valid.toml": strings.Join(tomlLines, "\n"),
+	}
+	for fileName, content := range files {
+		t.Run(fileName, func(t *testing.T) {
+			path := filepath.Join(ProjectRoot, fileName)
+			if e := os.WriteFile(path, []byte(content), 0600); e != nil {
+				t.Fatal(e)
+			}
+
+			cfg, e := ReadConfig(path)
+			if e != nil {
+				t.Fatal(e)
+			}
+			if !reflect.DeepEqual(cfg, expect) {
+				t.Fatalf("got %+v, expected %+v", cfg, expect)
+			}
+		})
+	}
+}
+
+func TestReadConfigEnvOverrides(t *testing.T) {
+	configPath, _ := useTestConfigFile(t, "http://127.0.0.1:1", "", 1, testPrivateKey("0"))
+
+	t.Setenv("WTSC_MAX_WORKERS", "5")
+	t.Setenv("WTSC_SERVICE_POOL", "0001,0021")
+	cfg, err := ReadConfig(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.MaxWorkers != 5 || !reflect.DeepEqual(cfg.ServicePool, []string{"0001", "0021"}) {
+		t.Fatalf("env vars were not applied over the file: %+v", cfg)
+	}
+
+	// the overridden config is validated too
+	t.Setenv("WTSC_MAX_WORKERS", "0")
+	if _, err = ReadConfig(configPath); err == nil {
+		t.Fatal("an invalid env override was accepted")
+	}
+
+	t.Setenv("WTSC_MAX_WORKERS", "many")
+	if _, err = ReadConfig(configPath); err == nil || !strings.Contains(err.Error(), "WTSC_MAX_WORKERS") {
+		t.Fatalf("got %v, expected an error about WTSC_MAX_WORKERS", err)
+	}
+}
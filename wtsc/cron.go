@@ -12,7 +12,7 @@ import (
 	"time"
 )
 
-func writeResults(result *generated.GetWhatToStakeResponse) {
+func writeResults(result *generated.GetWhatToStakeResponse, resultsPath string) {
 	// Convert the struct to pretty-printed JSON
 	prettyJSON, err := json.MarshalIndent(result, "", "  ")
 	if err != nil {
@@ -30,7 +30,7 @@ func writeResults(result *generated.GetWhatToStakeResponse) {
 	fileName := fmt.Sprintf("wts_result_%s.json", dateTimeString)
 
 	// Concatenate paths to form the full file path
-	fullPath := filepath.Join(ProjectRoot, AppConfig.ResultsPath, fileName)
+	fullPath := filepath.Join(ProjectRoot, resultsPath, fileName)
 
 	// Write the JSON to a file
 	file, err := os.Create(fullPath)
@@ -54,74 +54,222 @@ func writeResults(result *generated.GetWhatToStakeResponse) {
 	Logger.Info().Str("path", fullPath).Msg("writing results to file")
 }
 
+// evaluationJob is the scheduled job, it runs the evaluation with the process singletons
 func evaluationJob() {
-	Logger.Info().Msg("running evaluation")
+	DefaultApp.Evaluate()
+}
+
+// Evaluate calls what-to-stake and, depending on the config, submits the recommended stakes, proposes a plan for
+// approval or just reports the difference. It returns nil when another evaluation is already running.
+func (app *App) Evaluate() *RunRecord {
+	// scheduled and admin api triggered runs must not overlap, otherwise the same stake could be sent twice
+	if !app.State.TryStart() {
+		app.Logger.Warn().Msg("an evaluation is already running, skipping this one")
+		return nil
+	}
+	defer app.State.Done()
 
-	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(AppConfig.MaxTimeout)*time.Millisecond)
+	// the whole run (including the stake txs) uses this config snapshot and these dependencies, even if they are
+	// reloaded meanwhile
+	app, release := app.pin()
+	defer release()
+	cfg := app.Config()
+
+	app.Logger.Info().Uint64("config_version", cfg.Version).Str("config_hash", cfg.Hash).Msg("running evaluation")
+	run := NewRunRecord(app.Clock.Now())
+	run.DryMode = cfg.DryMode
+	run.ConfigVersion = cfg.Version
+	run.ConfigHash = cfg.Hash
+	defer app.finishRun(run)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.MaxTimeout)*time.Millisecond)
 	defer cancel()
 
-	input := generated.WtsProcessRequestInput{
-		Domain:               AppConfig.Domain,
-		Service_pool:         AppConfig.ServicePool,
-		Min_increase_percent: AppConfig.MinIncreasePercent,
-		Stake_weight:         int(AppConfig.StakeWeight),
-		Min_service_stake:    AppConfig.MinServiceStake.CastToGqlType(),
-		Time_period:          int(AppConfig.TimePeriod),
-	}
+	input := NewWtsInput(cfg)
+	run.Input = input
 
-	Logger.Info().Msg("calling what to stake service")
-	resp, err := generated.GetWhatToStake(ctx, *POKTscanApiClient, input)
+	app.Logger.Info().Msg("calling what to stake service")
+	wtsStart := app.Clock.Now()
+	resp, err := app.Wts.GetWhatToStake(ctx, input)
+	run.WtsMs = app.Clock.Now().Sub(wtsStart).Milliseconds()
 
 	if err != nil {
-		Logger.Error().Err(err).Str("service", AppConfig.POKTscanApi).Msg("failed to call what to stake service")
-		return
+		app.Logger.Error().Err(err).Str("service", cfg.POKTscanApi).Msg("failed to call what to stake service")
+		run.Error = err.Error()
+		run.Finish(RunOutcomeWtsFailed, app.Clock.Now())
+		n := NewNotification(EventWtsFailed, "failed to call what to stake service")
+		n.RunID = run.ID
+		n.Fields["error"] = err.Error()
+		app.notify(n)
+		return run
 	}
+	run.Response = resp
 
-	if IsEmptyString(AppConfig.ResultsPath) {
+	if IsEmptyString(cfg.ResultsPath) {
 		resultStr, e := json.Marshal(resp)
 		if e != nil {
-			Logger.Error().Err(e).Msg("failed to marshal results")
+			app.Logger.Error().Err(e).Msg("failed to marshal results")
 		} else {
-			Logger.Debug().Str("result", string(resultStr)).Msg("what to stake results")
+			app.Logger.Debug().Str("result", string(resultStr)).Msg("what to stake results")
 		}
 	} else {
-		writeResults(resp)
+		writeResults(resp, cfg.ResultsPath)
 	}
 
-	if AppConfig.DryMode {
-		Logger.Info().Msg("DRY MODE is on, omitting stake transactions.")
-		return
+	if cfg.DryMode || app.IsWatchOnly() {
+		if app.Signers.Size() > 0 || len(cfg.ServicerAddresses) > 0 {
+			// without signers or watched addresses there is nothing to compare
+			planCtx, planCancel := context.WithTimeout(context.Background(), time.Duration(cfg.MaxTimeout)*time.Millisecond)
+			defer planCancel()
+
+			run.Plan = app.BuildPlan(planCtx, resp)
+			app.ReportPlan(run.Plan)
+			app.notifyPlan(run.Plan, run.ID)
+		}
+
+		if cfg.DryMode {
+			app.Logger.Info().Msg("DRY MODE is on, omitting stake transactions.")
+			run.Finish(RunOutcomeDryMode, app.Clock.Now())
+		} else {
+			app.Logger.Info().Msg("there are only watch-only servicers, omitting stake transactions.")
+			run.Finish(RunOutcomeWatchOnly, app.Clock.Now())
+		}
+		return run
+	}
+
+	if app.State.Paused() {
+		app.Logger.Info().Msg("stake transactions are paused, omitting stake transactions.")
+		run.Finish(RunOutcomePaused, app.Clock.Now())
+		return run
 	}
 
 	if !resp.GetWhatToStake.Do_update {
-		Logger.Info().Msg("What-To-Stake thinks you does not need to update yet.")
-		return
+		app.Logger.Info().Msg("What-To-Stake thinks you does not need to update yet.")
+		run.Finish(RunOutcomeNoUpdate, app.Clock.Now())
+		return run
 	}
 
+	if cfg.RequireApproval {
+		planCtx, planCancel := context.WithTimeout(context.Background(), time.Duration(cfg.MaxTimeout)*time.Millisecond)
+		defer planCancel()
+
+		plan := app.BuildPlan(planCtx, resp)
+		if app.NeedsApproval(plan) {
+			if e := app.ProposePlan(plan, run.ID); e != nil {
+				// nobody could approve it, so there is no plan to notify
+				app.Logger.Error().Err(e).Msg("failed to save stake plan")
+				run.Error = e.Error()
+				run.Finish(RunOutcomePlanFailed, app.Clock.Now())
+				return run
+			}
+			run.PlanID = plan.ID
+			run.Finish(RunOutcomePlanPending, app.Clock.Now())
+			app.notifyPlan(plan, run.ID)
+			return run
+		}
+		app.notifyPlan(plan, run.ID)
+	}
+
+	run.Results = app.submitStakes(resp.GetWhatToStake.Servicers)
+	run.Finish(RunOutcomeCompleted, app.Clock.Now())
+	app.notifyStakes(run, resp.GetWhatToStake.Gain_change_percent)
+	return run
+}
+
+// submitStakes process the servicers on the worker pool and waits until all of them are done.
+func (app *App) submitStakes(servicers []generated.GetWhatToStakeGetWhatToStakeWtsOptimizationResponseServicersWtsStakeNode) []*ServicerResult {
 	// create a group inside worker pool because it allows just waiting without a stop
-	group := WorkerPool.Group()
+	group := app.Workers.Group()
+	report := &StakeReport{}
 
-	for _, wtsServicer := range resp.GetWhatToStake.Servicers {
-		if signer, ok := ServicersMap.Load(wtsServicer.Address); !ok {
-			Logger.Warn().Str("address", wtsServicer.Address).Msg("Failed to find signer")
+	for i := range servicers {
+		// take the reference from the slice, the loop variable is reused on each iteration
+		wtsServicer := &servicers[i]
+		if signer, ok := app.Signers.Load(wtsServicer.Address); !ok {
+			app.Logger.Warn().Str("address", wtsServicer.Address).Msg("Failed to find signer")
+			// record it anyway, so the history shows every recommended servicer
+			report.Add(&ServicerResult{
+				Address: wtsServicer.Address,
+				Chains:  wtsServicer.Services,
+				Status:  TxStatusNoSigner,
+				Error:   "there is no signer for the address",
+			})
 			continue
 		} else {
-			group.Submit(StakeServicer(signer, &wtsServicer))
+			group.Submit(app.StakeServicer(signer, wtsServicer, report))
 		}
 	}
 
-	// Stop group pool and wait for all submitted tasks to complete
+	// Stop group pool and wait for all submitted tasks to complete, then for their confirmations
 	group.Wait()
+	report.Wait()
+
+	app.logReport(report)
+
+	return report.Results()
+}
+
+func (app *App) finishRun(run *RunRecord) {
+	app.State.last.Store(run)
+	app.Observe(run)
+	app.saveRun(run)
+}
+
+func (app *App) saveRun(run *RunRecord) {
+	history := app.History()
+	if history == nil {
+		return
+	}
+
+	if err := history.SaveRun(run); err != nil {
+		app.Logger.Error().Err(err).Str("run_id", run.ID).Msg("failed to save run on history")
+		return
+	}
+
+	app.Logger.Debug().Str("run_id", run.ID).Str("outcome", string(run.Outcome)).Msg("run saved on history")
 }
 
-func Schedule(frequency string, runOnce bool) (entry cron.EntryID, err error) {
+func (app *App) logReport(report *StakeReport) {
+	for _, result := range report.Results() {
+		app.Logger.Info().
+			Str("address", result.Address).
+			Str("status", string(result.Status)).
+			Str("hash", result.Hash).
+			Str("error", result.Error).
+			Msg("servicer stake result")
+	}
+
+	counts := report.CountByStatus()
+	app.Logger.Info().
+		Int(string(TxStatusSubmitted), counts[TxStatusSubmitted]).
+		Int(string(TxStatusConfirmed), counts[TxStatusConfirmed]).
+		Int(string(TxStatusFailed), counts[TxStatusFailed]).
+		Int(string(TxStatusDropped), counts[TxStatusDropped]).
+		Int(string(TxStatusInsufficientFunds), counts[TxStatusInsufficientFunds]).
+		Int(string(TxStatusNoChange), counts[TxStatusNoChange]).
+		Int(string(TxStatusNoSigner), counts[TxStatusNoSigner]).
+		Msg("evaluation finished")
+}
+
+// Schedule starts the cron with the evaluation job and, when digestFrequency is not empty, the digest job
+func Schedule(frequency, digestFrequency string, runOnce bool) (entry cron.EntryID, err error) {
 	Logger.Info().Msg("preparing cron job")
 	CronJob = cron.New()
 	// Define the job
 	entry, err = CronJob.AddFunc(frequency, evaluationJob)
 	Logger.Debug().Int("schedule_id", int(entry)).Msg("scheduled job detail")
+	evaluationEntry = entry
+	if !IsEmptyString(digestFrequency) {
+		digestEntry, e := CronJob.AddFunc(digestFrequency, digestJob)
+		if e != nil {
+			Logger.Error().Err(e).Msg("failed to schedule digest")
+		} else {
+			Logger.Debug().Int("schedule_id", int(digestEntry)).Msg("scheduled digest detail")
+		}
+	}
 	// Start the cron job
 	CronJob.Start()
+	cronRunning.Store(true)
 	if runOnce {
 		// run it right now
 		go evaluationJob()
@@ -129,16 +277,16 @@ func Schedule(frequency string, runOnce bool) (entry cron.EntryID, err error) {
 	return
 }
 
-func ReSchedule(frequency string) error {
-	if WorkerPool.WaitingTasks() > 0 {
+func ReSchedule(frequency, digestFrequency string) error {
+	if rt := GetRuntime(); rt != nil && rt.Workers.WaitingTasks() > 0 {
 		// prefer to delay the schedule update to the moment where there are not waiting tasks
 		return errors.New("unable to update schedule due to waiting jobs")
 	}
 
-	CronJob.Stop()
+	StopSchedule()
 
 	// schedule it but without run it right now no mater what config say, because this is used on config hot-reload
-	entryId, err := Schedule(frequency, false)
+	entryId, err := Schedule(frequency, digestFrequency, false)
 
 	if err != nil {
 		return err
@@ -148,3 +296,9 @@ func ReSchedule(frequency string) error {
 
 	return nil
 }
+
+// StopSchedule stops the cron job, so no new evaluations are scheduled. Running ones are not affected.
+func StopSchedule() {
+	CronJob.Stop()
+	cronRunning.Store(false)
+}
@@ -0,0 +1,245 @@
+package wtsc
+
+import (
+	"errors"
+	"github.com/pokt-scan/wtsc/wtsc/generated"
+	"testing"
+	"time"
+)
+
+func TestSubmitStakesRecordsServicersWithoutSigner(t *testing.T) {
+	signer := newTestSigner(t, "0")
+	rpc := newFakeRpc()
+	rpc.addNode(signer, []string{"0001"})
+	cfg := &Config{NetworkID: "testnet", TxFee: "10000", MaxWorkers: 1}
+	app := newTestApp(t, cfg, rpc, newTestSigners(signer))
+
+	const watched = "0000000000000000000000000000000000000001"
+	results := app.submitStakes([]generated.GetWhatToStakeGetWhatToStakeWtsOptimizationResponseServicersWtsStakeNode{
+		{Address: signer.GetAddress(), Services: []string{"0021"}},
+		{Address: watched, Services: []string{"0021"}},
+	})
+
+	statuses := make(map[string]TxStatus)
+	for _, result := range results {
+		statuses[result.Address] = result.Status
+	}
+	if len(statuses) != 2 {
+		t.Fatalf("got %d results, expected one per servicer", len(statuses))
+	}
+	if statuses[signer.GetAddress()] != TxStatusSubmitted {
+		t.Fatalf("signer is %s, expected %s", statuses[signer.GetAddress()], TxStatusSubmitted)
+	}
+	if statuses[watched] != TxStatusNoSigner {
+		t.Fatalf("watched is %s, expected %s", statuses[watched], TxStatusNoSigner)
+	}
+	if rpc.sentTxs() != 1 {
+		t.Fatalf("sent %d txs, expected 1", rpc.sentTxs())
+	}
+
+	summary := NewRunSummary(&RunRecord{Outcome: RunOutcomeCompleted, Results: results})
+	if summary.Changes != 1 || summary.ExitCode != ExitCodeOK {
+		t.Fatalf("got %d changes and exit code %d, expected 1 and %d", summary.Changes, summary.ExitCode, ExitCodeOK)
+	}
+}
+
+func newTestEvaluation(t *testing.T, wts *fakeWts) (*App, *fakeRpc, Signer, *HistoryStore, *notificationRecorder) {
+	t.Helper()
+	signer := newTestSigner(t, "0")
+	rpc := newFakeRpc()
+	rpc.addNode(signer, []string{"0001"})
+	cfg := &Config{NetworkID: "testnet", TxFee: "10000", MaxWorkers: 1, MaxTimeout: 1000}
+
+	app := newTestApp(t, cfg, rpc, newTestSigners(signer))
+	hs := newTestHistoryStore(t)
+	notifications := &notificationRecorder{}
+	app.Wts = wts
+	app.Clock = fakeClock{now: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)}
+	app.History = func() *HistoryStore { return hs }
+	app.Notify = notifications.Notify
+	return app, rpc, signer, hs, notifications
+}
+
+func TestEvaluateSubmitsRecommendedStakes(t *testing.T) {
+	wts := &fakeWts{}
+	app, rpc, signer, hs, notifications := newTestEvaluation(t, wts)
+	wts.resp = &generated.GetWhatToStakeResponse{GetWhatToStake: generated.GetWhatToStakeGetWhatToStakeWtsOptimizationResponse{
+		Do_update:           true,
+		Gain_change_percent: 12.5,
+		Servicers: []generated.GetWhatToStakeGetWhatToStakeWtsOptimizationResponseServicersWtsStakeNode{
+			{Address: signer.GetAddress(), Services: []string{"0021"}},
+		},
+	}}
+	observed := 0
+	app.Observe = func(*RunRecord) { observed++ }
+
+	run := app.Evaluate()
+	if run == nil {
+		t.Fatal("evaluation did not run")
+	}
+	if run.Outcome != RunOutcomeCompleted {
+		t.Fatalf("outcome is %s, expected %s", run.Outcome, RunOutcomeCompleted)
+	}
+	if !run.StartedAt.Equal(app.Clock.Now()) {
+		t.Fatalf("run started at %s, expected the app clock", run.StartedAt)
+	}
+	if len(run.Results) != 1 || run.Results[0].Status != TxStatusSubmitted {
+		t.Fatalf("unexpected results %+v", run.Results)
+	}
+	if rpc.sentTxs() != 1 {
+		t.Fatalf("sent %d txs, expected 1", rpc.sentTxs())
+	}
+
+	if app.State.Running() {
+		t.Fatal("the run state is still running")
+	}
+	if app.State.LastRun() != run || observed != 1 {
+		t.Fatal("the run was not recorded on the app state and metrics")
+	}
+	if _, err := hs.GetRun(run.ID); err != nil {
+		t.Fatalf("run not saved on history: %s", err)
+	}
+	if events := notifications.Events(); len(events) != 1 || events[0] != EventStakesSubmitted {
+		t.Fatalf("got notifications %v, expected %s", events, EventStakesSubmitted)
+	}
+}
+
+func TestEvaluateWtsFailed(t *testing.T) {
+	app, rpc, _, _, notifications := newTestEvaluation(t, &fakeWts{err: errors.New("wts is down")})
+
+	run := app.Evaluate()
+	if run.Outcome != RunOutcomeWtsFailed || run.Error != "wts is down" {
+		t.Fatalf("got %s (%s), expected %s", run.Outcome, run.Error, RunOutcomeWtsFailed)
+	}
+	if rpc.sentTxs() != 0 {
+		t.Fatalf("sent %d txs, expected none", rpc.sentTxs())
+	}
+	if events := notifications.Events(); len(events) != 1 || events[0] != EventWtsFailed {
+		t.Fatalf("got notifications %v, expected %s", events, EventWtsFailed)
+	}
+}
+
+func TestEvaluateRunState(t *testing.T) {
+	wts := &fakeWts{resp: &generated.GetWhatToStakeResponse{}}
+	app, _, _, _, _ := newTestEvaluation(t, wts)
+
+	app.State.TryStart()
+	if run := app.Evaluate(); run != nil || wts.calls != 0 {
+		t.Fatal("evaluation ran while another one is running")
+	}
+	app.State.Done()
+
+	app.State.SetPaused(true)
+	wts.resp.GetWhatToStake.Do_update = true
+	if run := app.Evaluate(); run.Outcome != RunOutcomePaused {
+		t.Fatalf("outcome is %s, expected %s", run.Outcome, RunOutcomePaused)
+	}
+}
+
+func newTestApprovalEvaluation(t *testing.T) (*App, *fakeRpc, *HistoryStore, *notificationRecorder) {
+	t.Helper()
+	wts := &fakeWts{}
+	app, rpc, signer, hs, notifications := newTestEvaluation(t, wts)
+	app.Config().RequireApproval = true
+	wts.resp = &generated.GetWhatToStakeResponse{GetWhatToStake: generated.GetWhatToStakeGetWhatToStakeWtsOptimizationResponse{
+		Do_update: true,
+		Servicers: []generated.GetWhatToStakeGetWhatToStakeWtsOptimizationResponseServicersWtsStakeNode{
+			{Address: signer.GetAddress(), Services: []string{"0021"}},
+		},
+	}}
+	return app, rpc, hs, notifications
+}
+
+func TestEvaluateProposesPlan(t *testing.T) {
+	app, rpc, hs, notifications := newTestApprovalEvaluation(t)
+
+	run := app.Evaluate()
+	if run.Outcome != RunOutcomePlanPending || run.PlanID == "" {
+		t.Fatalf("got %s with plan %q, expected %s", run.Outcome, run.PlanID, RunOutcomePlanPending)
+	}
+	if plan, err := hs.GetPlan(run.PlanID); err != nil || plan.Status != PlanStatusPending {
+		t.Fatalf("plan not saved as pending: %v", err)
+	}
+	if rpc.sentTxs() != 0 {
+		t.Fatalf("sent %d txs before the approval", rpc.sentTxs())
+	}
+	if events := notifications.Events(); len(events) != 1 || events[0] != EventPlanComputed {
+		t.Fatalf("got notifications %v, expected %s", events, EventPlanComputed)
+	}
+}
+
+func TestEvaluatePlanNotSaved(t *testing.T) {
+	app, rpc, _, notifications := newTestApprovalEvaluation(t)
+	// the plan can not be saved without history
+	app.History = func() *HistoryStore { return nil }
+
+	run := app.Evaluate()
+	if run.Outcome != RunOutcomePlanFailed || run.PlanID != "" || run.Error != ErrHistoryDisabled.Error() {
+		t.Fatalf("got %s with plan %q (%s), expected %s without plan", run.Outcome, run.PlanID, run.Error, RunOutcomePlanFailed)
+	}
+	if rpc.sentTxs() != 0 {
+		t.Fatalf("sent %d txs of a plan that needs approval", rpc.sentTxs())
+	}
+	if events := notifications.Events(); len(events) != 0 {
+		t.Fatalf("got notifications %v of a plan that nobody could approve", events)
+	}
+	if summary := NewRunSummary(run); summary.ExitCode != ExitCodePlanFailed {
+		t.Fatalf("exit code is %d, expected %d", summary.ExitCode, ExitCodePlanFailed)
+	}
+}
+
+func TestEvaluateWatchOnly(t *testing.T) {
+	watched := newTestSigner(t, "watched")
+	other := newTestSigner(t, "other")
+	rpc := newFakeRpc()
+	rpc.addNode(watched, []string{"0001"})
+	rpc.addNode(other, []string{"0001"})
+	cfg := &Config{NetworkID: "testnet", TxFee: "10000", MaxWorkers: 1, MaxTimeout: 1000, ServicerAddresses: []string{watched.GetAddress()}}
+
+	// there are no keys at all
+	app := newTestApp(t, cfg, rpc, newTestSigners())
+	notifications := &notificationRecorder{}
+	app.Notify = notifications.Notify
+	app.Wts = &fakeWts{resp: &generated.GetWhatToStakeResponse{GetWhatToStake: generated.GetWhatToStakeGetWhatToStakeWtsOptimizationResponse{
+		Do_update: true,
+		Servicers: []generated.GetWhatToStakeGetWhatToStakeWtsOptimizationResponseServicersWtsStakeNode{
+			{Address: watched.GetAddress(), Services: []string{"0021"}},
+			{Address: other.GetAddress(), Services: []string{"0021"}},
+		},
+	}}}
+	if !app.IsWatchOnly() {
+		t.Fatal("expected a watch-only app")
+	}
+
+	run := app.Evaluate()
+	if run.Outcome != RunOutcomeWatchOnly {
+		t.Fatalf("outcome is %s, expected %s", run.Outcome, RunOutcomeWatchOnly)
+	}
+	if rpc.sentTxs() != 0 {
+		t.Fatalf("sent %d txs without keys", rpc.sentTxs())
+	}
+
+	own := run.Plan.OwnNodes()
+	if len(run.Plan.Nodes) != 2 || len(own) != 1 || own[0].Address != watched.GetAddress() {
+		t.Fatalf("got own nodes %+v, expected only the watched one", own)
+	}
+	if node := own[0]; !node.Watched || node.Signer || !node.Change || !IsSameStrSet(node.CurrentChains, []string{"0001"}) {
+		t.Fatalf("unexpected watched node %+v", node)
+	}
+	if len(run.Plan.ChangedNodes()) != 0 {
+		t.Fatal("a watched node without key could be signed")
+	}
+
+	if events := notifications.Events(); len(events) != 1 || events[0] != EventPlanComputed {
+		t.Fatalf("got notifications %v, expected %s", events, EventPlanComputed)
+	}
+	if summary := NewRunSummary(run); summary.Changes != 1 || summary.ExitCode != ExitCodeOK {
+		t.Fatalf("got %d changes and exit code %d, expected 1 and %d", summary.Changes, summary.ExitCode, ExitCodeOK)
+	}
+
+	// a signer turns it into a regular runner
+	app.Signers = newTestSigners(newTestSigner(t, "0"))
+	if app.IsWatchOnly() {
+		t.Fatal("an app with signers is not watch-only")
+	}
+}
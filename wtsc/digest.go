@@ -0,0 +1,94 @@
+package wtsc
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+const digestPeriod = 24 * time.Hour
+
+// BuildDigest summarizes the given runs on a single notification
+func BuildDigest(runs []*RunRecord, since time.Time) *Notification {
+	n := NewNotification(EventDailyDigest, fmt.Sprintf("%d runs since %s", len(runs), since.UTC().Format(time.RFC3339)))
+
+	outcomes := make(map[string]int)
+	txs := make(map[string]int)
+	for _, run := range runs {
+		outcomes[string(run.Outcome)]++
+
+		changed := make([]string, 0)
+		for _, result := range run.Results {
+			txs[string(result.Status)]++
+			if !result.IsSkipped() {
+				changed = append(changed, fmt.Sprintf("%s %s", result.Address, result.Status))
+			}
+		}
+
+		switch {
+		case run.Outcome == RunOutcomeWtsFailed || run.Outcome == RunOutcomePlanFailed:
+			n.Details = append(n.Details, fmt.Sprintf("%s: %s %s", run.ID, run.Outcome, run.Error))
+		case len(changed) > 0:
+			detail := fmt.Sprintf("%s: %s", run.ID, strings.Join(changed, ", "))
+			if run.Response != nil {
+				detail += fmt.Sprintf(" (gain change %.2f%%)", run.Response.GetWhatToStake.Gain_change_percent)
+			}
+			n.Details = append(n.Details, detail)
+		case run.Outcome == RunOutcomePlanPending:
+			n.Details = append(n.Details, fmt.Sprintf("%s: plan %s waiting for approval", run.ID, run.PlanID))
+		}
+	}
+
+	n.Fields["outcomes"] = formatCounts(outcomes)
+	n.Fields["txs"] = formatCounts(txs)
+
+	if last := lastWithResponse(runs); last != nil {
+		wts := last.Response.GetWhatToStake
+		n.Fields["last_reason"] = wts.Reason
+		n.Fields["last_current_modeled_gain_24h"] = wts.Current_modeled_gain_24h
+		n.Fields["last_optimal_modeled_gain_24h"] = wts.Optimal_modeled_gain_24h
+	}
+
+	return n
+}
+
+func lastWithResponse(runs []*RunRecord) *RunRecord {
+	for i := len(runs) - 1; i >= 0; i-- {
+		if runs[i].Response != nil {
+			return runs[i]
+		}
+	}
+	return nil
+}
+
+// formatCounts returns "key=value" pairs sorted by key, so the digest is stable
+func formatCounts(counts map[string]int) string {
+	if len(counts) == 0 {
+		return "none"
+	}
+	pairs := make([]string, 0, len(counts))
+	for k, v := range counts {
+		pairs = append(pairs, fmt.Sprintf("%s=%d", k, v))
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, " ")
+}
+
+func digestJob() {
+	history := DefaultApp.History()
+	if history == nil {
+		Logger.Warn().Msg("digest_schedule requires history_path, skipping digest")
+		return
+	}
+
+	since := time.Now().Add(-digestPeriod)
+	runs, err := history.ListRunsSince(since)
+	if err != nil {
+		Logger.Error().Err(err).Msg("failed to read runs for the digest")
+		return
+	}
+
+	Logger.Info().Int("runs", len(runs)).Msg("sending digest")
+	Notify(BuildDigest(runs, since))
+}
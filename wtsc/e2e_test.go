@@ -0,0 +1,414 @@
+package wtsc_test
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/Khan/genqlient/graphql"
+	"github.com/alitto/pond"
+	pocketGoProvider "github.com/pokt-foundation/pocket-go/provider"
+	"github.com/pokt-scan/wtsc/wtsc"
+	"github.com/pokt-scan/wtsc/wtsc/generated"
+	"github.com/pokt-scan/wtsc/wtsc/mock"
+	"github.com/puzpuzpuz/xsync"
+	"github.com/rs/zerolog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const (
+	e2eNetworkID = "testnet"
+	e2eTxFee     = 10000
+	e2eTokens    = 15000000000
+	e2eBalance   = 1000000000
+	e2eServicers = 2
+)
+
+var (
+	// e2eChains are the chains of the nodes before the run, they are not in the service pool, so any
+	// recommendation is a change
+	e2eChains      = []string{"0001"}
+	e2eServicePool = []string{"0021", "0003"}
+)
+
+// e2eCase is a run of the whole evaluation (config load, what-to-stake, stake txs and confirmation) against the
+// fake POKTscan API and the fake Pocket RPC.
+type e2eCase struct {
+	name      string
+	poktscan  []mock.POKTscanScenario
+	txs       []mock.TxScenario
+	networkID string
+	balance   int64
+	// noChange recommends the chains that the nodes already have
+	noChange bool
+	// unknown recommends mock.UnknownAddresses too, they must be recorded without a tx
+	unknown bool
+	// status of the result of each servicer, empty means that no tx must be processed
+	status   wtsc.TxStatus
+	exitCode int
+	// staked means that the nodes must have the recommended chains after the run
+	staked bool
+	// offline exports the signed txs of a plan to a file and broadcasts it, instead of an evaluation
+	offline bool
+	// external is offline without keys on the config, the txs are exported unsigned, signed by the case and
+	// assembled before the broadcast
+	external bool
+}
+
+var e2eCases = []e2eCase{
+	{name: "stake", status: wtsc.TxStatusConfirmed, staked: true},
+	{name: "no_update", poktscan: []mock.POKTscanScenario{mock.ScenarioNoUpdate}},
+	{name: "no_change", noChange: true, status: wtsc.TxStatusNoChange},
+	{name: "unknown_addresses", poktscan: []mock.POKTscanScenario{mock.ScenarioUnknownAddresses}, unknown: true, status: wtsc.TxStatusConfirmed, staked: true},
+	{name: "wts_error", poktscan: []mock.POKTscanScenario{mock.ScenarioGraphqlError}, exitCode: wtsc.ExitCodeWtsFailed},
+	{name: "tx_rejected", txs: []mock.TxScenario{mock.TxScenarioRejected}, status: wtsc.TxStatusFailed, exitCode: wtsc.ExitCodeTxFailed},
+	{name: "tx_failed", txs: []mock.TxScenario{mock.TxScenarioFailed}, status: wtsc.TxStatusFailed, exitCode: wtsc.ExitCodeTxFailed},
+	{name: "tx_dropped", txs: []mock.TxScenario{mock.TxScenarioDropped}, status: wtsc.TxStatusDropped, exitCode: wtsc.ExitCodeTxFailed},
+	{name: "wrong_network", networkID: "mainnet", status: wtsc.TxStatusFailed, exitCode: wtsc.ExitCodeTxFailed},
+	{name: "insufficient_funds", balance: e2eTxFee - 1, status: wtsc.TxStatusInsufficientFunds, exitCode: wtsc.ExitCodeTxFailed},
+	{name: "offline_broadcast", offline: true, status: wtsc.TxStatusConfirmed, staked: true},
+	{name: "offline_tx_failed", offline: true, txs: []mock.TxScenario{mock.TxScenarioFailed}, status: wtsc.TxStatusFailed, exitCode: wtsc.ExitCodeTxFailed},
+	{name: "external_signing", offline: true, external: true, status: wtsc.TxStatusConfirmed, staked: true},
+}
+
+// e2eWtsClient calls what-to-stake on the fake POKTscan API of a case
+type e2eWtsClient struct {
+	client graphql.Client
+}
+
+func (c e2eWtsClient) GetWhatToStake(ctx context.Context, input generated.WtsProcessRequestInput) (*generated.GetWhatToStakeResponse, error) {
+	return generated.GetWhatToStake(ctx, c.client, input)
+}
+
+// e2eEnv is the fake network of a case and the app wired to it, nothing is shared with other cases
+type e2eEnv struct {
+	app         *wtsc.App
+	cfg         *wtsc.Config
+	dir         string
+	pocketRpc   *mock.PocketRpc
+	addresses   []string
+	privateKeys map[string]ed25519.PrivateKey
+}
+
+// TestE2E runs the end-to-end suite: each case loads a config pointing to the fake POKTscan API and the fake Pocket
+// RPC, runs an evaluation and checks the run summary and the on-chain state of the nodes.
+func TestE2E(t *testing.T) {
+	if testing.Short() {
+		t.Skip("end-to-end suite")
+	}
+
+	for _, c := range e2eCases {
+		t.Run(c.name, func(t *testing.T) {
+			env := newE2EEnv(t, c)
+
+			recommended := make(map[string][]string)
+			var run *wtsc.RunRecord
+			switch {
+			case c.external:
+				run = runE2EExternal(t, env, recommended)
+			case c.offline:
+				run = runE2EOffline(t, env, recommended)
+			default:
+				// what-to-stake and stake, like `wtsc once`
+				run = env.app.Evaluate()
+				if run != nil && run.Response != nil {
+					for _, servicer := range run.Response.GetWhatToStake.Servicers {
+						recommended[servicer.Address] = servicer.Services
+					}
+				}
+			}
+			if run == nil {
+				t.Fatal("the evaluation did not run")
+			}
+			if c.offline && run.Outcome != wtsc.RunOutcomeBroadcast {
+				t.Errorf("outcome %s, expected %s", run.Outcome, wtsc.RunOutcomeBroadcast)
+			}
+
+			summary := wtsc.NewRunSummary(run)
+			if summary.ExitCode != c.exitCode {
+				t.Errorf("exit code %d, expected %d (outcome %s, error %q)", summary.ExitCode, c.exitCode, summary.Outcome, summary.Error)
+			}
+
+			if c.status == "" && len(run.Results) > 0 {
+				t.Errorf("%d stake results, expected none", len(run.Results))
+			}
+			results := e2eServicers
+			if c.unknown {
+				results += len(mock.UnknownAddresses)
+			}
+			if c.status != "" && len(run.Results) != results {
+				t.Errorf("%d stake results, expected %d", len(run.Results), results)
+			}
+
+			for _, result := range run.Results {
+				if c.unknown && wtsc.FindStringInSlice(mock.UnknownAddresses, result.Address) {
+					if result.Status != wtsc.TxStatusNoSigner {
+						t.Errorf("%s is %s, expected %s", result.Address, result.Status, wtsc.TxStatusNoSigner)
+					}
+					continue
+				}
+				if result.Status != c.status {
+					t.Errorf("%s is %s, expected %s (%s)", result.Address, result.Status, c.status, result.Error)
+				}
+				if !wtsc.FindStringInSlice(env.addresses, result.Address) {
+					t.Errorf("unexpected result of %s", result.Address)
+				}
+			}
+
+			// the on-chain state is the final verification, the results could be right by accident
+			for _, address := range env.addresses {
+				node, _ := env.pocketRpc.Node(address)
+				expected := e2eChains
+				if c.staked {
+					expected = recommended[address]
+				}
+				if !wtsc.IsSameStrSet(node.Chains, expected) {
+					t.Errorf("%s has chains %v on-chain, expected %v", address, node.Chains, expected)
+				}
+			}
+		})
+	}
+}
+
+// newE2EEnv starts the fake services of the case, writes and loads its config and builds an app for it
+func newE2EEnv(t *testing.T, c e2eCase) *e2eEnv {
+	t.Helper()
+	env := &e2eEnv{
+		dir:         t.TempDir(),
+		pocketRpc:   mock.NewPocketRpc(),
+		privateKeys: make(map[string]ed25519.PrivateKey, e2eServicers),
+	}
+
+	env.pocketRpc.NetworkID = e2eNetworkID
+	if c.networkID != "" {
+		env.pocketRpc.NetworkID = c.networkID
+	}
+	env.pocketRpc.TxScenarios = c.txs
+	env.pocketRpc.DefaultBalance = e2eBalance
+	if c.balance != 0 {
+		env.pocketRpc.DefaultBalance = c.balance
+	}
+
+	keys := make([]string, 0, e2eServicers)
+	for i := 0; i < e2eServicers; i++ {
+		// the same keys on each run, so a failure could be reproduced
+		seed := sha256.Sum256([]byte(fmt.Sprintf("wtsc-e2e-%d", i)))
+		privateKey := ed25519.NewKeyFromSeed(seed[:])
+		node, err := mock.NewNode(hex.EncodeToString(privateKey.Public().(ed25519.PublicKey)), e2eChains, e2eTokens)
+		if err != nil {
+			t.Fatal(err)
+		}
+		env.pocketRpc.AddNode(node)
+		keys = append(keys, hex.EncodeToString(privateKey))
+		env.privateKeys[node.Address] = privateKey
+		env.addresses = append(env.addresses, node.Address)
+	}
+
+	poktscan := &mock.POKTscan{Scenarios: c.poktscan, Servicers: env.addresses}
+	if c.noChange {
+		poktscan.Recommendation = make(map[string][]string)
+		for _, address := range env.addresses {
+			poktscan.Recommendation[address] = e2eChains
+		}
+	}
+
+	poktscanServer := mock.NewPOKTscanServer(poktscan)
+	t.Cleanup(poktscanServer.Close)
+	pocketServer := mock.NewPocketRpcServer(env.pocketRpc)
+	t.Cleanup(pocketServer.Close)
+
+	configPath := filepath.Join(env.dir, "config.json")
+	if c.external {
+		// only the addresses, the keys are on the "hardware wallet" of the case
+		writeE2EConfig(t, configPath, poktscanServer.URL, pocketServer.URL, nil, env.addresses)
+	} else {
+		writeE2EConfig(t, configPath, poktscanServer.URL, pocketServer.URL, keys, nil)
+	}
+
+	cfg, err := wtsc.ReadConfig(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	env.cfg = cfg
+
+	loaded, err := wtsc.LoadSigners(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signers := xsync.NewMapOf[wtsc.Signer]()
+	for _, signer := range loaded {
+		signers.Store(signer.GetAddress(), signer)
+	}
+
+	rpc := pocketGoProvider.NewProvider(cfg.PocketRPC)
+	rpc.UpdateRequestConfig(pocketGoProvider.RequestConfigOpts{Timeout: time.Duration(cfg.MaxTimeout) * time.Millisecond})
+
+	workers := pond.New(int(cfg.MaxWorkers), 0)
+	t.Cleanup(workers.StopAndWait)
+
+	logger := zerolog.Nop()
+	env.app = &wtsc.App{
+		Config:  func() *wtsc.Config { return cfg },
+		Logger:  &logger,
+		Wts:     e2eWtsClient{client: graphql.NewClient(cfg.POKTscanApi, http.DefaultClient)},
+		Rpc:     rpc,
+		Signers: signers,
+		Clock:   wtsc.SystemClock{},
+		Workers: workers,
+		Entropy: wtsc.RandomEntropy,
+		State:   &wtsc.RunState{},
+		History: func() *wtsc.HistoryStore { return nil },
+		Notify:  func(*wtsc.Config, *wtsc.Notification) {},
+		Observe: func(*wtsc.RunRecord) {},
+	}
+	return env
+}
+
+// newE2EPlan calls what-to-stake and compares it with the on-chain state, like `wtsc plan`
+func newE2EPlan(t *testing.T, env *e2eEnv, recommended map[string][]string) *wtsc.Plan {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(env.cfg.MaxTimeout)*time.Millisecond)
+	defer cancel()
+
+	resp, err := env.app.Wts.GetWhatToStake(ctx, wtsc.NewWtsInput(env.cfg))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plan := env.app.BuildPlan(ctx, resp)
+	for _, node := range plan.Nodes {
+		recommended[node.Address] = node.ProposedChains
+	}
+	return plan
+}
+
+// runE2EOffline exports the txs of a new plan to a file, like `wtsc export`, and broadcasts it, like
+// `wtsc broadcast`. The export must not reach the rpc.
+func runE2EOffline(t *testing.T, env *e2eEnv, recommended map[string][]string) *wtsc.RunRecord {
+	t.Helper()
+	plan := newE2EPlan(t, env, recommended)
+
+	bundlePath := filepath.Join(env.dir, "stake_txs.json")
+	if err := wtsc.WriteTxBundle(bundlePath, env.app.ExportPlan(plan)); err != nil {
+		t.Fatal(err)
+	}
+	if txs := env.pocketRpc.Txs(); len(txs) > 0 {
+		t.Fatalf("export submitted %d txs", len(txs))
+	}
+
+	bundle, err := wtsc.ReadTxBundle(bundlePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bundle.Skipped) > 0 {
+		t.Fatalf("export skipped %v", bundle.Skipped)
+	}
+
+	run, err := env.app.BroadcastBundle(bundle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return run
+}
+
+// runE2EExternal exports the unsigned txs of a new plan, like `wtsc export -unsigned`, signs their sign bytes with
+// the keys like an external tool would, assembles them, like `wtsc assemble`, and broadcasts the signed bundle.
+// Signatures of another key must not be assembled.
+func runE2EExternal(t *testing.T, env *e2eEnv, recommended map[string][]string) *wtsc.RunRecord {
+	t.Helper()
+	plan := newE2EPlan(t, env, recommended)
+
+	unsignedPath := filepath.Join(env.dir, "stake_txs.unsigned.json")
+	if err := wtsc.WriteTxBundle(unsignedPath, env.app.ExportUnsignedPlan(plan)); err != nil {
+		t.Fatal(err)
+	}
+	unsigned, err := wtsc.ReadTxBundle(unsignedPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(unsigned.Skipped) > 0 || len(unsigned.Txs) != len(env.privateKeys) {
+		t.Fatalf("exported %d unsigned txs, skipped %v", len(unsigned.Txs), unsigned.Skipped)
+	}
+
+	signatures := make(map[string]string)
+	wrongSignatures := make(map[string]string)
+	wrongKey := ed25519.NewKeyFromSeed(make([]byte, ed25519.SeedSize))
+	for _, tx := range unsigned.Txs {
+		signBytes, e := hex.DecodeString(tx.SignBytes)
+		if e != nil {
+			t.Fatal(e)
+		}
+		signatures[tx.Address] = hex.EncodeToString(ed25519.Sign(env.privateKeys[tx.Address], signBytes))
+		wrongSignatures[tx.Address] = hex.EncodeToString(ed25519.Sign(wrongKey, signBytes))
+	}
+
+	rejected, err := wtsc.AssembleBundle(unsigned, wrongSignatures)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rejected.Txs) > 0 {
+		t.Fatalf("assembled %d txs with signatures of another key", len(rejected.Txs))
+	}
+
+	bundle, err := wtsc.AssembleBundle(unsigned, signatures)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bundle.Skipped) > 0 {
+		t.Fatalf("assemble skipped %v", bundle.Skipped)
+	}
+	if txs := env.pocketRpc.Txs(); len(txs) > 0 {
+		t.Fatalf("export submitted %d txs", len(txs))
+	}
+
+	run, err := env.app.BroadcastBundle(bundle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return run
+}
+
+func writeE2EConfig(t *testing.T, path, poktscanApi, pocketRpc string, keys, addresses []string) {
+	t.Helper()
+	cfg := map[string]any{
+		"dry_mode":             false,
+		"poktscan_api":         poktscanApi,
+		"poktscan_api_token":   "e2e",
+		"network_id":           e2eNetworkID,
+		"tx_fee":               e2eTxFee,
+		"domain":               "e2e.mock",
+		"service_pool":         e2eServicePool,
+		"servicer_keys":        keys,
+		"servicer_addresses":   addresses,
+		"stake_weight":         1,
+		"min_increase_percent": 5,
+		"min_service_stake": []map[string]any{
+			{"service": e2eServicePool[0], "min_node": 1},
+		},
+		"time_period":                24,
+		"pocket_rpc":                 pocketRpc,
+		"log_level":                  "error",
+		"log_format":                 wtsc.LogTextFormat,
+		"schedule":                   "@every 5m",
+		"max_workers":                2,
+		"max_retries":                0,
+		"max_timeout":                15000,
+		"confirmation_blocks":        2,
+		"confirmation_timeout":       5000,
+		"confirmation_poll_interval": 1000,
+	}
+
+	bz, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = os.WriteFile(path, bz, 0600); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,113 @@
+package wtsc
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func (ec *EmailConfig) Name() string {
+	return "email"
+}
+
+// Send delivers the notification text as a plain text email
+func (ec *EmailConfig) Send(ctx context.Context, n *Notification) error {
+	address := net.JoinHostPort(ec.Host, strconv.Itoa(int(ec.Port)))
+
+	dialer := &net.Dialer{}
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return err
+	}
+	// net/smtp does not support context, so the deadline is set on the connection
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, ec.Host)
+	if err != nil {
+		_ = conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err = client.StartTLS(&tls.Config{ServerName: ec.Host}); err != nil {
+			return err
+		}
+	}
+
+	if !IsEmptyString(ec.Username) {
+		if err = client.Auth(smtp.PlainAuth("", ec.Username, ec.Password, ec.Host)); err != nil {
+			return err
+		}
+	}
+
+	if err = client.Mail(ec.From); err != nil {
+		return err
+	}
+	for _, to := range ec.To {
+		if err = client.Rcpt(to); err != nil {
+			return err
+		}
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err = w.Write(ec.message(n)); err != nil {
+		return err
+	}
+	if err = w.Close(); err != nil {
+		return err
+	}
+
+	return client.Quit()
+}
+
+func (ec *EmailConfig) message(n *Notification) []byte {
+	var msg bytes.Buffer
+	msg.WriteString(fmt.Sprintf("From: %s\r\n", ec.From))
+	msg.WriteString(fmt.Sprintf("To: %s\r\n", strings.Join(ec.To, ", ")))
+	msg.WriteString(fmt.Sprintf("Subject: [wtsc] %s %s\r\n", n.Event, n.Domain))
+	msg.WriteString(fmt.Sprintf("Date: %s\r\n", n.Time.Format(time.RFC1123Z)))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	msg.WriteString("\r\n")
+	msg.WriteString(strings.ReplaceAll(n.Text(), "\n", "\r\n"))
+	msg.WriteString("\r\n")
+	return msg.Bytes()
+}
+
+func CheckEmail(email []EmailConfig) (errs []*ConfigError) {
+	for i, ec := range email {
+		key := fmt.Sprintf("email[%d]", i)
+		if IsEmptyString(ec.Host) {
+			errs = append(errs, newConfigError(key+".host", nil, "is required"))
+		}
+		if ec.Port == 0 {
+			errs = append(errs, newConfigError(key+".port", ec.Port, "is required"))
+		}
+		if _, err := mail.ParseAddress(ec.From); err != nil {
+			errs = append(errs, newConfigError(key+".from", ec.From, "must be an email address"))
+		}
+		if len(ec.To) == 0 {
+			errs = append(errs, newConfigError(key+".to", nil, "must have at least one recipient"))
+		}
+		for j, to := range ec.To {
+			if _, err := mail.ParseAddress(to); err != nil {
+				errs = append(errs, newConfigError(fmt.Sprintf("%s.to[%d]", key, j), to, "must be an email address"))
+			}
+		}
+		errs = append(errs, CheckNotificationFilter(key, ec.NotificationFilter)...)
+	}
+	return errs
+}
@@ -0,0 +1,225 @@
+package wtsc
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/alitto/pond"
+	pocketGoProvider "github.com/pokt-foundation/pocket-go/provider"
+	pocketGoSigner "github.com/pokt-foundation/pocket-go/signer"
+	"github.com/pokt-scan/wtsc/wtsc/generated"
+	"github.com/puzpuzpuz/xsync"
+	"github.com/rs/zerolog"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+)
+
+var errTxNotFound = errors.New("tx not found")
+
+// fakeRpc is an in-memory PocketRpc. A submitted stake tx is included (and its chains applied) on the first lookup
+// that include allows, by default the first one.
+type fakeRpc struct {
+	mu       sync.Mutex
+	nodes    map[string]*pocketGoProvider.Node
+	balance  int64
+	height   int
+	sent     []string
+	pending  map[string]*DecodedStakeTx
+	included map[string]int
+	// include reports if the tx could be included, given the amount of txs sent so far
+	include func(sent int) bool
+	// heightErr makes every height query fail
+	heightErr error
+	// balanceErr makes every balance query fail
+	balanceErr error
+}
+
+func newFakeRpc() *fakeRpc {
+	return &fakeRpc{
+		nodes:    make(map[string]*pocketGoProvider.Node),
+		balance:  1000000000,
+		height:   1,
+		pending:  make(map[string]*DecodedStakeTx),
+		included: make(map[string]int),
+	}
+}
+
+// addNode stakes a node of the signer on chains
+func (f *fakeRpc) addNode(signer Signer, chains []string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.nodes[signer.GetAddress()] = &pocketGoProvider.Node{
+		Address:       signer.GetAddress(),
+		Chains:        chains,
+		PublicKey:     signer.GetPublicKey(),
+		ServiceURL:    "https://" + signer.GetAddress()[:8] + ".wtsc.test:443",
+		Status:        2,
+		Tokens:        "15000000000",
+		OutputAddress: signer.GetAddress(),
+	}
+}
+
+func (f *fakeRpc) chains(address string) []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.nodes[address].Chains
+}
+
+func (f *fakeRpc) sentTxs() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.sent)
+}
+
+func (f *fakeRpc) GetNodeWithCtx(_ context.Context, address string, _ *pocketGoProvider.GetNodeOptions) (*pocketGoProvider.GetNodeOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	node, ok := f.nodes[address]
+	if !ok {
+		return nil, fmt.Errorf("node %s not found", address)
+	}
+	n := *node
+	return &pocketGoProvider.GetNodeOutput{Node: &n}, nil
+}
+
+func (f *fakeRpc) GetTransactionWithCtx(_ context.Context, hash string, _ *pocketGoProvider.GetTransactionOptions) (*pocketGoProvider.GetTransactionOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if height, ok := f.included[hash]; ok {
+		return &pocketGoProvider.GetTransactionOutput{Transaction: &pocketGoProvider.Transaction{Hash: hash, Height: height, TxResult: &pocketGoProvider.TxResult{}}}, nil
+	}
+
+	tx, ok := f.pending[hash]
+	if !ok || (f.include != nil && !f.include(len(f.sent))) {
+		return nil, errTxNotFound
+	}
+
+	f.height++
+	f.included[hash] = f.height
+	delete(f.pending, hash)
+	node := *f.nodes[tx.Address]
+	node.Chains = tx.Chains
+	f.nodes[tx.Address] = &node
+	return &pocketGoProvider.GetTransactionOutput{Transaction: &pocketGoProvider.Transaction{Hash: hash, Height: f.height, TxResult: &pocketGoProvider.TxResult{}}}, nil
+}
+
+func (f *fakeRpc) GetBlockHeightWithCtx(_ context.Context) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.heightErr != nil {
+		return 0, f.heightErr
+	}
+	return f.height, nil
+}
+
+func (f *fakeRpc) GetBalanceWithCtx(_ context.Context, _ string, _ *pocketGoProvider.GetBalanceOptions) (*big.Int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.balanceErr != nil {
+		return nil, f.balanceErr
+	}
+	return big.NewInt(f.balance), nil
+}
+
+func (f *fakeRpc) SendTransactionWithCtx(_ context.Context, input *pocketGoProvider.SendTransactionInput) (*pocketGoProvider.SendTransactionOutput, error) {
+	txBytes, err := hex.DecodeString(input.RawHexBytes)
+	if err != nil {
+		return nil, err
+	}
+	tx, err := DecodeStakeTx(txBytes, "")
+	if err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	hash := TxHash(txBytes)
+	f.sent = append(f.sent, hash)
+	f.pending[hash] = tx
+	return &pocketGoProvider.SendTransactionOutput{Txhash: hash}, nil
+}
+
+// newTestSigner returns a local signer with a key derived from name, so the addresses are the same on each run
+func newTestSigner(t *testing.T, name string) Signer {
+	t.Helper()
+	seed := sha256.Sum256([]byte("wtsc-test-" + name))
+	signer, err := pocketGoSigner.NewSignerFromPrivateKey(hex.EncodeToString(ed25519.NewKeyFromSeed(seed[:])))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signer
+}
+
+func newTestSigners(signers ...Signer) *xsync.MapOf[string, Signer] {
+	m := xsync.NewMapOf[Signer]()
+	for _, signer := range signers {
+		m.Store(signer.GetAddress(), signer)
+	}
+	return m
+}
+
+// newTestApp returns an app without network nor globals, the history is disabled and the notifications dropped.
+// The workers are stopped at the end of the test.
+func newTestApp(t *testing.T, cfg *Config, rpc PocketRpc, signers SignerStore) *App {
+	t.Helper()
+	logger := zerolog.Nop()
+	workers := pond.New(int(cfg.MaxWorkers), 0)
+	t.Cleanup(workers.StopAndWait)
+	return &App{
+		Config:  func() *Config { return cfg },
+		Logger:  &logger,
+		Rpc:     rpc,
+		Signers: signers,
+		Clock:   SystemClock{},
+		Workers: workers,
+		Entropy: RandomEntropy,
+		State:   &RunState{},
+		History: func() *HistoryStore { return nil },
+		Notify:  func(*Config, *Notification) {},
+		Observe: func(*RunRecord) {},
+	}
+}
+
+// fakeWts answers what-to-stake with resp or err
+type fakeWts struct {
+	resp  *generated.GetWhatToStakeResponse
+	err   error
+	calls int
+}
+
+func (f *fakeWts) GetWhatToStake(_ context.Context, _ generated.WtsProcessRequestInput) (*generated.GetWhatToStakeResponse, error) {
+	f.calls++
+	return f.resp, f.err
+}
+
+// fakeClock is always at now
+type fakeClock struct {
+	now time.Time
+}
+
+func (c fakeClock) Now() time.Time {
+	return c.now
+}
+
+// notificationRecorder keeps the notifications of an app instead of sending them
+type notificationRecorder struct {
+	mu     sync.Mutex
+	events []NotificationEvent
+}
+
+func (r *notificationRecorder) Notify(_ *Config, n *Notification) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, n.Event)
+}
+
+func (r *notificationRecorder) Events() []NotificationEvent {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]NotificationEvent(nil), r.events...)
+}
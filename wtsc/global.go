@@ -3,11 +3,10 @@ package wtsc
 import (
 	"context"
 	"errors"
+	"fmt"
 	"github.com/Khan/genqlient/graphql"
 	"github.com/alitto/pond"
 	"github.com/hashicorp/go-retryablehttp"
-	pocketGoProvider "github.com/pokt-foundation/pocket-go/provider"
-	pocketGoSigner "github.com/pokt-foundation/pocket-go/signer"
 	pocketCoreCodec "github.com/pokt-network/pocket-core/codec"
 	"github.com/puzpuzpuz/xsync"
 	"github.com/robfig/cron/v3"
@@ -18,72 +17,56 @@ import (
 )
 
 var (
-	Logger            zerolog.Logger
-	HttpClient        *retryablehttp.Client
-	POKTscanApiClient *graphql.Client
-	PocketRpcProvider *pocketGoProvider.Provider
-	PocketCoreCodec   *pocketCoreCodec.Codec
-	WorkerPool        *pond.WorkerPool
-	CronJob           *cron.Cron
-	AppConfig         *Config
-	ServicersMap      *xsync.MapOf[string, *pocketGoSigner.Signer]
+	Version         string
+	Logger          zerolog.Logger
+	PocketCoreCodec *pocketCoreCodec.Codec
+	CronJob         *cron.Cron
+	HttpServer      *http.Server
+	AdminServer     *http.Server
 )
 
-// UpdateServicers adds new servicers to the servicers map and removes orphaned servicers from the map.
-func UpdateServicers(servicers []string) (int, error) {
+// UpdateServicers returns a new servicers map with the signers, the current one (nil on load) is not modified since
+// a run could be using it.
+func UpdateServicers(current *xsync.MapOf[string, Signer], signers []Signer) *xsync.MapOf[string, Signer] {
 	Logger.Info().Msg("updating signer map")
-	currentSignerMapSize := ServicersMap.Size()
+	servicers := xsync.NewMapOf[Signer]()
 
 	// add new
-	signers := make([]*pocketGoSigner.Signer, 0)
-	for i, key := range servicers {
-		signer, e := pocketGoSigner.NewSignerFromPrivateKey(key)
-		if e != nil {
-			return i, e
-		}
-		signers = append(signers, signer)
-	}
-
-	// do a second round of iter here to prevent update the map if we get error on any signer.
 	added := 0
 	for _, signer := range signers {
-		_, found := ServicersMap.LoadOrStore(signer.GetAddress(), signer)
-
-		if !found {
+		servicers.Store(signer.GetAddress(), signer)
+		if current == nil {
+			added++
+		} else if _, found := current.Load(signer.GetAddress()); !found {
 			added++
 			Logger.Debug().Str("address", signer.GetAddress()).Msg("signer added")
 		}
 	}
 
-	// remove orphans
-	toRemove := make([]string, 0)
-	ServicersMap.Range(func(key string, value *pocketGoSigner.Signer) bool {
-		found := FindStringInSlice(servicers, value.GetPrivateKey())
-		if !found {
-			toRemove = append(toRemove, value.GetAddress())
+	if current == nil {
+		Logger.Debug().Int("added", added).Msg("signer map loaded")
+		return servicers
+	}
+
+	// orphans are just left out
+	removed := 0
+	current.Range(func(address string, _ Signer) bool {
+		if _, found := servicers.Load(address); !found {
+			removed++
 		}
 		return true
 	})
+	Logger.Debug().
+		Int("added", added).
+		Int("removed", removed).
+		Msg("signer map updated")
 
-	for _, address := range toRemove {
-		ServicersMap.Delete(address)
-	}
-
-	if currentSignerMapSize > 0 {
-		Logger.Debug().
-			Int("added", added).
-			Int("removed", len(toRemove)).
-			Msg("signer map updated")
-	} else {
-		Logger.Debug().Int("added", added).Msg("signer map loaded")
-	}
-
-	return -1, nil
+	return servicers
 }
 
-func NewWorker(maxWorkers, maxCapacity uint) {
+func NewWorker(maxWorkers, maxCapacity uint) *pond.WorkerPool {
 	Logger.Info().Msg("preparing worker pool")
-	WorkerPool = pond.New(
+	return pond.New(
 		int(maxWorkers),                        // max amount of parallel workers (configurable by config.json)
 		int(maxCapacity),                       // max amount of tasks in queue before block, it will be the amount of servicers
 		pond.IdleTimeout(100*time.Millisecond), // remove unused workers after 100ms
@@ -97,19 +80,26 @@ func NewWorker(maxWorkers, maxCapacity uint) {
 	)
 }
 
-func NewSignerMap(servicerKeys []string) {
+// NewSignerMap loads the signers of cfg, it fails when any of them could not be loaded (i.e. a wrong key file
+// passphrase or an unreachable remote signer).
+func NewSignerMap(cfg *Config) (*xsync.MapOf[string, Signer], error) {
 	Logger.Info().Msg("preparing signer map")
-	ServicersMap = xsync.NewMapOf[*pocketGoSigner.Signer]()
-	if index, err := UpdateServicers(servicerKeys); err != nil {
-		// stop everything
-		Logger.Fatal().Err(err).Int("index", index).Msg("unable to update servicers")
+	signers, err := LoadSigners(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load servicers: %w", err)
 	}
+	return UpdateServicers(nil, signers), nil
 }
 
-func NewHttpClient(token string, maxRetries, maxTimeout uint) {
+// NewHttpClient returns the http client of the POKTscan api, authenticated with token
+func NewHttpClient(token string, maxRetries, maxTimeout uint) *retryablehttp.Client {
 	Logger.Info().Msg("preparing http client")
-	HttpClient = retryablehttp.NewClient()
-	HttpClient.CheckRetry = func(ctx context.Context, resp *http.Response, err error) (bool, error) {
+	httpClient := retryablehttp.NewClient()
+	httpClient.CheckRetry = func(ctx context.Context, resp *http.Response, err error) (bool, error) {
+		if resp != nil {
+			ObserveRateLimit(resp)
+		}
+
 		if err != nil || resp.StatusCode == http.StatusOK {
 			return false, err
 		}
@@ -140,18 +130,19 @@ func NewHttpClient(token string, maxRetries, maxTimeout uint) {
 
 		return true, nil
 	}
-	HttpClient.RetryMax = int(maxRetries)
-	HttpClient.Logger = NewZerologLeveledLogger(Logger)
-	HttpClient.HTTPClient.Timeout = time.Duration(maxTimeout) * time.Millisecond
-	HttpClient.HTTPClient.Transport = &AuthedTransport{
+	httpClient.RetryMax = int(maxRetries)
+	httpClient.Logger = NewZerologLeveledLogger(Logger)
+	httpClient.HTTPClient.Timeout = time.Duration(maxTimeout) * time.Millisecond
+	httpClient.HTTPClient.Transport = &AuthedTransport{
 		token: token,
 		// wrap it to add authorization header on each request to poktscan api
-		wrapped: HttpClient.HTTPClient.Transport,
+		wrapped: httpClient.HTTPClient.Transport,
 	}
+	return httpClient
 }
 
-func NewPOKTscanClient(url string) {
+// NewPOKTscanClient returns the what-to-stake client of the POKTscan api, on top of httpClient
+func NewPOKTscanClient(url string, httpClient *retryablehttp.Client) WtsClient {
 	Logger.Info().Msg("preparing poktscan api client")
-	gClient := graphql.NewClient(url, HttpClient.StandardClient())
-	POKTscanApiClient = &gClient
+	return POKTscanWtsClient{Client: graphql.NewClient(url, httpClient.StandardClient())}
 }
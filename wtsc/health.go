@@ -0,0 +1,241 @@
+package wtsc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/robfig/cron/v3"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// reachabilityCacheTTL avoids hitting POKTscan API and pocket rpc on every probe, POKTscan calls spend credits.
+	reachabilityCacheTTL = 30 * time.Second
+	reachabilityTimeout  = 5 * time.Second
+	redactedValue        = "REDACTED"
+)
+
+var (
+	cronRunning atomic.Bool
+	// evaluationEntry is the cron entry of the evaluation job, other entries (digest) are not part of the status
+	evaluationEntry cron.EntryID
+
+	poktscanReachability  = &reachabilityCheck{check: checkPOKTscanApi}
+	pocketRpcReachability = &reachabilityCheck{check: checkPocketRpc}
+)
+
+// reachabilityCheck caches the result of a remote check during reachabilityCacheTTL
+type reachabilityCheck struct {
+	mu        sync.Mutex
+	checkedAt time.Time
+	err       error
+	check     func(ctx context.Context) error
+}
+
+func (rc *reachabilityCheck) Err() error {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	if time.Since(rc.checkedAt) < reachabilityCacheTTL {
+		return rc.err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), reachabilityTimeout)
+	defer cancel()
+
+	rc.err = rc.check(ctx)
+	rc.checkedAt = time.Now()
+	return rc.err
+}
+
+func checkPOKTscanApi(ctx context.Context) error {
+	cfg := GetConfig()
+	body := bytes.NewBufferString(`{"query":"{__typename}"}`)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, cfg.POKTscanApi, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", cfg.POKTscanApiToken)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	_ = resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return errors.New(resp.Status)
+	}
+	return nil
+}
+
+func checkPocketRpc(ctx context.Context) error {
+	_, err := DefaultApp.Rpc.GetBlockHeightWithCtx(ctx)
+	return err
+}
+
+type StatusResponse struct {
+	Version     string     `json:"version"`
+	CronRunning bool       `json:"cron_running"`
+	Running     bool       `json:"running"`
+	Paused      bool       `json:"paused"`
+	NextRun     *time.Time `json:"next_run,omitempty"`
+	LastRun     *RunStatus `json:"last_run,omitempty"`
+	// ConfigVersion and ConfigHash identify the config snapshot in use
+	ConfigVersion uint64  `json:"config_version"`
+	ConfigHash    string  `json:"config_hash"`
+	Config        *Config `json:"config"`
+}
+
+type RunStatus struct {
+	ID         string         `json:"id"`
+	StartedAt  time.Time      `json:"started_at"`
+	FinishedAt time.Time      `json:"finished_at"`
+	Outcome    RunOutcome     `json:"outcome"`
+	ConfigHash string         `json:"config_hash"`
+	Error      string         `json:"error,omitempty"`
+	Results    map[string]int `json:"results,omitempty"`
+}
+
+// RedactConfig returns a copy of the config without secrets
+func RedactConfig(cfg *Config) *Config {
+	redacted := *cfg
+	if !IsEmptyString(redacted.POKTscanApiToken) {
+		redacted.POKTscanApiToken = redactedValue
+	}
+	if !IsEmptyString(redacted.AdminToken) {
+		redacted.AdminToken = redactedValue
+	}
+	if !IsEmptyString(redacted.RemoteSignerToken) {
+		redacted.RemoteSignerToken = redactedValue
+	}
+	redacted.Webhooks = make([]WebhookConfig, len(cfg.Webhooks))
+	for i, webhook := range cfg.Webhooks {
+		// webhook urls usually carry the secret (slack, discord)
+		webhook.URL = redactedValue
+		redacted.Webhooks[i] = webhook
+	}
+	redacted.Telegram = make([]TelegramConfig, len(cfg.Telegram))
+	for i, telegram := range cfg.Telegram {
+		telegram.BotToken = redactedValue
+		redacted.Telegram[i] = telegram
+	}
+	redacted.Email = make([]EmailConfig, len(cfg.Email))
+	for i, email := range cfg.Email {
+		if !IsEmptyString(email.Password) {
+			email.Password = redactedValue
+		}
+		redacted.Email[i] = email
+	}
+	redacted.ServicerKeys = make([]string, len(cfg.ServicerKeys))
+	for i := range cfg.ServicerKeys {
+		redacted.ServicerKeys[i] = redactedValue
+	}
+	return &redacted
+}
+
+func writeJson(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		Logger.Error().Err(err).Msg("failed to write http response")
+	}
+}
+
+// HealthzHandler reports if the process is alive and the cron is running
+func HealthzHandler(w http.ResponseWriter, _ *http.Request) {
+	if !cronRunning.Load() {
+		writeJson(w, http.StatusServiceUnavailable, map[string]string{"status": "cron is not running"})
+		return
+	}
+	writeJson(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// ReadyzHandler reports if the config is loaded, the remote services are reachable and the signers are loaded
+func ReadyzHandler(w http.ResponseWriter, _ *http.Request) {
+	checks := make(map[string]string)
+	ready := true
+
+	fail := func(name string, err error) {
+		ready = false
+		checks[name] = err.Error()
+	}
+
+	cfg := GetConfig()
+	if cfg == nil {
+		fail("config", errors.New("config not loaded"))
+	} else {
+		checks["config"] = "ok"
+
+		if err := poktscanReachability.Err(); err != nil {
+			fail("poktscan_api", err)
+		} else {
+			checks["poktscan_api"] = "ok"
+		}
+
+		if err := pocketRpcReachability.Err(); err != nil {
+			fail("pocket_rpc", err)
+		} else {
+			checks["pocket_rpc"] = "ok"
+		}
+
+		if rt := GetRuntime(); rt == nil || rt.Signers == nil {
+			fail("signers", errors.New("signer map not loaded"))
+		} else if rt.Signers.Size() == 0 && !cfg.DryMode && len(cfg.ServicerAddresses) == 0 {
+			fail("signers", errors.New("signer map is empty"))
+		} else {
+			checks["signers"] = fmt.Sprintf("%d loaded", rt.Signers.Size())
+		}
+	}
+
+	code := http.StatusOK
+	if !ready {
+		code = http.StatusServiceUnavailable
+	}
+	writeJson(w, code, checks)
+}
+
+// StatusHandler shows last run, next run and the effective config without secrets
+func StatusHandler(w http.ResponseWriter, _ *http.Request) {
+	cfg := GetConfig()
+	status := StatusResponse{
+		Version:       Version,
+		CronRunning:   cronRunning.Load(),
+		Running:       DefaultApp.State.Running(),
+		Paused:        DefaultApp.State.Paused(),
+		ConfigVersion: cfg.Version,
+		ConfigHash:    cfg.Hash,
+		Config:        RedactConfig(cfg),
+	}
+
+	if CronJob != nil {
+		if next := CronJob.Entry(evaluationEntry).Next; !next.IsZero() {
+			status.NextRun = &next
+		}
+	}
+
+	if run := DefaultApp.State.LastRun(); run != nil {
+		status.LastRun = &RunStatus{
+			ID:         run.ID,
+			StartedAt:  run.StartedAt,
+			FinishedAt: run.FinishedAt,
+			Outcome:    run.Outcome,
+			ConfigHash: run.ConfigHash,
+			Error:      run.Error,
+		}
+		if len(run.Results) > 0 {
+			status.LastRun.Results = make(map[string]int)
+			for _, result := range run.Results {
+				status.LastRun.Results[string(result.Status)]++
+			}
+		}
+	}
+
+	writeJson(w, http.StatusOK, status)
+}
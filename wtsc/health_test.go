@@ -0,0 +1,190 @@
+package wtsc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// useTestRuntime publishes rt and the given reachability checks until the end of the test
+func useTestRuntime(t *testing.T, rt *Runtime, poktscanErr, pocketRpcErr error) {
+	t.Helper()
+	oldRuntime, oldPOKTscan, oldPocketRpc := GetRuntime(), poktscanReachability, pocketRpcReachability
+	t.Cleanup(func() {
+		currentRuntime.Store(oldRuntime)
+		poktscanReachability, pocketRpcReachability = oldPOKTscan, oldPocketRpc
+	})
+	currentRuntime.Store(rt)
+	poktscanReachability = &reachabilityCheck{check: func(context.Context) error { return poktscanErr }}
+	pocketRpcReachability = &reachabilityCheck{check: func(context.Context) error { return pocketRpcErr }}
+}
+
+func serveHealth(t *testing.T, handler http.HandlerFunc, v any) int {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil {
+		t.Fatalf("invalid response %q: %s", rec.Body.String(), err)
+	}
+	return rec.Code
+}
+
+func TestHealthzHandler(t *testing.T) {
+	old := cronRunning.Load()
+	t.Cleanup(func() { cronRunning.Store(old) })
+
+	for _, running := range []bool{false, true} {
+		cronRunning.Store(running)
+		expected := http.StatusServiceUnavailable
+		if running {
+			expected = http.StatusOK
+		}
+		if code := serveHealth(t, HealthzHandler, &map[string]string{}); code != expected {
+			t.Fatalf("got %d with cron running %t, expected %d", code, running, expected)
+		}
+	}
+}
+
+func TestReadyzHandler(t *testing.T) {
+	signer := newTestSigner(t, "0")
+	cases := []struct {
+		name         string
+		rt           *Runtime
+		poktscanErr  error
+		pocketRpcErr error
+		failed       []string
+	}{
+		{name: "ready", rt: &Runtime{Config: &Config{}, Signers: newTestSigners(signer)}},
+		{name: "no config", failed: []string{"config"}},
+		{name: "no signers", rt: &Runtime{Config: &Config{}}, failed: []string{"signers"}},
+		{name: "empty signers", rt: &Runtime{Config: &Config{}, Signers: newTestSigners()}, failed: []string{"signers"}},
+		{name: "empty signers on dry mode", rt: &Runtime{Config: &Config{DryMode: true}, Signers: newTestSigners()}},
+		{name: "empty signers with watch only", rt: &Runtime{Config: &Config{ServicerAddresses: []string{signer.GetAddress()}}, Signers: newTestSigners()}},
+		{
+			name:         "unreachable",
+			rt:           &Runtime{Config: &Config{}, Signers: newTestSigners(signer)},
+			poktscanErr:  errors.New("401 Unauthorized"),
+			pocketRpcErr: errors.New("connection refused"),
+			failed:       []string{"poktscan_api", "pocket_rpc"},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			useTestRuntime(t, c.rt, c.poktscanErr, c.pocketRpcErr)
+
+			checks := make(map[string]string)
+			code := serveHealth(t, ReadyzHandler, &checks)
+			expected := http.StatusOK
+			if len(c.failed) > 0 {
+				expected = http.StatusServiceUnavailable
+			}
+			if code != expected {
+				t.Fatalf("got %d %v, expected %d", code, checks, expected)
+			}
+			for _, name := range c.failed {
+				if checks[name] == "" || checks[name] == "ok" {
+					t.Errorf("check %s is %q, expected a failure", name, checks[name])
+				}
+			}
+		})
+	}
+}
+
+func TestReachabilityCheckIsCached(t *testing.T) {
+	calls := 0
+	rc := &reachabilityCheck{check: func(context.Context) error {
+		calls++
+		return errors.New("down")
+	}}
+
+	for i := 0; i < 3; i++ {
+		if err := rc.Err(); err == nil {
+			t.Fatal("expected the error of the check")
+		}
+	}
+	if calls != 1 {
+		t.Fatalf("checked %d times, expected the cached result", calls)
+	}
+
+	rc.checkedAt = time.Now().Add(-reachabilityCacheTTL)
+	_ = rc.Err()
+	if calls != 2 {
+		t.Fatalf("checked %d times, expected a new check once expired", calls)
+	}
+}
+
+func TestCheckPOKTscanApi(t *testing.T) {
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+
+	useTestRuntime(t, &Runtime{Config: &Config{POKTscanApi: server.URL, POKTscanApiToken: "token"}}, nil, nil)
+	if err := checkPOKTscanApi(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	status = http.StatusTooManyRequests
+	if err := checkPOKTscanApi(context.Background()); err == nil {
+		t.Fatal("expected the status as error")
+	}
+}
+
+func TestRedactConfig(t *testing.T) {
+	cfg := &Config{
+		POKTscanApiToken: "api-token",
+		AdminToken:       "admin-token",
+		ServicerKeys:     []string{"key"},
+		Webhooks:         []WebhookConfig{{URL: "https://hooks.slack.test/secret"}},
+		Telegram:         []TelegramConfig{{BotToken: "bot-token"}},
+		Email:            []EmailConfig{{Password: "password"}, {}},
+	}
+
+	redacted := RedactConfig(cfg)
+	if redacted.POKTscanApiToken != redactedValue || redacted.AdminToken != redactedValue || redacted.RemoteSignerToken != "" ||
+		redacted.ServicerKeys[0] != redactedValue || redacted.Webhooks[0].URL != redactedValue ||
+		redacted.Telegram[0].BotToken != redactedValue || redacted.Email[0].Password != redactedValue || redacted.Email[1].Password != "" {
+		t.Fatalf("secrets are not redacted: %+v", redacted)
+	}
+	if cfg.POKTscanApiToken != "api-token" || cfg.ServicerKeys[0] != "key" || cfg.Webhooks[0].URL == redactedValue ||
+		cfg.Telegram[0].BotToken != "bot-token" || cfg.Email[0].Password != "password" {
+		t.Fatal("the config was modified")
+	}
+}
+
+func TestStatusHandler(t *testing.T) {
+	oldState := DefaultApp.State
+	t.Cleanup(func() { DefaultApp.State = oldState })
+	DefaultApp.State = &RunState{}
+	DefaultApp.State.SetPaused(true)
+	DefaultApp.State.last.Store(&RunRecord{
+		ID:      "run",
+		Outcome: RunOutcomeCompleted,
+		Results: []*ServicerResult{{Status: TxStatusSubmitted}, {Status: TxStatusSubmitted}, {Status: TxStatusNoChange}},
+	})
+	useTestRuntime(t, &Runtime{Config: &Config{Version: 3, Hash: "hash", AdminToken: "admin-token"}}, nil, nil)
+
+	status := StatusResponse{}
+	if code := serveHealth(t, StatusHandler, &status); code != http.StatusOK {
+		t.Fatalf("got %d", code)
+	}
+	if !status.Paused || status.Running || status.ConfigVersion != 3 || status.ConfigHash != "hash" {
+		t.Fatalf("unexpected status %+v", status)
+	}
+	if status.Config.AdminToken != redactedValue {
+		t.Fatal("the status shows the admin token")
+	}
+	if status.LastRun == nil || status.LastRun.ID != "run" || status.LastRun.Results["submitted"] != 2 || status.LastRun.Results["no_change"] != 1 {
+		t.Fatalf("unexpected last run %+v", status.LastRun)
+	}
+}
@@ -0,0 +1,251 @@
+package wtsc
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/pokt-scan/wtsc/wtsc/generated"
+	bolt "go.etcd.io/bbolt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	// RecordIDFormat is sortable, so the history buckets are iterated in chronological order
+	RecordIDFormat = "20060102T150405.000000000"
+	// historyOpenTimeout is how long we wait for the file lock held by another process (daemon or cli)
+	historyOpenTimeout = 5 * time.Second
+)
+
+var (
+	historyRunsBucket  = []byte("runs")
+	historyPlansBucket = []byte("plans")
+	// ErrRecordNotFound is returned when the requested id is not on the history store
+	ErrRecordNotFound = errors.New("record not found")
+)
+
+type RunOutcome string
+
+const (
+	// RunOutcomeWtsFailed the call to what-to-stake service failed
+	RunOutcomeWtsFailed RunOutcome = "wts_failed"
+	// RunOutcomeDryMode the recommendation was computed but dry mode prevents the stake txs
+	RunOutcomeDryMode RunOutcome = "dry_mode"
+	// RunOutcomeWatchOnly the recommendation was compared with the on-chain state but there are no signers
+	RunOutcomeWatchOnly RunOutcome = "watch_only"
+	// RunOutcomePaused the recommendation was computed but stake txs are paused by the admin api
+	RunOutcomePaused RunOutcome = "paused"
+	// RunOutcomeNoUpdate what-to-stake does not recommend an update
+	RunOutcomeNoUpdate RunOutcome = "no_update"
+	// RunOutcomePlanPending the stake plan was persisted and is waiting for approval
+	RunOutcomePlanPending RunOutcome = "plan_pending"
+	// RunOutcomePlanFailed the stake plan needs approval but could not be persisted, nothing was sent
+	RunOutcomePlanFailed RunOutcome = "plan_failed"
+	// RunOutcomeApplied the servicers of an approved plan were processed, check the results for each one
+	RunOutcomeApplied RunOutcome = "applied"
+	// RunOutcomeBroadcast the txs of an exported bundle were processed, check the results for each one
+	RunOutcomeBroadcast RunOutcome = "broadcast"
+	// RunOutcomeCompleted the servicers were processed, check the results for each one
+	RunOutcomeCompleted RunOutcome = "completed"
+)
+
+// RunRecord is everything we know about a single evaluation run.
+type RunRecord struct {
+	ID         string    `json:"id"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	DurationMs int64     `json:"duration_ms"`
+	WtsMs      int64     `json:"wts_ms"`
+	DryMode    bool      `json:"dry_mode"`
+	// ConfigVersion and ConfigHash identify the config snapshot used by the whole run
+	ConfigVersion uint64                            `json:"config_version"`
+	ConfigHash    string                            `json:"config_hash"`
+	Outcome       RunOutcome                        `json:"outcome"`
+	PlanID        string                            `json:"plan_id,omitempty"`
+	Plan          *Plan                             `json:"plan,omitempty"`
+	Error         string                            `json:"error,omitempty"`
+	Input         generated.WtsProcessRequestInput  `json:"input"`
+	Response      *generated.GetWhatToStakeResponse `json:"response,omitempty"`
+	Results       []*ServicerResult                 `json:"results,omitempty"`
+}
+
+// NewRecordID returns a sortable id based on the given time
+func NewRecordID(t time.Time) string {
+	return t.UTC().Format(RecordIDFormat)
+}
+
+func NewRunRecord(now time.Time) *RunRecord {
+	return &RunRecord{
+		ID:        NewRecordID(now),
+		StartedAt: now,
+	}
+}
+
+// Finish sets the outcome and the timings of the run
+func (r *RunRecord) Finish(outcome RunOutcome, now time.Time) {
+	r.Outcome = outcome
+	r.FinishedAt = now
+	r.DurationMs = r.FinishedAt.Sub(r.StartedAt).Milliseconds()
+}
+
+// HistoryStore is an embedded bolt database with a journal of the evaluation runs.
+// The database is opened on each operation, so the cli is able to read it while the daemon is running.
+type HistoryStore struct {
+	path string
+}
+
+// NewHistoryStore returns the store of path (relative to the project root), nil when the history is disabled
+func NewHistoryStore(path string) *HistoryStore {
+	if IsEmptyString(path) {
+		Logger.Info().Msg("run history is disabled")
+		return nil
+	}
+	Logger.Info().Msg("preparing run history store")
+	return &HistoryStore{path: filepath.Join(ProjectRoot, path)}
+}
+
+func (hs *HistoryStore) open(readOnly bool) (*bolt.DB, error) {
+	if _, err := os.Stat(hs.path); readOnly && os.IsNotExist(err) {
+		// nothing was recorded yet
+		return nil, ErrRecordNotFound
+	}
+	return bolt.Open(hs.path, 0600, &bolt.Options{Timeout: historyOpenTimeout, ReadOnly: readOnly})
+}
+
+// SaveRun stores (or replace) the run record
+func (hs *HistoryStore) SaveRun(run *RunRecord) error {
+	return hs.put(historyRunsBucket, run.ID, run)
+}
+
+// ListRuns returns the latest runs first, up to limit. Zero limit returns all of them.
+func (hs *HistoryStore) ListRuns(limit int) (runs []*RunRecord, err error) {
+	err = hs.list(historyRunsBucket, limit, func(v []byte) error {
+		run := &RunRecord{}
+		if e := json.Unmarshal(v, run); e != nil {
+			return e
+		}
+		runs = append(runs, run)
+		return nil
+	})
+	return
+}
+
+// ListRunsSince returns the runs started after t, the oldest first
+func (hs *HistoryStore) ListRunsSince(t time.Time) (runs []*RunRecord, err error) {
+	err = hs.listFrom(historyRunsBucket, NewRecordID(t), func(v []byte) error {
+		run := &RunRecord{}
+		if e := json.Unmarshal(v, run); e != nil {
+			return e
+		}
+		runs = append(runs, run)
+		return nil
+	})
+	return
+}
+
+// GetRun returns a single run by id
+func (hs *HistoryStore) GetRun(id string) (*RunRecord, error) {
+	run := &RunRecord{}
+	if err := hs.get(historyRunsBucket, id, run); err != nil {
+		return nil, err
+	}
+	return run, nil
+}
+
+func (hs *HistoryStore) put(bucketName []byte, key string, v any) error {
+	bz, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	db, err := hs.open(false)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	return db.Update(func(tx *bolt.Tx) error {
+		bucket, e := tx.CreateBucketIfNotExists(bucketName)
+		if e != nil {
+			return e
+		}
+		return bucket.Put([]byte(key), bz)
+	})
+}
+
+func (hs *HistoryStore) get(bucketName []byte, key string, v any) error {
+	db, err := hs.open(true)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	return db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(bucketName)
+		if bucket == nil {
+			return ErrRecordNotFound
+		}
+		bz := bucket.Get([]byte(key))
+		if bz == nil {
+			return ErrRecordNotFound
+		}
+		return json.Unmarshal(bz, v)
+	})
+}
+
+// list iterates the bucket from the latest record to the oldest one, up to limit. Zero limit iterates all of them.
+func (hs *HistoryStore) list(bucketName []byte, limit int, fn func(v []byte) error) error {
+	db, err := hs.open(true)
+	if errors.Is(err, ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	return db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(bucketName)
+		if bucket == nil {
+			return nil
+		}
+		c := bucket.Cursor()
+		count := 0
+		for k, v := c.Last(); k != nil; k, v = c.Prev() {
+			if limit > 0 && count >= limit {
+				break
+			}
+			if e := fn(v); e != nil {
+				return e
+			}
+			count++
+		}
+		return nil
+	})
+}
+
+// listFrom iterates the bucket from the first key equal or greater than fromKey to the latest record
+func (hs *HistoryStore) listFrom(bucketName []byte, fromKey string, fn func(v []byte) error) error {
+	db, err := hs.open(true)
+	if errors.Is(err, ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	return db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(bucketName)
+		if bucket == nil {
+			return nil
+		}
+		c := bucket.Cursor()
+		for k, v := c.Seek([]byte(fromKey)); k != nil; k, v = c.Next() {
+			if e := fn(v); e != nil {
+				return e
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,166 @@
+package wtsc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	pocketGoSigner "github.com/pokt-foundation/pocket-go/signer"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	// KeyFilePassphraseEnv is the env var used to get the key files passphrase when keyfile_passphrase_file is not set
+	KeyFilePassphraseEnv = "WTSC_KEYFILE_PASSPHRASE"
+)
+
+var ErrMissingPassphrase = errors.New("key files are configured but there is no passphrase")
+
+// HasKeyFiles reports if the config has any encrypted key file source
+func HasKeyFiles(cfg *Config) bool {
+	return len(cfg.ServicerKeyFiles) > 0 || !IsEmptyString(cfg.ServicerKeyFilesDir)
+}
+
+// GetKeyFilePaths returns the key files of servicer_keyfiles plus the *.json files inside servicer_keyfiles_dir
+func GetKeyFilePaths(cfg *Config) ([]string, error) {
+	paths := make([]string, 0, len(cfg.ServicerKeyFiles))
+	for _, path := range cfg.ServicerKeyFiles {
+		paths = append(paths, resolvePath(path))
+	}
+
+	if IsEmptyString(cfg.ServicerKeyFilesDir) {
+		return paths, nil
+	}
+
+	dirPaths, err := filepath.Glob(filepath.Join(resolvePath(cfg.ServicerKeyFilesDir), "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(dirPaths)
+
+	return append(paths, dirPaths...), nil
+}
+
+// GetKeyFilePassphrase reads the passphrase from keyfile_passphrase_file or WTSC_KEYFILE_PASSPHRASE env var
+func GetKeyFilePassphrase(cfg *Config) (string, error) {
+	if !IsEmptyString(cfg.KeyFilePassphraseFile) {
+		bz, err := os.ReadFile(resolvePath(cfg.KeyFilePassphraseFile))
+		if err != nil {
+			return "", err
+		}
+		// secret files usually end with a new line
+		return strings.TrimRight(string(bz), "\r\n"), nil
+	}
+
+	if passphrase := os.Getenv(KeyFilePassphraseEnv); !IsEmptyString(passphrase) {
+		return passphrase, nil
+	}
+
+	return "", ErrMissingPassphrase
+}
+
+// ReadKeyFile reads an armored key file as exported by `pocket accounts export`
+func ReadKeyFile(path string) (*pocketGoSigner.PPK, error) {
+	bz, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	ppk := &pocketGoSigner.PPK{}
+	if err = json.Unmarshal(bz, ppk); err != nil {
+		return nil, err
+	}
+
+	if !ppk.Validate() {
+		return nil, pocketGoSigner.ErrInvalidPPK
+	}
+
+	return ppk, nil
+}
+
+// CheckKeyFiles checks that every key file could be read and there is a passphrase to decrypt them.
+// Decryption is expensive (scrypt), so it is done only when the signers are loaded.
+func CheckKeyFiles(cfg *Config) (errs []*ConfigError) {
+	if !HasKeyFiles(cfg) {
+		return nil
+	}
+
+	paths, err := GetKeyFilePaths(cfg)
+	if err != nil {
+		return []*ConfigError{newConfigError("servicer_keyfiles_dir", cfg.ServicerKeyFilesDir, "must be a readable directory: "+err.Error())}
+	}
+
+	for _, path := range paths {
+		if _, e := ReadKeyFile(path); e != nil {
+			errs = append(errs, newConfigError("servicer_keyfiles", path, "must be a valid key file: "+e.Error()))
+		}
+	}
+
+	if _, err = GetKeyFilePassphrase(cfg); err != nil {
+		errs = append(errs, newConfigError("keyfile_passphrase_file", cfg.KeyFilePassphraseFile, err.Error()))
+	}
+	return errs
+}
+
+// LoadSigners returns the signers of every configured source: plain keys, key files, key files directory
+// and remote signer.
+func LoadSigners(cfg *Config) ([]Signer, error) {
+	signers := make([]Signer, 0, len(cfg.ServicerKeys))
+
+	for i, key := range cfg.ServicerKeys {
+		signer, err := pocketGoSigner.NewSignerFromPrivateKey(key)
+		if err != nil {
+			return nil, fmt.Errorf("servicer_keys[%d]: %w", i, err)
+		}
+		signers = append(signers, signer)
+	}
+
+	if !IsEmptyString(cfg.RemoteSignerURL) {
+		remoteSigners, err := LoadRemoteSigners(cfg.RemoteSignerURL, cfg.RemoteSignerToken, cfg.MaxTimeout)
+		if err != nil {
+			return nil, err
+		}
+		signers = append(signers, remoteSigners...)
+	}
+
+	if !HasKeyFiles(cfg) {
+		return signers, nil
+	}
+
+	paths, err := GetKeyFilePaths(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	passphrase, err := GetKeyFilePassphrase(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, path := range paths {
+		ppk, e := ReadKeyFile(path)
+		if e != nil {
+			return nil, fmt.Errorf("%s: %w", path, e)
+		}
+
+		signer, e := pocketGoSigner.NewSignerFromPPK(passphrase, ppk)
+		if e != nil {
+			return nil, fmt.Errorf("%s: unable to decrypt key file: %w", path, e)
+		}
+
+		Logger.Debug().Str("path", path).Str("address", signer.GetAddress()).Msg("key file decrypted")
+		signers = append(signers, signer)
+	}
+
+	return signers, nil
+}
+
+// resolvePath joins relative paths to the project root
+func resolvePath(path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(ProjectRoot, path)
+}
@@ -0,0 +1,154 @@
+package wtsc
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// the key files of testdata/keyfiles are the keys of newTestSigner("keyfile-0") and newTestSigner("keyfile-1"),
+// encrypted with the passphrase of testdata/keyfile_passphrase.txt
+const testKeyFilePassphrase = "wtsc-test-passphrase"
+
+// useTestProjectRoot resolves the relative paths of the config to the package directory during the test
+func useTestProjectRoot(t *testing.T) {
+	t.Helper()
+	old := ProjectRoot
+	t.Cleanup(func() { ProjectRoot = old })
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ProjectRoot = wd
+}
+
+func signerAddresses(signers []Signer) []string {
+	addresses := make([]string, 0, len(signers))
+	for _, signer := range signers {
+		addresses = append(addresses, signer.GetAddress())
+	}
+	return addresses
+}
+
+func TestLoadSignersFromKeyFiles(t *testing.T) {
+	useTestProjectRoot(t)
+	t.Setenv(KeyFilePassphraseEnv, "")
+	expected := []string{newTestSigner(t, "keyfile-0").GetAddress(), newTestSigner(t, "keyfile-1").GetAddress()}
+
+	cases := []struct {
+		name string
+		cfg  *Config
+	}{
+		{name: "key files", cfg: &Config{ServicerKeyFiles: []string{"testdata/keyfiles/keyfile-0.json", "testdata/keyfiles/keyfile-1.json"}}},
+		{name: "key files dir", cfg: &Config{ServicerKeyFilesDir: "testdata/keyfiles"}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			c.cfg.KeyFilePassphraseFile = "testdata/keyfile_passphrase.txt"
+			signers, err := LoadSigners(c.cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := signerAddresses(signers); strings.Join(got, ",") != strings.Join(expected, ",") {
+				t.Fatalf("got signers %v, expected %v", got, expected)
+			}
+		})
+	}
+}
+
+func TestLoadSignersWrongPassphrase(t *testing.T) {
+	useTestProjectRoot(t)
+	t.Setenv(KeyFilePassphraseEnv, "wrong")
+
+	_, err := LoadSigners(&Config{ServicerKeyFiles: []string{"testdata/keyfiles/keyfile-0.json"}})
+	if err == nil || !strings.Contains(err.Error(), "unable to decrypt key file") {
+		t.Fatalf("got %v, expected a decrypt error", err)
+	}
+}
+
+func TestGetKeyFilePassphrase(t *testing.T) {
+	useTestProjectRoot(t)
+	cases := []struct {
+		name     string
+		file     string
+		env      string
+		expected string
+		err      bool
+	}{
+		{name: "file", file: "testdata/keyfile_passphrase.txt", expected: testKeyFilePassphrase},
+		{name: "env", env: "from-env", expected: "from-env"},
+		{name: "file over env", file: "testdata/keyfile_passphrase.txt", env: "from-env", expected: testKeyFilePassphrase},
+		{name: "missing file", file: "testdata/missing.txt", env: "from-env", err: true},
+		{name: "none", err: true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			t.Setenv(KeyFilePassphraseEnv, c.env)
+			passphrase, err := GetKeyFilePassphrase(&Config{KeyFilePassphraseFile: c.file})
+			if c.err {
+				if err == nil {
+					t.Fatalf("got %q, expected an error", passphrase)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if passphrase != c.expected {
+				t.Fatalf("got %q, expected %q", passphrase, c.expected)
+			}
+		})
+	}
+
+	t.Setenv(KeyFilePassphraseEnv, "")
+	if _, err := GetKeyFilePassphrase(&Config{}); !errors.Is(err, ErrMissingPassphrase) {
+		t.Fatalf("got %v, expected %s", err, ErrMissingPassphrase)
+	}
+}
+
+func TestGetKeyFilePaths(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"b.json", "a.json", "notes.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("{}"), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	paths, err := GetKeyFilePaths(&Config{ServicerKeyFiles: []string{"/keys/z.json"}, ServicerKeyFilesDir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"/keys/z.json", filepath.Join(dir, "a.json"), filepath.Join(dir, "b.json")}
+	if strings.Join(paths, ",") != strings.Join(expected, ",") {
+		t.Fatalf("got %v, expected %v", paths, expected)
+	}
+}
+
+func TestCheckKeyFiles(t *testing.T) {
+	useTestProjectRoot(t)
+	t.Setenv(KeyFilePassphraseEnv, "")
+	invalid := filepath.Join(t.TempDir(), "invalid.json")
+	if err := os.WriteFile(invalid, []byte(`{"kdf":"scrypt"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if errs := CheckKeyFiles(&Config{}); len(errs) != 0 {
+		t.Fatalf("got %v without key files", errs)
+	}
+	if errs := CheckKeyFiles(&Config{ServicerKeyFilesDir: "testdata/keyfiles", KeyFilePassphraseFile: "testdata/keyfile_passphrase.txt"}); len(errs) != 0 {
+		t.Fatalf("got %v, expected valid key files", errs)
+	}
+
+	errs := CheckKeyFiles(&Config{ServicerKeyFiles: []string{"testdata/keyfiles/keyfile-0.json", invalid}})
+	keys := make([]string, 0, len(errs))
+	for _, e := range errs {
+		keys = append(keys, e.Key)
+	}
+	if strings.Join(keys, ",") != "servicer_keyfiles,keyfile_passphrase_file" {
+		t.Fatalf("got errors %v, expected the invalid key file and the missing passphrase", errs)
+	}
+}
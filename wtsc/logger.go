@@ -5,6 +5,7 @@ import (
 	"github.com/rs/zerolog/diode"
 	"github.com/rs/zerolog/log"
 	"github.com/rs/zerolog/pkgerrors"
+	"io"
 	"os"
 	"time"
 )
@@ -14,6 +15,9 @@ const (
 	LogTextFormat = "text"
 )
 
+// LogOutput is where the logs are written. Commands that print a result on stdout move the logs to stderr.
+var LogOutput io.Writer = os.Stdout
+
 // ZerologLeveledLogger is an implementation of retryablehttp.LeveledLogger using zerolog
 type ZerologLeveledLogger struct {
 	logger zerolog.Logger
@@ -51,7 +55,7 @@ func GetDefaultLogger() zerolog.Logger {
 	zerolog.TimeFieldFormat = zerolog.TimeFormatUnix
 	zerolog.ErrorStackMarshaler = pkgerrors.MarshalStack
 	// prevent slow down the process due to log write on console
-	wr := diode.NewWriter(os.Stdout, 1000, 10*time.Millisecond, func(missed int) {
+	wr := diode.NewWriter(LogOutput, 1000, 10*time.Millisecond, func(missed int) {
 		log.Warn().Msgf("Logger Dropped %d messages", missed)
 	})
 
@@ -68,19 +72,18 @@ func GetDefaultLogger() zerolog.Logger {
 				NextSampler: &zerolog.BasicSampler{N: 100},
 			},
 		}).
-		Output(zerolog.ConsoleWriter{Out: os.Stdout})
+		Output(zerolog.ConsoleWriter{Out: LogOutput})
 }
 
+// ConfigLogger applies the log level of the config. Logger is never replaced, runs keep a copy of it, so the level
+// is the global one of zerolog, which is safe to change while they log.
 func ConfigLogger(level, format string) {
 	// level is already parse on ValidateConfig
 	newLvl, _ := zerolog.ParseLevel(level)
+	zerolog.SetGlobalLevel(newLvl)
 
-	Logger = GetDefaultLogger().Level(newLvl)
-
-	// Override the output to console
+	// the default logger writes to console already
 	if format == LogTextFormat {
-		// replace global logger format
-		Logger = Logger.Output(zerolog.ConsoleWriter{Out: os.Stdout})
 		Logger.Info().Str("format", format).Msg("switch logger format")
 	}
 }
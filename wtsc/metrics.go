@@ -0,0 +1,167 @@
+package wtsc
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"net/http"
+	"strconv"
+)
+
+const metricsNamespace = "wtsc"
+
+var (
+	MetricsRegistry = prometheus.NewRegistry()
+
+	evaluationRunsMetric = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "evaluation_runs_total",
+		Help:      "Amount of evaluation runs by outcome.",
+	}, []string{"outcome"})
+
+	wtsRequestDurationMetric = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "wts_request_duration_seconds",
+		Help:      "Latency of the calls to the what-to-stake service.",
+		Buckets:   []float64{0.5, 1, 2.5, 5, 10, 15, 30, 60},
+	})
+
+	wtsRequestErrorsMetric = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "wts_request_errors_total",
+		Help:      "Amount of failed calls to the what-to-stake service.",
+	})
+
+	gainChangePercentMetric = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "gain_change_percent",
+		Help:      "Difference between the POKT incomes of the current and proposed strategy in % on the last run.",
+	})
+
+	currentModeledGainMetric = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "current_modeled_gain_24h",
+		Help:      "Expected income in POKT in 24hs using the current deployment strategy on the last run.",
+	})
+
+	optimalModeledGainMetric = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "optimal_modeled_gain_24h",
+		Help:      "Expected income in POKT in 24hs using the proposed deployment strategy on the last run.",
+	})
+
+	stakeTxsMetric = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "stake_txs_total",
+		Help:      "Amount of stake node transactions processed by servicer address and status.",
+	}, []string{"address", "status"})
+
+	servicerBalanceMetric = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "servicer_balance_upokt",
+		Help:      "Last balance in uPOKT seen on the pre-flight check of a stake tx by servicer address.",
+	}, []string{"address"})
+
+	configReloadsMetric = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "config_reloads_total",
+		Help:      "Amount of config reloads by result.",
+	}, []string{"result"})
+
+	notificationsMetric = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "notifications_total",
+		Help:      "Amount of notifications by event, channel and result.",
+	}, []string{"event", "channel", "result"})
+
+	rateLimitHitsMetric = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "poktscan_rate_limit_hits_total",
+		Help:      "Amount of POKTscan API responses with status 429.",
+	})
+
+	rateLimitMetric = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "poktscan_rate_limit",
+		Help:      "Last value seen on the POKTscan API rate limit headers.",
+	}, []string{"header"})
+
+	workerPoolWaitingTasksMetric = prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "worker_pool_waiting_tasks",
+		Help:      "Amount of tasks waiting on the worker pool queue.",
+	}, func() float64 {
+		rt := GetRuntime()
+		if rt == nil || rt.Workers == nil {
+			return 0
+		}
+		return float64(rt.Workers.WaitingTasks())
+	})
+
+	// rateLimitHeaders are the POKTscan API headers exposed as metrics
+	rateLimitHeaders = []string{
+		"X-RateLimit-Limit",
+		"X-RateLimit-Remaining",
+		"X-Long-RateLimit-Limit",
+		"X-Long-RateLimit-Remaining",
+		"X-Long-RateLimit-Consumed-Points",
+	}
+)
+
+func init() {
+	MetricsRegistry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		evaluationRunsMetric,
+		wtsRequestDurationMetric,
+		wtsRequestErrorsMetric,
+		gainChangePercentMetric,
+		currentModeledGainMetric,
+		optimalModeledGainMetric,
+		stakeTxsMetric,
+		servicerBalanceMetric,
+		configReloadsMetric,
+		notificationsMetric,
+		rateLimitHitsMetric,
+		rateLimitMetric,
+		workerPoolWaitingTasksMetric,
+	)
+}
+
+// ObserveRun updates the metrics with the result of an evaluation run
+func ObserveRun(run *RunRecord) {
+	evaluationRunsMetric.WithLabelValues(string(run.Outcome)).Inc()
+
+	if run.Outcome == RunOutcomeWtsFailed {
+		wtsRequestErrorsMetric.Inc()
+	}
+	// applies and broadcasts never call what-to-stake
+	if run.Response != nil || run.Outcome == RunOutcomeWtsFailed {
+		wtsRequestDurationMetric.Observe(float64(run.WtsMs) / 1000)
+	}
+
+	if run.Response != nil {
+		gainChangePercentMetric.Set(run.Response.GetWhatToStake.Gain_change_percent)
+		currentModeledGainMetric.Set(run.Response.GetWhatToStake.Current_modeled_gain_24h)
+		optimalModeledGainMetric.Set(run.Response.GetWhatToStake.Optimal_modeled_gain_24h)
+	}
+
+	for _, result := range run.Results {
+		stakeTxsMetric.WithLabelValues(result.Address, string(result.Status)).Inc()
+	}
+}
+
+// ObserveRateLimit keeps the last value of the POKTscan API rate limit headers
+func ObserveRateLimit(resp *http.Response) {
+	if resp.StatusCode == http.StatusTooManyRequests {
+		rateLimitHitsMetric.Inc()
+	}
+
+	for _, header := range rateLimitHeaders {
+		value, err := strconv.ParseFloat(resp.Header.Get(header), 64)
+		if err != nil {
+			// missing or not a number
+			continue
+		}
+		rateLimitMetric.WithLabelValues(header).Set(value)
+	}
+}
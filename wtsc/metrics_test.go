@@ -0,0 +1,176 @@
+package wtsc
+
+import (
+	"github.com/alitto/pond"
+	"github.com/pokt-scan/wtsc/wtsc/generated"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestObserveRun(t *testing.T) {
+	const address = "00000000000000000000000000000000000000aa"
+	completed := testutil.ToFloat64(evaluationRunsMetric.WithLabelValues(string(RunOutcomeCompleted)))
+	submitted := testutil.ToFloat64(stakeTxsMetric.WithLabelValues(address, string(TxStatusSubmitted)))
+	wtsErrors := testutil.ToFloat64(wtsRequestErrorsMetric)
+
+	ObserveRun(&RunRecord{
+		Outcome: RunOutcomeCompleted,
+		WtsMs:   1500,
+		Response: &generated.GetWhatToStakeResponse{GetWhatToStake: generated.GetWhatToStakeGetWhatToStakeWtsOptimizationResponse{
+			Gain_change_percent:      12.5,
+			Current_modeled_gain_24h: 100,
+			Optimal_modeled_gain_24h: 112.5,
+		}},
+		Results: []*ServicerResult{{Address: address, Status: TxStatusSubmitted}},
+	})
+
+	if got := testutil.ToFloat64(evaluationRunsMetric.WithLabelValues(string(RunOutcomeCompleted))); got != completed+1 {
+		t.Fatalf("got %v completed runs, expected %v", got, completed+1)
+	}
+	if got := testutil.ToFloat64(stakeTxsMetric.WithLabelValues(address, string(TxStatusSubmitted))); got != submitted+1 {
+		t.Fatalf("got %v submitted txs, expected %v", got, submitted+1)
+	}
+	if testutil.ToFloat64(wtsRequestErrorsMetric) != wtsErrors {
+		t.Fatal("a completed run counted a wts error")
+	}
+	if testutil.ToFloat64(gainChangePercentMetric) != 12.5 || testutil.ToFloat64(currentModeledGainMetric) != 100 ||
+		testutil.ToFloat64(optimalModeledGainMetric) != 112.5 {
+		t.Fatal("the gain gauges were not set from the response")
+	}
+
+	// a failed call keeps the gauges of the last response
+	ObserveRun(&RunRecord{Outcome: RunOutcomeWtsFailed, WtsMs: 30000})
+	if got := testutil.ToFloat64(wtsRequestErrorsMetric); got != wtsErrors+1 {
+		t.Fatalf("got %v wts errors, expected %v", got, wtsErrors+1)
+	}
+	if testutil.ToFloat64(gainChangePercentMetric) != 12.5 {
+		t.Fatal("a failed run changed the gain gauges")
+	}
+}
+
+func TestObserveRateLimit(t *testing.T) {
+	hits := testutil.ToFloat64(rateLimitHitsMetric)
+
+	resp := &http.Response{StatusCode: http.StatusTooManyRequests, Header: http.Header{}}
+	resp.Header.Set("X-RateLimit-Remaining", "3")
+	resp.Header.Set("X-Long-RateLimit-Remaining", "not a number")
+	ObserveRateLimit(resp)
+
+	if got := testutil.ToFloat64(rateLimitHitsMetric); got != hits+1 {
+		t.Fatalf("got %v rate limit hits, expected %v", got, hits+1)
+	}
+	if got := testutil.ToFloat64(rateLimitMetric.WithLabelValues("X-RateLimit-Remaining")); got != 3 {
+		t.Fatalf("got %v remaining, expected 3", got)
+	}
+
+	resp = &http.Response{StatusCode: http.StatusOK, Header: http.Header{}}
+	resp.Header.Set("X-RateLimit-Remaining", "2")
+	ObserveRateLimit(resp)
+	if testutil.ToFloat64(rateLimitHitsMetric) != hits+1 {
+		t.Fatal("a 200 response counted as a rate limit hit")
+	}
+	if got := testutil.ToFloat64(rateLimitMetric.WithLabelValues("X-RateLimit-Remaining")); got != 2 {
+		t.Fatalf("got %v remaining, expected the last value 2", got)
+	}
+}
+
+func TestWorkerPoolWaitingTasksMetric(t *testing.T) {
+	oldRuntime := GetRuntime()
+	t.Cleanup(func() { currentRuntime.Store(oldRuntime) })
+
+	currentRuntime.Store(nil)
+	if got := testutil.ToFloat64(workerPoolWaitingTasksMetric); got != 0 {
+		t.Fatalf("got %v waiting tasks without runtime, expected 0", got)
+	}
+
+	// the queue must hold the waiting tasks, otherwise Submit blocks
+	workers := pond.New(1, 3)
+	t.Cleanup(workers.StopAndWait)
+	release := make(chan struct{})
+	defer close(release)
+	for i := 0; i < 3; i++ {
+		workers.Submit(func() { <-release })
+	}
+	currentRuntime.Store(&Runtime{Workers: workers})
+
+	deadline := time.Now().Add(5 * time.Second)
+	for workers.WaitingTasks() == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if workers.WaitingTasks() == 0 {
+		t.Fatal("no task is waiting on the pool")
+	}
+	if got := testutil.ToFloat64(workerPoolWaitingTasksMetric); got != float64(workers.WaitingTasks()) {
+		t.Fatalf("got %v waiting tasks, expected %d", got, workers.WaitingTasks())
+	}
+}
+
+func TestMetricsEndpoint(t *testing.T) {
+	ObserveRun(&RunRecord{Outcome: RunOutcomeCompleted})
+
+	rec := httptest.NewRecorder()
+	promhttp.HandlerFor(MetricsRegistry, promhttp.HandlerOpts{}).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("got status %d", rec.Code)
+	}
+	for _, name := range []string{
+		"wtsc_evaluation_runs_total",
+		"wtsc_wts_request_duration_seconds",
+		"wtsc_worker_pool_waiting_tasks",
+		"go_goroutines",
+	} {
+		if !strings.Contains(rec.Body.String(), name) {
+			t.Errorf("%s is not exposed", name)
+		}
+	}
+}
+
+// histogramCount returns the amount of observations of the histogram
+func histogramCount(t *testing.T, h prometheus.Histogram) uint64 {
+	t.Helper()
+	m := &dto.Metric{}
+	if err := h.Write(m); err != nil {
+		t.Fatal(err)
+	}
+	return m.GetHistogram().GetSampleCount()
+}
+
+func TestObserveRunWtsDuration(t *testing.T) {
+	cases := []struct {
+		name     string
+		run      *RunRecord
+		observed bool
+	}{
+		{name: "evaluation", run: &RunRecord{Outcome: RunOutcomeNoUpdate, WtsMs: 800, Response: &generated.GetWhatToStakeResponse{}}, observed: true},
+		{name: "wts failed", run: &RunRecord{Outcome: RunOutcomeWtsFailed, WtsMs: 30000}, observed: true},
+		{name: "applied plan", run: &RunRecord{Outcome: RunOutcomeApplied}},
+		{name: "broadcast bundle", run: &RunRecord{Outcome: RunOutcomeBroadcast}},
+		{name: "plan failed", run: &RunRecord{Outcome: RunOutcomePlanFailed, WtsMs: 800, Response: &generated.GetWhatToStakeResponse{}}, observed: true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			before := histogramCount(t, wtsRequestDurationMetric)
+			runs := testutil.ToFloat64(evaluationRunsMetric.WithLabelValues(string(c.run.Outcome)))
+			ObserveRun(c.run)
+
+			expected := before
+			if c.observed {
+				expected++
+			}
+			if got := histogramCount(t, wtsRequestDurationMetric); got != expected {
+				t.Fatalf("got %d wts durations, expected %d", got, expected)
+			}
+			if got := testutil.ToFloat64(evaluationRunsMetric.WithLabelValues(string(c.run.Outcome))); got != runs+1 {
+				t.Fatalf("got %v %s runs, expected %v", got, c.run.Outcome, runs+1)
+			}
+		})
+	}
+}
@@ -0,0 +1,434 @@
+package mock
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	pocketGoProvider "github.com/pokt-foundation/pocket-go/provider"
+	pocketGoUtils "github.com/pokt-foundation/pocket-go/utils"
+	pocketCoreCrypto "github.com/pokt-network/pocket-core/crypto"
+	pocketCoreAuthTypes "github.com/pokt-network/pocket-core/x/auth/types"
+	pocketCoreNodesTypes "github.com/pokt-network/pocket-core/x/nodes/types"
+	"github.com/pokt-scan/wtsc/wtsc"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// TxScenario is a scripted answer of the fake Pocket RPC to a submitted tx
+type TxScenario string
+
+const (
+	// TxScenarioOk the tx is included on the next block and the node takes the new chains
+	TxScenarioOk TxScenario = "ok"
+	// TxScenarioRejected the rpc rejects the tx with a 400, like a failed CheckTx
+	TxScenarioRejected TxScenario = "rejected"
+	// TxScenarioRpcError the rpc answers with a 500
+	TxScenarioRpcError TxScenario = "rpc_error"
+	// TxScenarioFailed the tx is included on the next block with an error code, the node does not change
+	TxScenarioFailed TxScenario = "failed"
+	// TxScenarioDropped the tx is accepted but never included
+	TxScenarioDropped TxScenario = "dropped"
+
+	// DefaultNetworkID is the network id used to verify the signatures when NetworkID is not set
+	DefaultNetworkID = "testnet"
+	// NodeStatusStaked is the status of the nodes returned by NewNode
+	NodeStatusStaked = 2
+	// TxFailedCode is the code of the txs included by TxScenarioFailed
+	TxFailedCode = 1
+)
+
+var TxScenarios = []TxScenario{
+	TxScenarioOk,
+	TxScenarioRejected,
+	TxScenarioRpcError,
+	TxScenarioFailed,
+	TxScenarioDropped,
+}
+
+// PocketTx is a tx received by the fake Pocket RPC
+type PocketTx struct {
+	Hash     string     `json:"hash"`
+	Address  string     `json:"address"`
+	Chains   []string   `json:"chains"`
+	Scenario TxScenario `json:"scenario"`
+	// Height is 0 until the tx is included
+	Height int    `json:"height"`
+	Code   int    `json:"code"`
+	Log    string `json:"log"`
+	Fee    int64  `json:"fee"`
+
+	raw []byte
+	msg *pocketCoreNodesTypes.MsgStake
+}
+
+// PocketRpc is a fake Pocket RPC with an in-memory node registry. The submitted stake txs are decoded with the
+// wtsc codec and their signature is verified against the public key of the node, like a real node would do, so a
+// wrong network_id, fee or key is caught. Each height query mints a block that includes the pending txs.
+type PocketRpc struct {
+	// NetworkID is the chain id of the signatures, empty means DefaultNetworkID
+	NetworkID string
+	// TxScenarios are answered in order to each submitted tx, the last one is repeated. Empty means TxScenarioOk.
+	TxScenarios []TxScenario
+	// DefaultBalance is the balance of the accounts without one set with SetBalance
+	DefaultBalance int64
+
+	mu        sync.Mutex
+	submitted int
+	height    int
+	nodes     map[string]*pocketGoProvider.Node
+	balances  map[string]*big.Int
+	txs       []*PocketTx
+	pending   []*PocketTx
+}
+
+// NewPocketRpc returns a fake Pocket RPC at height 1 without nodes
+func NewPocketRpc() *PocketRpc {
+	return &PocketRpc{
+		height:   1,
+		nodes:    make(map[string]*pocketGoProvider.Node),
+		balances: make(map[string]*big.Int),
+	}
+}
+
+// NewPocketRpcServer starts the fake Pocket RPC on a local port, close it when done
+func NewPocketRpcServer(p *PocketRpc) *httptest.Server {
+	return httptest.NewServer(p)
+}
+
+// NewNode returns a staked node of the public key, with the output address set to its own address
+func NewNode(publicKey string, chains []string, tokens int64) (pocketGoProvider.Node, error) {
+	address, err := pocketGoUtils.GetAddressFromPublickey(publicKey)
+	if err != nil {
+		return pocketGoProvider.Node{}, err
+	}
+
+	return pocketGoProvider.Node{
+		Address:       address,
+		Chains:        chains,
+		PublicKey:     publicKey,
+		ServiceURL:    fmt.Sprintf("https://%s.mock:443", address[:8]),
+		Status:        NodeStatusStaked,
+		Tokens:        strconv.FormatInt(tokens, 10),
+		OutputAddress: address,
+	}, nil
+}
+
+// AddNode registers (or replaces) a staked node
+func (p *PocketRpc) AddNode(node pocketGoProvider.Node) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.nodes[node.Address] = &node
+}
+
+// Node returns a copy of the node registered with the address
+func (p *PocketRpc) Node(address string) (pocketGoProvider.Node, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	node, ok := p.nodes[address]
+	if !ok {
+		return pocketGoProvider.Node{}, false
+	}
+	return *node, true
+}
+
+// SetBalance sets the balance in upokt of an account
+func (p *PocketRpc) SetBalance(address string, balance int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.balances[address] = big.NewInt(balance)
+}
+
+// Balance returns the balance in upokt of an account
+func (p *PocketRpc) Balance(address string) *big.Int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return new(big.Int).Set(p.balance(address))
+}
+
+// Height returns the current height
+func (p *PocketRpc) Height() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.height
+}
+
+// Txs returns the txs received, without the rejected ones
+func (p *PocketRpc) Txs() []PocketTx {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	txs := make([]PocketTx, 0, len(p.txs))
+	for _, tx := range p.txs {
+		txs = append(txs, *tx)
+	}
+	return txs
+}
+
+// MintBlock increases the height and includes the pending txs
+func (p *PocketRpc) MintBlock() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.mintBlock()
+}
+
+func (p *PocketRpc) mintBlock() int {
+	p.height++
+	for _, tx := range p.pending {
+		tx.Height = p.height
+		// the fee is paid even if the tx fails
+		balance := p.balance(tx.Address)
+		balance.Sub(balance, big.NewInt(tx.Fee))
+		if tx.Scenario == TxScenarioFailed {
+			tx.Code = TxFailedCode
+			tx.Log = "mock: stake failed"
+			continue
+		}
+		if node, ok := p.nodes[tx.Address]; ok {
+			node.Chains = tx.msg.Chains
+			node.ServiceURL = tx.msg.ServiceUrl
+			node.Tokens = tx.msg.Value.String()
+		}
+	}
+	p.pending = nil
+	return p.height
+}
+
+func (p *PocketRpc) balance(address string) *big.Int {
+	balance, ok := p.balances[address]
+	if !ok {
+		balance = big.NewInt(p.DefaultBalance)
+		p.balances[address] = balance
+	}
+	return balance
+}
+
+// nextTxScenario returns the scenario of the current tx, must be called with the lock held
+func (p *PocketRpc) nextTxScenario() TxScenario {
+	n := p.submitted
+	p.submitted++
+	if len(p.TxScenarios) == 0 {
+		return TxScenarioOk
+	}
+	if n >= len(p.TxScenarios) {
+		return p.TxScenarios[len(p.TxScenarios)-1]
+	}
+	return p.TxScenarios[n]
+}
+
+func (p *PocketRpc) networkID() string {
+	if p.NetworkID == "" {
+		return DefaultNetworkID
+	}
+	return p.NetworkID
+}
+
+func (p *PocketRpc) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	params := struct {
+		Address     string `json:"address"`
+		Hash        string `json:"hash"`
+		RawHexBytes string `json:"raw_hex_bytes"`
+	}{}
+	if r.ContentLength != 0 {
+		// height is sent without body
+		_ = json.NewDecoder(r.Body).Decode(&params)
+	}
+
+	switch r.URL.Path {
+	case string(pocketGoProvider.QueryHeightRoute):
+		p.mu.Lock()
+		height := p.mintBlock()
+		p.mu.Unlock()
+		writeJson(w, map[string]int{"height": height})
+	case string(pocketGoProvider.QueryNodeRoute):
+		node, ok := p.Node(params.Address)
+		if !ok {
+			writeRpcError(w, fmt.Sprintf("validator not found for %s", params.Address))
+			return
+		}
+		writeJson(w, node)
+	case string(pocketGoProvider.QueryBalanceRoute):
+		writeJson(w, map[string]*big.Int{"balance": p.Balance(params.Address)})
+	case string(pocketGoProvider.QueryTXRoute):
+		p.serveTx(w, params.Hash)
+	case string(pocketGoProvider.ClientRawTXRoute):
+		p.serveRawTx(w, params.Address, params.RawHexBytes)
+	default:
+		http.Error(w, "not found", http.StatusNotFound)
+	}
+}
+
+func (p *PocketRpc) serveTx(w http.ResponseWriter, hash string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, tx := range p.txs {
+		if tx.Hash != hash || tx.Height == 0 {
+			continue
+		}
+		writeJson(w, &pocketGoProvider.Transaction{
+			Hash:   tx.Hash,
+			Height: tx.Height,
+			Tx:     base64.StdEncoding.EncodeToString(tx.raw),
+			TxResult: &pocketGoProvider.TxResult{
+				Code:        tx.Code,
+				Codespace:   "pos",
+				Log:         tx.Log,
+				MessageType: "stake_validator",
+				Signer:      tx.Address,
+			},
+		})
+		return
+	}
+
+	writeRpcError(w, fmt.Sprintf("Tx (%s) not found", hash))
+}
+
+// serveRawTx checks the tx like the CheckTx of a node, then it is answered with the next scenario
+func (p *PocketRpc) serveRawTx(w http.ResponseWriter, address, rawHexBytes string) {
+	raw, err := hex.DecodeString(rawHexBytes)
+	if err != nil {
+		writeRpcError(w, fmt.Sprintf("invalid raw_hex_bytes: %s", err))
+		return
+	}
+
+	tx, msg, err := p.checkTx(address, raw)
+	if err != nil {
+		writeRpcError(w, err.Error())
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	// fee is already validated by checkTx
+	fee := tx.Fee.AmountOf("upokt").Int64()
+	if p.balance(address).Cmp(big.NewInt(fee)) < 0 {
+		writeRpcError(w, fmt.Sprintf("insufficient funds: %s can not pay the fee of %dupokt", address, fee))
+		return
+	}
+
+	scenario := p.nextTxScenario()
+	switch scenario {
+	case TxScenarioRejected:
+		writeRpcError(w, "mock: tx rejected")
+		return
+	case TxScenarioRpcError:
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	pocketTx := &PocketTx{
+		Hash:     wtsc.TxHash(raw),
+		Address:  address,
+		Chains:   msg.Chains,
+		Scenario: scenario,
+		Fee:      fee,
+		raw:      raw,
+		msg:      msg,
+	}
+	p.txs = append(p.txs, pocketTx)
+	if scenario != TxScenarioDropped {
+		p.pending = append(p.pending, pocketTx)
+	}
+
+	writeJson(w, &pocketGoProvider.SendTransactionOutput{
+		Height: strconv.Itoa(p.height),
+		Txhash: pocketTx.Hash,
+	})
+}
+
+// checkTx decodes the tx and verifies that it is a valid MsgStake of a registered node signed by its key
+func (p *PocketRpc) checkTx(address string, raw []byte) (*pocketCoreAuthTypes.StdTx, *pocketCoreNodesTypes.MsgStake, error) {
+	tx, err := wtsc.DecodeTx(raw)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var msg *pocketCoreNodesTypes.MsgStake
+	switch m := tx.Msg.(type) {
+	case *pocketCoreNodesTypes.MsgStake:
+		msg = m
+	case pocketCoreNodesTypes.MsgStake:
+		msg = &m
+	default:
+		return nil, nil, fmt.Errorf("unexpected msg type %T", tx.Msg)
+	}
+
+	if e := tx.ValidateBasic(); e != nil {
+		return nil, nil, e
+	}
+	if e := msg.ValidateBasic(); e != nil {
+		return nil, nil, e
+	}
+	if len(tx.Fee) != 1 || tx.Fee[0].Denom != "upokt" {
+		return nil, nil, fmt.Errorf("fee must be paid in upokt, got %s", tx.Fee.String())
+	}
+
+	msgAddress := strings.ToLower(msg.PublicKey.Address().String())
+	if msgAddress != address {
+		return nil, nil, fmt.Errorf("tx of %s submitted as %s", msgAddress, address)
+	}
+
+	node, ok := p.Node(address)
+	if !ok {
+		return nil, nil, fmt.Errorf("validator not found for %s", address)
+	}
+
+	publicKey, err := pocketCoreCrypto.NewPublicKey(node.PublicKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	signBytes, err := pocketCoreAuthTypes.StdSignBytes(p.networkID(), tx.Entropy, tx.Fee, msg, tx.Memo)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !publicKey.VerifyBytes(signBytes, tx.Signature.Signature) {
+		return nil, nil, fmt.Errorf("unauthorized: signature verification failed for %s on %s", address, p.networkID())
+	}
+
+	// the stake of a node can only grow
+	if tokens, ok := new(big.Int).SetString(node.Tokens, 10); ok && msg.Value.BigInt().Cmp(tokens) < 0 {
+		return nil, nil, fmt.Errorf("stake of %s can not be lowered from %s to %s", address, node.Tokens, msg.Value.String())
+	}
+
+	return tx, msg, nil
+}
+
+func writeRpcError(w http.ResponseWriter, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	_ = json.NewEncoder(w).Encode(&pocketGoProvider.RPCError{Code: http.StatusBadRequest, Message: message})
+}
+
+// ParseTxScenarios parses a comma separated list of tx scenarios
+func ParseTxScenarios(value string) ([]TxScenario, error) {
+	scenarios := make([]TxScenario, 0)
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		found := false
+		for _, scenario := range TxScenarios {
+			if TxScenario(item) == scenario {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown tx scenario %q", item)
+		}
+		scenarios = append(scenarios, TxScenario(item))
+	}
+	return scenarios, nil
+}
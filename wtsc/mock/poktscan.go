@@ -0,0 +1,258 @@
+// Package mock has fake versions of the services used by wtsc (POKTscan API and Pocket RPC), so the whole
+// evaluation loop could run offline on CI or on a demo. They are plain http.Handler, use them with httptest or
+// with the `wtsc mock-*` commands.
+package mock
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/pokt-scan/wtsc/wtsc/generated"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+)
+
+// POKTscanScenario is a scripted answer of the fake POKTscan API
+type POKTscanScenario string
+
+const (
+	// ScenarioUpdate recommends an update, each servicer gets a different service of the pool on each request
+	ScenarioUpdate POKTscanScenario = "update"
+	// ScenarioNoUpdate the recommendation says that there is no need to update
+	ScenarioNoUpdate POKTscanScenario = "no_update"
+	// ScenarioUnknownAddresses recommends an update that also includes servicers that are not ours
+	ScenarioUnknownAddresses POKTscanScenario = "unknown_addresses"
+	// ScenarioGraphqlError answers with a graphql error
+	ScenarioGraphqlError POKTscanScenario = "graphql_error"
+	// ScenarioServerError answers with a 500
+	ScenarioServerError POKTscanScenario = "server_error"
+	// ScenarioRateLimited answers with a 429 and the POKTscan rate limit headers
+	ScenarioRateLimited POKTscanScenario = "rate_limited"
+	// ScenarioSlow waits Delay before answering like ScenarioUpdate
+	ScenarioSlow POKTscanScenario = "slow"
+
+	// DefaultSlowDelay is the delay of ScenarioSlow when Delay is not set
+	DefaultSlowDelay = 5 * time.Second
+)
+
+var (
+	POKTscanScenarios = []POKTscanScenario{
+		ScenarioUpdate,
+		ScenarioNoUpdate,
+		ScenarioUnknownAddresses,
+		ScenarioGraphqlError,
+		ScenarioServerError,
+		ScenarioRateLimited,
+		ScenarioSlow,
+	}
+
+	// UnknownAddresses are the servicers added by ScenarioUnknownAddresses
+	UnknownAddresses = []string{
+		strings.Repeat("f", 40),
+		strings.Repeat("e", 40),
+	}
+)
+
+type graphqlRequest struct {
+	OperationName string          `json:"operationName"`
+	Query         string          `json:"query"`
+	Variables     json.RawMessage `json:"variables"`
+}
+
+type graphqlError struct {
+	Message string `json:"message"`
+}
+
+type graphqlResponse struct {
+	Data   any            `json:"data"`
+	Errors []graphqlError `json:"errors,omitempty"`
+}
+
+// POKTscan is a fake POKTscan API that answers the GetWhatToStake operation from scripted scenarios
+type POKTscan struct {
+	// Scenarios are answered in order, the last one is repeated. Empty means ScenarioUpdate.
+	Scenarios []POKTscanScenario
+	// Servicers are the addresses of the recommendation
+	Servicers []string
+	// Recommendation sets the services of a servicer instead of rotating the service pool
+	Recommendation map[string][]string
+	// Token is the expected Authorization header, empty accepts any
+	Token string
+	// Delay of ScenarioSlow
+	Delay time.Duration
+
+	mu        sync.Mutex
+	requests  int
+	lastInput *generated.WtsProcessRequestInput
+}
+
+// NewPOKTscanServer starts the fake POKTscan API on a local port, close it when done
+func NewPOKTscanServer(p *POKTscan) *httptest.Server {
+	return httptest.NewServer(p)
+}
+
+// Requests returns the amount of requests received
+func (p *POKTscan) Requests() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.requests
+}
+
+// LastInput returns the input of the last GetWhatToStake call
+func (p *POKTscan) LastInput() *generated.WtsProcessRequestInput {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.lastInput
+}
+
+// next returns the scenario of the current request and the request number (starting on 0)
+func (p *POKTscan) next() (POKTscanScenario, int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	n := p.requests
+	p.requests++
+
+	if len(p.Scenarios) == 0 {
+		return ScenarioUpdate, n
+	}
+	if n >= len(p.Scenarios) {
+		return p.Scenarios[len(p.Scenarios)-1], n
+	}
+	return p.Scenarios[n], n
+}
+
+func (p *POKTscan) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if p.Token != "" && r.Header.Get("Authorization") != p.Token {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	req := graphqlRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if req.OperationName != "GetWhatToStake" {
+		writeGraphqlError(w, fmt.Sprintf("unknown operation %q", req.OperationName))
+		return
+	}
+
+	variables := struct {
+		Input generated.WtsProcessRequestInput `json:"input"`
+	}{}
+	if err := json.Unmarshal(req.Variables, &variables); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	scenario, n := p.next()
+
+	p.mu.Lock()
+	p.lastInput = &variables.Input
+	p.mu.Unlock()
+
+	switch scenario {
+	case ScenarioGraphqlError:
+		writeGraphqlError(w, "what to stake optimization failed")
+	case ScenarioServerError:
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+	case ScenarioRateLimited:
+		w.Header().Set("Retry-After", "30")
+		w.Header().Set("X-RateLimit-Limit", "100")
+		w.Header().Set("X-RateLimit-Remaining", "0")
+		w.Header().Set("X-RateLimit-Reset", time.Now().Add(30*time.Second).UTC().Format(time.RFC3339))
+		w.Header().Set("X-Long-RateLimit-Limit", "100000")
+		w.Header().Set("X-Long-RateLimit-Remaining", "99000")
+		w.Header().Set("X-Long-RateLimit-Consumed-Points", "1")
+		http.Error(w, "too many requests", http.StatusTooManyRequests)
+	case ScenarioNoUpdate:
+		writeJson(w, graphqlResponse{Data: p.recommendation(&variables.Input, n, false, nil)})
+	case ScenarioUnknownAddresses:
+		writeJson(w, graphqlResponse{Data: p.recommendation(&variables.Input, n, true, UnknownAddresses)})
+	case ScenarioSlow:
+		delay := p.Delay
+		if delay == 0 {
+			delay = DefaultSlowDelay
+		}
+		select {
+		case <-time.After(delay):
+		case <-r.Context().Done():
+			return
+		}
+		writeJson(w, graphqlResponse{Data: p.recommendation(&variables.Input, n, true, nil)})
+	default:
+		writeJson(w, graphqlResponse{Data: p.recommendation(&variables.Input, n, true, nil)})
+	}
+}
+
+// recommendation builds the response of the n request. Without a Recommendation each servicer gets a single service
+// of the pool, that changes on each request, so there is always something to stake.
+func (p *POKTscan) recommendation(input *generated.WtsProcessRequestInput, n int, doUpdate bool, extra []string) *generated.GetWhatToStakeResponse {
+	resp := &generated.GetWhatToStakeResponse{}
+	wts := &resp.GetWhatToStake
+	wts.Do_update = doUpdate
+	wts.Current_modeled_gain_24h = 100
+	if doUpdate {
+		wts.Reason = "mock: the proposed strategy improves the gain"
+		wts.Gain_change_percent = 10 + float64(n)
+	} else {
+		wts.Reason = "mock: the current strategy is good enough"
+		wts.Gain_change_percent = 0
+	}
+	wts.Optimal_modeled_gain_24h = wts.Current_modeled_gain_24h * (1 + wts.Gain_change_percent/100)
+
+	servicers := append(append([]string{}, p.Servicers...), extra...)
+	for i, address := range servicers {
+		services, ok := p.Recommendation[address]
+		if !ok && len(input.Service_pool) > 0 {
+			services = []string{input.Service_pool[(n+i)%len(input.Service_pool)]}
+		}
+		wts.Servicers = append(wts.Servicers, generated.GetWhatToStakeGetWhatToStakeWtsOptimizationResponseServicersWtsStakeNode{
+			Address:  address,
+			Services: services,
+		})
+	}
+
+	return resp
+}
+
+func writeGraphqlError(w http.ResponseWriter, message string) {
+	writeJson(w, graphqlResponse{Errors: []graphqlError{{Message: message}}})
+}
+
+func writeJson(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// ParsePOKTscanScenarios parses a comma separated list of scenarios
+func ParsePOKTscanScenarios(value string) ([]POKTscanScenario, error) {
+	scenarios := make([]POKTscanScenario, 0)
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		found := false
+		for _, scenario := range POKTscanScenarios {
+			if POKTscanScenario(item) == scenario {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown scenario %q", item)
+		}
+		scenarios = append(scenarios, POKTscanScenario(item))
+	}
+	return scenarios, nil
+}
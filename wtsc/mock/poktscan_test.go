@@ -0,0 +1,220 @@
+package mock
+
+import (
+	"context"
+	"errors"
+	"github.com/Khan/genqlient/graphql"
+	"github.com/pokt-scan/wtsc/wtsc/generated"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const testToken = "Bearer 0123456789abcdef"
+
+var testServicers = []string{strings.Repeat("a", 40), strings.Repeat("b", 40)}
+
+// headerRecorder sends the token and keeps the headers of the last response
+type headerRecorder struct {
+	mu     sync.Mutex
+	header http.Header
+}
+
+func (hr *headerRecorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", testToken)
+	resp, err := http.DefaultTransport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	hr.mu.Lock()
+	hr.header = resp.Header
+	hr.mu.Unlock()
+	return resp, nil
+}
+
+func (hr *headerRecorder) Header() http.Header {
+	hr.mu.Lock()
+	defer hr.mu.Unlock()
+	return hr.header
+}
+
+// newTestClient starts the fake POKTscan API and returns the generated client for it, like the one of wtsc
+func newTestClient(t *testing.T, p *POKTscan) (graphql.Client, *headerRecorder) {
+	t.Helper()
+	p.Servicers = testServicers
+	p.Token = testToken
+	server := NewPOKTscanServer(p)
+	t.Cleanup(server.Close)
+
+	recorder := &headerRecorder{}
+	return graphql.NewClient(server.URL, &http.Client{Transport: recorder}), recorder
+}
+
+func getWhatToStake(ctx context.Context, client graphql.Client) (*generated.GetWhatToStakeResponse, error) {
+	return generated.GetWhatToStake(ctx, client, generated.WtsProcessRequestInput{
+		Domain:       "wtsc.test",
+		Service_pool: []string{"0001", "0021"},
+	})
+}
+
+func servicerAddresses(resp *generated.GetWhatToStakeResponse) []string {
+	addresses := make([]string, 0, len(resp.GetWhatToStake.Servicers))
+	for _, servicer := range resp.GetWhatToStake.Servicers {
+		addresses = append(addresses, servicer.Address)
+	}
+	return addresses
+}
+
+func TestPOKTscanUpdate(t *testing.T) {
+	p := &POKTscan{Scenarios: []POKTscanScenario{ScenarioUpdate}}
+	client, _ := newTestClient(t, p)
+	resp, err := getWhatToStake(context.Background(), client)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wts := resp.GetWhatToStake
+	if !wts.Do_update || wts.Gain_change_percent <= 0 {
+		t.Fatalf("got do_update %t and gain %.2f, expected an update", wts.Do_update, wts.Gain_change_percent)
+	}
+	if got := strings.Join(servicerAddresses(resp), ","); got != strings.Join(testServicers, ",") {
+		t.Fatalf("got servicers %s, expected %s", got, strings.Join(testServicers, ","))
+	}
+	for _, servicer := range wts.Servicers {
+		if len(servicer.Services) != 1 {
+			t.Fatalf("%s got services %v, expected one of the pool", servicer.Address, servicer.Services)
+		}
+	}
+	if p.Requests() != 1 || p.LastInput().Domain != "wtsc.test" {
+		t.Fatal("the request was not recorded")
+	}
+}
+
+func TestPOKTscanNoUpdate(t *testing.T) {
+	client, _ := newTestClient(t, &POKTscan{Scenarios: []POKTscanScenario{ScenarioNoUpdate}})
+	resp, err := getWhatToStake(context.Background(), client)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wts := resp.GetWhatToStake
+	if wts.Do_update || wts.Gain_change_percent != 0 {
+		t.Fatalf("got do_update %t and gain %.2f, expected no update", wts.Do_update, wts.Gain_change_percent)
+	}
+	if len(wts.Servicers) != len(testServicers) {
+		t.Fatalf("got %d servicers, expected %d", len(wts.Servicers), len(testServicers))
+	}
+}
+
+func TestPOKTscanUnknownAddresses(t *testing.T) {
+	client, _ := newTestClient(t, &POKTscan{Scenarios: []POKTscanScenario{ScenarioUnknownAddresses}})
+	resp, err := getWhatToStake(context.Background(), client)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := strings.Join(append(append([]string{}, testServicers...), UnknownAddresses...), ",")
+	if got := strings.Join(servicerAddresses(resp), ","); got != expected {
+		t.Fatalf("got servicers %s, expected %s", got, expected)
+	}
+}
+
+func TestPOKTscanGraphqlError(t *testing.T) {
+	client, _ := newTestClient(t, &POKTscan{Scenarios: []POKTscanScenario{ScenarioGraphqlError}})
+	_, err := getWhatToStake(context.Background(), client)
+	if err == nil || !strings.Contains(err.Error(), "what to stake optimization failed") {
+		t.Fatalf("got %v, expected the graphql error", err)
+	}
+}
+
+func TestPOKTscanServerError(t *testing.T) {
+	client, _ := newTestClient(t, &POKTscan{Scenarios: []POKTscanScenario{ScenarioServerError}})
+	_, err := getWhatToStake(context.Background(), client)
+	if err == nil || !strings.Contains(err.Error(), "500") {
+		t.Fatalf("got %v, expected a 500", err)
+	}
+}
+
+func TestPOKTscanRateLimited(t *testing.T) {
+	client, recorder := newTestClient(t, &POKTscan{Scenarios: []POKTscanScenario{ScenarioRateLimited}})
+	_, err := getWhatToStake(context.Background(), client)
+	if err == nil || !strings.Contains(err.Error(), "429") {
+		t.Fatalf("got %v, expected a 429", err)
+	}
+
+	header := recorder.Header()
+	for _, name := range []string{"Retry-After", "X-RateLimit-Limit", "X-RateLimit-Remaining", "X-RateLimit-Reset", "X-Long-RateLimit-Limit", "X-Long-RateLimit-Remaining", "X-Long-RateLimit-Consumed-Points"} {
+		if header.Get(name) == "" {
+			t.Errorf("missing %s header", name)
+		}
+	}
+	if header.Get("X-RateLimit-Remaining") != "0" {
+		t.Fatalf("got %s credits remaining, expected 0", header.Get("X-RateLimit-Remaining"))
+	}
+}
+
+func TestPOKTscanSlow(t *testing.T) {
+	t.Run("answers after the delay", func(t *testing.T) {
+		client, _ := newTestClient(t, &POKTscan{Scenarios: []POKTscanScenario{ScenarioSlow}, Delay: 50 * time.Millisecond})
+		start := time.Now()
+		resp, err := getWhatToStake(context.Background(), client)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if time.Since(start) < 50*time.Millisecond {
+			t.Fatal("answered before the delay")
+		}
+		if !resp.GetWhatToStake.Do_update {
+			t.Fatal("expected an update")
+		}
+	})
+
+	t.Run("context cancel", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		client, _ := newTestClient(t, &POKTscan{Scenarios: []POKTscanScenario{ScenarioSlow}})
+		start := time.Now()
+		_, err := getWhatToStake(ctx, client)
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("got %v, expected %s", err, context.DeadlineExceeded)
+		}
+		if time.Since(start) >= DefaultSlowDelay {
+			t.Fatal("the request was not cancelled")
+		}
+	})
+}
+
+func TestPOKTscanScenariosInOrder(t *testing.T) {
+	p := &POKTscan{Scenarios: []POKTscanScenario{ScenarioServerError, ScenarioNoUpdate}}
+	client, _ := newTestClient(t, p)
+	if _, err := getWhatToStake(context.Background(), client); err == nil {
+		t.Fatal("expected the first scenario to fail")
+	}
+	// the last scenario is repeated
+	for i := 0; i < 2; i++ {
+		resp, err := getWhatToStake(context.Background(), client)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.GetWhatToStake.Do_update {
+			t.Fatal("expected no update")
+		}
+	}
+	if p.Requests() != 3 {
+		t.Fatalf("got %d requests, expected 3", p.Requests())
+	}
+}
+
+func TestPOKTscanUnauthorized(t *testing.T) {
+	server := NewPOKTscanServer(&POKTscan{Token: testToken})
+	defer server.Close()
+
+	client := graphql.NewClient(server.URL, http.DefaultClient)
+	if _, err := generated.GetWhatToStake(context.Background(), client, generated.WtsProcessRequestInput{}); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Fatalf("got %v, expected a 401", err)
+	}
+}
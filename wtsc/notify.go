@@ -0,0 +1,342 @@
+package wtsc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/hashicorp/go-cleanhttp"
+	"net/http"
+	neturl "net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+type NotificationEvent string
+
+const (
+	// EventPlanComputed the recommendation was compared with the on-chain state of the nodes
+	EventPlanComputed NotificationEvent = "plan_computed"
+	// EventStakesSubmitted the stake txs of an evaluation (or an applied plan) were processed
+	EventStakesSubmitted NotificationEvent = "stakes_submitted"
+	// EventTxFailed a stake tx failed or was dropped
+	EventTxFailed NotificationEvent = "tx_failed"
+	// EventWtsFailed the call to what-to-stake service failed
+	EventWtsFailed NotificationEvent = "wts_failed"
+	// EventConfigReloadFailed the new config could not be applied
+	EventConfigReloadFailed NotificationEvent = "config_reload_failed"
+	// EventDailyDigest the summary of the runs of the last 24h
+	EventDailyDigest NotificationEvent = "daily_digest"
+	// EventLowBalance the balance of a servicer is close to not pay low_balance_restakes restakes
+	EventLowBalance NotificationEvent = "low_balance"
+)
+
+const (
+	WebhookFormatGeneric = "generic"
+	WebhookFormatSlack   = "slack"
+	WebhookFormatDiscord = "discord"
+
+	// discordMaxContent is the max length of a discord message
+	discordMaxContent = 2000
+)
+
+var (
+	NotificationEvents = []NotificationEvent{
+		EventPlanComputed,
+		EventStakesSubmitted,
+		EventTxFailed,
+		EventWtsFailed,
+		EventConfigReloadFailed,
+		EventDailyDigest,
+		EventLowBalance,
+	}
+
+	webhookClient = cleanhttp.DefaultPooledClient()
+	// notificationsWg tracks the in-flight notifications, so they are not lost on shutdown
+	notificationsWg sync.WaitGroup
+)
+
+// Notification is the body sent to generic webhooks, slack and discord receive its text.
+type Notification struct {
+	Event   NotificationEvent `json:"event"`
+	Time    time.Time         `json:"time"`
+	Domain  string            `json:"domain"`
+	Message string            `json:"message"`
+	RunID   string            `json:"run_id,omitempty"`
+	PlanID  string            `json:"plan_id,omitempty"`
+	// GainChangePercent is only set on the events of a recommendation, it is used by min_gain_change_percent
+	GainChangePercent *float64       `json:"gain_change_percent,omitempty"`
+	Fields            map[string]any `json:"fields,omitempty"`
+	// Details are human-readable lines, like the chains changed on each node and its tx hash
+	Details []string `json:"details,omitempty"`
+}
+
+// Notifier is a channel able to deliver notifications (webhook, telegram, email)
+type Notifier interface {
+	// Name identifies the channel on logs and metrics
+	Name() string
+	Accepts(n *Notification) bool
+	Send(ctx context.Context, n *Notification) error
+}
+
+func NewNotification(event NotificationEvent, message string) *Notification {
+	return &Notification{
+		Event:   event,
+		Time:    time.Now(),
+		Message: message,
+		Fields:  make(map[string]any),
+	}
+}
+
+// WithGain sets the gain change percent of the recommendation related to the notification
+func (n *Notification) WithGain(gainChangePercent float64) *Notification {
+	n.GainChangePercent = &gainChangePercent
+	return n
+}
+
+// Text is the human-readable version of the notification used by chat webhooks
+func (n *Notification) Text() string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("[wtsc] %s: %s", n.Event, n.Message))
+	if !IsEmptyString(n.Domain) {
+		sb.WriteString(fmt.Sprintf("\ndomain: %s", n.Domain))
+	}
+	if !IsEmptyString(n.RunID) {
+		sb.WriteString(fmt.Sprintf("\nrun: %s", n.RunID))
+	}
+	if !IsEmptyString(n.PlanID) {
+		sb.WriteString(fmt.Sprintf("\nplan: %s", n.PlanID))
+	}
+	if n.GainChangePercent != nil {
+		sb.WriteString(fmt.Sprintf("\ngain change: %.2f%%", *n.GainChangePercent))
+	}
+
+	// keep the fields order stable between messages
+	keys := make([]string, 0, len(n.Fields))
+	for k := range n.Fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		sb.WriteString(fmt.Sprintf("\n%s: %v", k, n.Fields[k]))
+	}
+
+	for _, detail := range n.Details {
+		sb.WriteString("\n- " + detail)
+	}
+
+	return sb.String()
+}
+
+// Accepts reports if the notification passes the event and gain filters
+func (nf *NotificationFilter) Accepts(n *Notification) bool {
+	if len(nf.Events) > 0 && !FindStringInSlice(nf.Events, string(n.Event)) {
+		return false
+	}
+	if n.GainChangePercent != nil && *n.GainChangePercent < nf.MinGainChangePercent {
+		return false
+	}
+	return true
+}
+
+func (wc *WebhookConfig) Name() string {
+	if IsEmptyString(wc.Format) {
+		return "webhook_" + WebhookFormatGeneric
+	}
+	return "webhook_" + wc.Format
+}
+
+// Payload returns the body of the webhook request in the configured format
+func (wc *WebhookConfig) Payload(n *Notification) ([]byte, error) {
+	switch wc.Format {
+	case WebhookFormatSlack:
+		return json.Marshal(map[string]string{"text": n.Text()})
+	case WebhookFormatDiscord:
+		text := n.Text()
+		if len(text) > discordMaxContent {
+			text = text[:discordMaxContent]
+		}
+		return json.Marshal(map[string]string{"content": text})
+	default:
+		return json.Marshal(n)
+	}
+}
+
+func CheckWebhooks(webhooks []WebhookConfig) (errs []*ConfigError) {
+	for i, webhook := range webhooks {
+		key := fmt.Sprintf("webhooks[%d]", i)
+		if !IsValidHttpURI(webhook.URL) {
+			// the url usually holds a secret token
+			errs = append(errs, newConfigError(key+".url", nil, "must be a http(s) url"))
+		}
+
+		switch webhook.Format {
+		case "", WebhookFormatGeneric, WebhookFormatSlack, WebhookFormatDiscord:
+		default:
+			errs = append(errs, newConfigError(key+".format", webhook.Format, fmt.Sprintf("must be %q, %q or %q", WebhookFormatGeneric, WebhookFormatSlack, WebhookFormatDiscord)))
+		}
+
+		errs = append(errs, CheckNotificationFilter(key, webhook.NotificationFilter)...)
+	}
+
+	return errs
+}
+
+// Notifiers returns every notification channel of the config
+func Notifiers(cfg *Config) []Notifier {
+	notifiers := make([]Notifier, 0, len(cfg.Webhooks)+len(cfg.Telegram)+len(cfg.Email))
+	for i := range cfg.Webhooks {
+		notifiers = append(notifiers, &cfg.Webhooks[i])
+	}
+	for i := range cfg.Telegram {
+		notifiers = append(notifiers, &cfg.Telegram[i])
+	}
+	for i := range cfg.Email {
+		notifiers = append(notifiers, &cfg.Email[i])
+	}
+	return notifiers
+}
+
+// Notify sends the notification with the channels of the current config
+func Notify(n *Notification) {
+	NotifyWith(GetConfig(), n)
+}
+
+// NotifyWith sends the notification to every channel of cfg that accepts it. It does not block the caller.
+func NotifyWith(cfg *Config, n *Notification) {
+	if cfg == nil {
+		return
+	}
+	if IsEmptyString(n.Domain) {
+		n.Domain = cfg.Domain
+	}
+
+	timeout := time.Duration(cfg.MaxTimeout) * time.Millisecond
+	for _, notifier := range Notifiers(cfg) {
+		if !notifier.Accepts(n) {
+			continue
+		}
+
+		notificationsWg.Add(1)
+		go func(notifier Notifier) {
+			defer notificationsWg.Done()
+
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+
+			if err := notifier.Send(ctx, n); err != nil {
+				Logger.Error().Err(err).Str("event", string(n.Event)).Str("channel", notifier.Name()).Msg("failed to send notification")
+				notificationsMetric.WithLabelValues(string(n.Event), notifier.Name(), "failed").Inc()
+				return
+			}
+			Logger.Debug().Str("event", string(n.Event)).Str("channel", notifier.Name()).Msg("notification sent")
+			notificationsMetric.WithLabelValues(string(n.Event), notifier.Name(), "sent").Inc()
+		}(notifier)
+	}
+}
+
+// WaitNotifications blocks until the in-flight notifications are sent (or failed)
+func WaitNotifications() {
+	notificationsWg.Wait()
+}
+
+func (wc *WebhookConfig) Send(ctx context.Context, n *Notification) error {
+	body, err := wc.Payload(n)
+	if err != nil {
+		return err
+	}
+	return postJson(ctx, wc.URL, body)
+}
+
+// postJson is used by the http based channels, any 2xx response is a success. The errors never include the url,
+// since it usually carries a secret (webhook path, telegram bot token).
+func postJson(ctx context.Context, url string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return redactURLError(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := webhookClient.Do(req)
+	if err != nil {
+		return redactURLError(err)
+	}
+	_ = resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("responded with %s", resp.Status)
+	}
+
+	return nil
+}
+
+// redactURLError drops the url from the errors of the http client (*url.Error), keeping the operation and cause
+func redactURLError(err error) error {
+	var urlErr *neturl.Error
+	if errors.As(err, &urlErr) {
+		return fmt.Errorf("%s request failed: %w", urlErr.Op, urlErr.Err)
+	}
+	return err
+}
+
+// notifyPlan sends the plan_computed event
+func (app *App) notifyPlan(plan *Plan, runID string) {
+	n := NewNotification(EventPlanComputed, plan.Reason).WithGain(plan.GainChangePercent)
+	n.RunID = runID
+	n.PlanID = plan.ID
+	n.Fields["do_update"] = plan.DoUpdate
+	n.Fields["nodes"] = len(plan.OwnNodes())
+	changes := 0
+	for _, node := range plan.OwnNodes() {
+		if node.Change {
+			changes++
+		}
+	}
+	n.Fields["changes"] = changes
+	for _, node := range plan.OwnNodes() {
+		if node.Change {
+			n.Details = append(n.Details, fmt.Sprintf("%s: %s -> %s", node.Address, strings.Join(node.CurrentChains, ","), strings.Join(node.ProposedChains, ",")))
+		}
+	}
+	if plan.Status == PlanStatusPending {
+		n.Fields["expires_at"] = plan.ExpiresAt.Format(time.RFC3339)
+	}
+	app.notify(n)
+}
+
+// notifyStakes sends the stakes_submitted event when at least one stake tx was sent
+func (app *App) notifyStakes(run *RunRecord, gainChangePercent float64) {
+	counts := make(map[string]int)
+	sent := 0
+	details := make([]string, 0, len(run.Results))
+	for _, result := range run.Results {
+		counts[string(result.Status)]++
+		if result.IsSkipped() {
+			continue
+		}
+		sent++
+		detail := fmt.Sprintf("%s: %s %s", result.Address, result.Status, strings.Join(result.Chains, ","))
+		if !IsEmptyString(result.Hash) {
+			detail += " tx " + result.Hash
+		}
+		if !IsEmptyString(result.Error) {
+			detail += " error: " + result.Error
+		}
+		details = append(details, detail)
+	}
+	if sent == 0 {
+		return
+	}
+
+	n := NewNotification(EventStakesSubmitted, fmt.Sprintf("%d stake transactions processed", sent)).WithGain(gainChangePercent)
+	n.RunID = run.ID
+	n.PlanID = run.PlanID
+	for status, count := range counts {
+		n.Fields[status] = count
+	}
+	n.Details = details
+	app.notify(n)
+}
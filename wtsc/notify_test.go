@@ -0,0 +1,47 @@
+package wtsc
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// closedServerURL returns the url of a server that is no longer listening, so requests to it fail
+func closedServerURL(t *testing.T) string {
+	t.Helper()
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+	return server.URL
+}
+
+func TestWebhookSendDoesNotLeakURL(t *testing.T) {
+	const secret = "T000/B000/XXXXXXXXXXXXXXXX"
+	n := &Notification{Event: EventTxFailed, Message: "test", Fields: map[string]any{}}
+
+	for _, format := range []string{WebhookFormatGeneric, WebhookFormatSlack, WebhookFormatDiscord} {
+		t.Run(format, func(t *testing.T) {
+			webhook := &WebhookConfig{URL: closedServerURL(t) + "/services/" + secret, Format: format}
+			err := webhook.Send(context.Background(), n)
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			if strings.Contains(err.Error(), secret) {
+				t.Fatalf("error leaks the url: %s", err)
+			}
+		})
+	}
+}
+
+func TestWebhookSendInvalidURLDoesNotLeakURL(t *testing.T) {
+	const secret = "XXXXXXXXXXXXXXXX"
+	webhook := &WebhookConfig{URL: "http://hooks.example.com:port/" + secret}
+	err := webhook.Send(context.Background(), &Notification{Event: EventTxFailed, Fields: map[string]any{}})
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if strings.Contains(err.Error(), secret) {
+		t.Fatalf("error leaks the url: %s", err)
+	}
+}
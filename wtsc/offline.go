@@ -0,0 +1,448 @@
+package wtsc
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	pocketGoProvider "github.com/pokt-foundation/pocket-go/provider"
+	"os"
+	"strings"
+	"time"
+)
+
+var (
+	ErrBundleNetwork  = errors.New("bundle network_id does not match the config")
+	ErrBundleUnsigned = errors.New("bundle is unsigned, assemble it with the signatures first")
+	ErrBundleSigned   = errors.New("bundle is already signed")
+)
+
+// TxBundle is a set of signed stake txs exported to be broadcast later, probably from another machine. The txs
+// could be signed on an air-gapped machine from a plan created online, since the plan has the on-chain state of
+// the nodes.
+type TxBundle struct {
+	CreatedAt         time.Time `json:"created_at"`
+	NetworkID         string    `json:"network_id"`
+	PlanID            string    `json:"plan_id,omitempty"`
+	GainChangePercent float64   `json:"gain_change_percent"`
+	// Unsigned bundles are signed by an external tool, see AssembleBundle
+	Unsigned bool        `json:"unsigned,omitempty"`
+	Txs      []*BundleTx `json:"txs"`
+	// Skipped are the changed nodes of the plan that could not be signed and why
+	Skipped map[string]string `json:"skipped,omitempty"`
+}
+
+// BundleTx is a signed stake tx, RawHex is what gets broadcast and Decoded is there to audit it. On an unsigned
+// bundle, RawHex is the tx without signature, SignBytes (hex) is what the node key must sign and Signature (hex)
+// could be filled by the signing tool.
+type BundleTx struct {
+	Address   string          `json:"address"`
+	Chains    []string        `json:"chains"`
+	RawHex    string          `json:"raw_hex"`
+	Decoded   *DecodedStakeTx `json:"decoded"`
+	SignBytes string          `json:"sign_bytes,omitempty"`
+	Signature string          `json:"signature,omitempty"`
+}
+
+// ExportPlan signs the stake txs of the changed nodes of the plan with the local signers, nothing is submitted and
+// no call is made (but to a remote signer). The nodes need the on-chain state that BuildPlan records.
+func (app *App) ExportPlan(plan *Plan) *TxBundle {
+	return app.exportPlan(plan, false)
+}
+
+// ExportUnsignedPlan is ExportPlan without signing, so no key is needed: the public key of each node is the
+// on-chain one. The txs of all the changed own nodes are exported, to be signed by an external tool.
+func (app *App) ExportUnsignedPlan(plan *Plan) *TxBundle {
+	return app.exportPlan(plan, true)
+}
+
+func (app *App) exportPlan(plan *Plan, unsigned bool) *TxBundle {
+	cfg := app.Config()
+	// value is already validated
+	txFee, _ := cfg.TxFee.Int64()
+
+	bundle := &TxBundle{
+		CreatedAt:         app.Clock.Now(),
+		NetworkID:         cfg.NetworkID,
+		PlanID:            plan.ID,
+		GainChangePercent: plan.GainChangePercent,
+		Unsigned:          unsigned,
+		Txs:               make([]*BundleTx, 0),
+		Skipped:           make(map[string]string),
+	}
+
+	for _, node := range plan.Nodes {
+		if !node.Change || !IsEmptyString(node.Error) {
+			continue
+		}
+
+		var tx *BundleTx
+		var err error
+		if unsigned {
+			if !node.Signer && !node.Watched {
+				continue
+			}
+			tx, err = app.exportUnsignedStakeTx(node, cfg.NetworkID, txFee, cfg.TxMemo)
+		} else {
+			signer, ok := app.Signers.Load(node.Address)
+			if !ok {
+				if node.Signer || node.Watched {
+					// our node, but its key is not on this machine
+					bundle.Skipped[node.Address] = "there is no key to sign it"
+				}
+				continue
+			}
+			tx, err = app.exportStakeTx(signer, node, cfg.NetworkID, txFee, cfg.TxMemo)
+		}
+		if err != nil {
+			app.Logger.Error().Err(err).Str("address", node.Address).Msg("failed to export stake node transaction")
+			bundle.Skipped[node.Address] = err.Error()
+			continue
+		}
+
+		app.Logger.Info().Str("address", node.Address).Strs("chains", tx.Chains).Bool("unsigned", unsigned).Msg("stake node transaction exported")
+		bundle.Txs = append(bundle.Txs, tx)
+	}
+
+	return bundle
+}
+
+func (app *App) newExportStakeTx(publicKey string, node *PlanNode, networkID string, txFee int64, memo string) (*StakeTx, error) {
+	if node.OnChain == nil {
+		return nil, errors.New("the plan does not have the on-chain state of the node")
+	}
+
+	msg, err := NewStakeMsg(publicKey, node.OnChain, node.ProposedChains)
+	if err != nil {
+		return nil, err
+	}
+
+	entropy, err := app.Entropy()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate entropy: %w", err)
+	}
+
+	return NewStakeTx(networkID, msg, txFee, memo, entropy), nil
+}
+
+func (app *App) exportStakeTx(signer Signer, node *PlanNode, networkID string, txFee int64, memo string) (*BundleTx, error) {
+	stakeTx, err := app.newExportStakeTx(signer.GetPublicKey(), node, networkID, txFee, memo)
+	if err != nil {
+		return nil, err
+	}
+
+	txBytes, err := stakeTx.Sign(signer)
+	if err != nil {
+		return nil, err
+	}
+
+	decoded, err := DecodeStakeTx(txBytes, networkID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &BundleTx{
+		Address: node.Address,
+		Chains:  node.ProposedChains,
+		RawHex:  hex.EncodeToString(txBytes),
+		Decoded: decoded,
+	}, nil
+}
+
+func (app *App) exportUnsignedStakeTx(node *PlanNode, networkID string, txFee int64, memo string) (*BundleTx, error) {
+	if node.OnChain == nil || IsEmptyString(node.OnChain.PublicKey) {
+		return nil, errors.New("the plan does not have the on-chain public key of the node")
+	}
+
+	stakeTx, err := app.newExportStakeTx(node.OnChain.PublicKey, node, networkID, txFee, memo)
+	if err != nil {
+		return nil, err
+	}
+	// the rpc answer could be wrong, the tx would be signed by the key of another address
+	if address := strings.ToLower(stakeTx.Msg.PublicKey.Address().String()); address != node.Address {
+		return nil, fmt.Errorf("the on-chain public key is the one of %s", address)
+	}
+
+	signBytes, err := stakeTx.SignBytes()
+	if err != nil {
+		return nil, err
+	}
+	txBytes, err := stakeTx.Encode(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// without network id, there is no signature to verify
+	decoded, err := DecodeStakeTx(txBytes, "")
+	if err != nil {
+		return nil, err
+	}
+
+	return &BundleTx{
+		Address:   node.Address,
+		Chains:    node.ProposedChains,
+		RawHex:    hex.EncodeToString(txBytes),
+		Decoded:   decoded,
+		SignBytes: hex.EncodeToString(signBytes),
+	}, nil
+}
+
+// AssembleBundle adds the signatures (hex by address, the ones of the bundle are used otherwise) to the txs of an
+// unsigned bundle and returns the signed bundle, ready to be broadcast. Each signature is verified against the
+// sign bytes rebuilt from the tx, a tx without a valid signature is skipped.
+func AssembleBundle(bundle *TxBundle, signatures map[string]string) (*TxBundle, error) {
+	if !bundle.Unsigned {
+		return nil, ErrBundleSigned
+	}
+
+	signed := &TxBundle{
+		CreatedAt:         bundle.CreatedAt,
+		NetworkID:         bundle.NetworkID,
+		PlanID:            bundle.PlanID,
+		GainChangePercent: bundle.GainChangePercent,
+		Txs:               make([]*BundleTx, 0, len(bundle.Txs)),
+		Skipped:           make(map[string]string),
+	}
+	for address, reason := range bundle.Skipped {
+		signed.Skipped[address] = reason
+	}
+
+	for _, tx := range bundle.Txs {
+		signature, ok := signatures[tx.Address]
+		if !ok {
+			signature = tx.Signature
+		}
+		if IsEmptyString(signature) {
+			signed.Skipped[tx.Address] = "missing signature"
+			continue
+		}
+
+		signedTx, err := assembleStakeTx(tx, bundle.NetworkID, signature)
+		if err != nil {
+			signed.Skipped[tx.Address] = err.Error()
+			continue
+		}
+		signed.Txs = append(signed.Txs, signedTx)
+	}
+
+	return signed, nil
+}
+
+func assembleStakeTx(tx *BundleTx, networkID string, signatureHex string) (*BundleTx, error) {
+	txBytes, err := hex.DecodeString(tx.RawHex)
+	if err != nil {
+		return nil, fmt.Errorf("invalid raw_hex: %w", err)
+	}
+	signature, err := hex.DecodeString(strings.TrimPrefix(strings.TrimSpace(signatureHex), "0x"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid signature: %w", err)
+	}
+
+	// never trust the file, the tx is what gets signed and broadcast, not sign_bytes
+	stakeTx, err := StakeTxFromRaw(txBytes, networkID)
+	if err != nil {
+		return nil, err
+	}
+	if address := strings.ToLower(stakeTx.Msg.PublicKey.Address().String()); address != tx.Address || !IsSameStrSet(stakeTx.Msg.Chains, tx.Chains) {
+		return nil, errors.New("raw_hex is not a stake tx of the address and chains")
+	}
+	signBytes, err := stakeTx.SignBytes()
+	if err != nil {
+		return nil, err
+	}
+	if hex.EncodeToString(signBytes) != tx.SignBytes {
+		return nil, errors.New("sign_bytes does not match raw_hex")
+	}
+
+	valid, err := stakeTx.VerifySignature(signature)
+	if err != nil {
+		return nil, err
+	}
+	if !valid {
+		return nil, errors.New("invalid signature, it must be the ed25519 signature of sign_bytes by the node key")
+	}
+
+	signedBytes, err := stakeTx.Encode(signature)
+	if err != nil {
+		return nil, err
+	}
+	decoded, err := DecodeStakeTx(signedBytes, networkID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &BundleTx{
+		Address: tx.Address,
+		Chains:  tx.Chains,
+		RawHex:  hex.EncodeToString(signedBytes),
+		Decoded: decoded,
+	}, nil
+}
+
+// BroadcastBundle submits the txs of an exported bundle through the pocket rpc and tracks them like the txs of an
+// evaluation, it refuses to in dry mode. The bundle is not modified, a tx that is already on-chain ends as no_change.
+func (app *App) BroadcastBundle(bundle *TxBundle) (*RunRecord, error) {
+	if bundle.Unsigned {
+		return nil, ErrBundleUnsigned
+	}
+
+	if app.State.Paused() {
+		return nil, ErrStakesPaused
+	}
+
+	if !app.State.TryStart() {
+		return nil, ErrEvaluationRunning
+	}
+	defer app.State.Done()
+
+	app, release := app.pin()
+	defer release()
+	cfg := app.Config()
+
+	if bundle.NetworkID != cfg.NetworkID {
+		return nil, fmt.Errorf("%w: %s != %s", ErrBundleNetwork, bundle.NetworkID, cfg.NetworkID)
+	}
+	if cfg.DryMode {
+		return nil, ErrDryMode
+	}
+
+	app.Logger.Info().Int("txs", len(bundle.Txs)).Str("plan_id", bundle.PlanID).Msg("broadcasting stake bundle")
+
+	run := NewRunRecord(app.Clock.Now())
+	run.ConfigVersion = cfg.Version
+	run.ConfigHash = cfg.Hash
+	run.PlanID = bundle.PlanID
+
+	group := app.Workers.Group()
+	report := &StakeReport{}
+	for _, tx := range bundle.Txs {
+		group.Submit(app.BroadcastStakeTx(tx, report))
+	}
+	group.Wait()
+	report.Wait()
+	app.logReport(report)
+
+	run.Results = report.Results()
+	run.Finish(RunOutcomeBroadcast, app.Clock.Now())
+	app.finishRun(run)
+	app.notifyStakes(run, bundle.GainChangePercent)
+
+	return run, nil
+}
+
+// BroadcastStakeTx checks that the bundle tx is a valid stake tx of its address for this network and that the node
+// did not change on-chain since the export (but its chains), then it goes through the same pre-flight, submission
+// and tracking that StakeServicer does.
+func (app *App) BroadcastStakeTx(tx *BundleTx, report *StakeReport) func() {
+	return func() {
+		cfg := app.Config()
+		start := app.Clock.Now()
+		result := &ServicerResult{
+			Address: tx.Address,
+			Chains:  tx.Chains,
+			Status:  TxStatusFailed,
+		}
+		defer app.finishResult(result, start, report)
+
+		txBytes, err := hex.DecodeString(tx.RawHex)
+		if err != nil {
+			result.Error = fmt.Sprintf("invalid raw_hex: %s", err)
+			return
+		}
+
+		// never trust the file, it could have been edited
+		decoded, err := DecodeStakeTx(txBytes, cfg.NetworkID)
+		if err != nil {
+			result.Error = err.Error()
+			return
+		}
+		if decoded.Address != tx.Address || !IsSameStrSet(decoded.Chains, tx.Chains) || !*decoded.SignatureValid {
+			app.Logger.Error().Str("address", tx.Address).Str("tx_address", decoded.Address).Bool("signature_valid", *decoded.SignatureValid).Msg("bundle tx does not match its entry")
+			result.Error = "raw_hex is not a valid stake tx of the address and chains"
+			return
+		}
+		stdTx, err := DecodeTx(txBytes)
+		if err != nil {
+			result.Error = err.Error()
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		node, err := app.Rpc.GetNodeWithCtx(ctx, tx.Address, &pocketGoProvider.GetNodeOptions{Height: 0})
+		if err != nil {
+			app.Logger.Error().Err(err).Str("address", tx.Address).Msg("failed to get pocket node")
+			result.Error = err.Error()
+			return
+		}
+		if IsSameStrSet(node.Chains, tx.Chains) {
+			app.Logger.Info().Str("address", tx.Address).Strs("chains", node.Chains).Msg("node already has the bundle chains, skipping stake")
+			result.Status = TxStatusNoChange
+			return
+		}
+		// the tx restakes the node as it was when the plan was made, it would undo any change made since then
+		if field := staleBundleField(decoded, node.Node); !IsEmptyString(field) {
+			app.Logger.Error().Str("address", tx.Address).Str("field", field).Msg("node changed on-chain since the bundle was exported, skipping stake")
+			result.Error = fmt.Sprintf("stale bundle: %s changed on-chain, export it again", field)
+			return
+		}
+
+		if !app.checkBalance(ctx, tx.Address, stdTx.Fee.AmountOf(FeeDenom).Int64(), cfg.LowBalanceRestakes, result) {
+			return
+		}
+
+		app.submitTx(ctx, txBytes, result)
+	}
+}
+
+// staleBundleField returns the first field of the stake tx, but the chains, that is not the one of the on-chain node
+func staleBundleField(decoded *DecodedStakeTx, node *pocketGoProvider.Node) string {
+	switch {
+	case decoded.Value != node.Tokens:
+		return "value"
+	case decoded.ServiceURL != node.ServiceURL:
+		return "service_url"
+	case !strings.EqualFold(decoded.OutputAddress, node.OutputAddress):
+		return "output_address"
+	case !isSameRewardDelegators(decoded.RewardDelegators, node.RewardDelegators):
+		return "reward_delegators"
+	}
+	return ""
+}
+
+func isSameRewardDelegators(delegators1, delegators2 map[string]uint32) bool {
+	if len(delegators1) != len(delegators2) {
+		return false
+	}
+	for address, share := range delegators1 {
+		if other, ok := delegators2[address]; !ok || other != share {
+			return false
+		}
+	}
+	return true
+}
+
+// WriteTxBundle writes the bundle as json
+func WriteTxBundle(path string, bundle *TxBundle) error {
+	bz, err := json.MarshalIndent(bundle, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(bz, '\n'), 0644)
+}
+
+// ReadTxBundle reads a bundle written by WriteTxBundle
+func ReadTxBundle(path string) (*TxBundle, error) {
+	bz, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	bundle := &TxBundle{}
+	if e := json.Unmarshal(bz, bundle); e != nil {
+		return nil, fmt.Errorf("invalid bundle %s: %w", path, e)
+	}
+	return bundle, nil
+}
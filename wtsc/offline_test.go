@@ -0,0 +1,101 @@
+package wtsc
+
+import (
+	"context"
+	"errors"
+	pocketGoProvider "github.com/pokt-foundation/pocket-go/provider"
+	"strings"
+	"testing"
+)
+
+// newTestBundle exports the stake of the signer node to chains, as read from rpc
+func newTestBundle(t *testing.T, app *App, rpc *fakeRpc, signer Signer, chains []string) *TxBundle {
+	t.Helper()
+	out, err := rpc.GetNodeWithCtx(context.Background(), signer.GetAddress(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bundle := app.ExportPlan(&Plan{ID: "plan", Nodes: []*PlanNode{{
+		Address:        signer.GetAddress(),
+		CurrentChains:  out.Node.Chains,
+		ProposedChains: chains,
+		Change:         true,
+		Signer:         true,
+		OnChain:        out.Node,
+	}}})
+	if len(bundle.Txs) != 1 {
+		t.Fatalf("exported %d txs, skipped %v", len(bundle.Txs), bundle.Skipped)
+	}
+	return bundle
+}
+
+func TestBroadcastBundleDryMode(t *testing.T) {
+	signer := newTestSigner(t, "node")
+	rpc := newFakeRpc()
+	rpc.addNode(signer, []string{"0001"})
+	cfg := &Config{NetworkID: "testnet", TxFee: "10000", MaxWorkers: 1, MaxTimeout: 1000}
+	app := newTestApp(t, cfg, rpc, newTestSigners(signer))
+	bundle := newTestBundle(t, app, rpc, signer, []string{"0021"})
+
+	cfg.DryMode = true
+	if _, err := app.BroadcastBundle(bundle); !errors.Is(err, ErrDryMode) {
+		t.Fatalf("got %v, expected %s", err, ErrDryMode)
+	}
+	if rpc.sentTxs() != 0 {
+		t.Fatalf("sent %d txs on dry mode", rpc.sentTxs())
+	}
+}
+
+func TestBroadcastBundleStale(t *testing.T) {
+	cases := []struct {
+		name   string
+		field  string
+		change func(node *pocketGoProvider.Node)
+	}{
+		{name: "value", field: "value", change: func(node *pocketGoProvider.Node) { node.Tokens = "16000000000" }},
+		{name: "service url", field: "service_url", change: func(node *pocketGoProvider.Node) { node.ServiceURL = "https://moved.wtsc.test:443" }},
+		{name: "output address", field: "output_address", change: func(node *pocketGoProvider.Node) { node.OutputAddress = strings.Repeat("a", 40) }},
+		{name: "reward delegators", field: "reward_delegators", change: func(node *pocketGoProvider.Node) {
+			node.RewardDelegators = map[string]uint32{strings.Repeat("b", 40): 10}
+		}},
+		{name: "unchanged"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			signer := newTestSigner(t, "node")
+			rpc := newFakeRpc()
+			rpc.addNode(signer, []string{"0001"})
+			app := newTestApp(t, &Config{NetworkID: "testnet", TxFee: "10000", MaxWorkers: 1, MaxTimeout: 1000}, rpc, newTestSigners(signer))
+			bundle := newTestBundle(t, app, rpc, signer, []string{"0021"})
+
+			if c.change != nil {
+				rpc.mu.Lock()
+				c.change(rpc.nodes[signer.GetAddress()])
+				rpc.mu.Unlock()
+			}
+
+			run, err := app.BroadcastBundle(bundle)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(run.Results) != 1 {
+				t.Fatalf("unexpected results %+v", run.Results)
+			}
+			result := run.Results[0]
+
+			if c.change == nil {
+				if result.Status != TxStatusSubmitted || rpc.sentTxs() != 1 {
+					t.Fatalf("got %s with %d txs sent, expected the tx to be submitted", result.Status, rpc.sentTxs())
+				}
+				return
+			}
+			if result.Status != TxStatusFailed || !strings.Contains(result.Error, "stale bundle: "+c.field) {
+				t.Fatalf("got %s (%s), expected a stale %s", result.Status, result.Error, c.field)
+			}
+			if rpc.sentTxs() != 0 {
+				t.Fatalf("sent %d txs of a stale bundle", rpc.sentTxs())
+			}
+		})
+	}
+}
@@ -0,0 +1,393 @@
+package wtsc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	pocketGoProvider "github.com/pokt-foundation/pocket-go/provider"
+	"github.com/pokt-scan/wtsc/wtsc/generated"
+	bolt "go.etcd.io/bbolt"
+	"time"
+)
+
+const (
+	// DefaultPlanTTL is used when plan_ttl is not set (minutes)
+	DefaultPlanTTL = 60
+)
+
+type PlanStatus string
+
+const (
+	// PlanStatusPending the plan is waiting for approval
+	PlanStatusPending PlanStatus = "pending"
+	// PlanStatusApplying the plan was approved and its stake txs are being submitted, it is never applied again
+	PlanStatusApplying PlanStatus = "applying"
+	// PlanStatusApplied the plan was approved and its stake txs were submitted
+	PlanStatusApplied PlanStatus = "applied"
+	// PlanStatusFailed the plan was approved but some of its stake txs failed, check the results of its apply run
+	PlanStatusFailed PlanStatus = "failed"
+	// PlanStatusExpired the plan was not approved before its TTL
+	PlanStatusExpired PlanStatus = "expired"
+)
+
+var (
+	ErrHistoryDisabled   = errors.New("history_path is not configured")
+	ErrPlanNotPending    = errors.New("plan is not pending")
+	ErrPlanExpired       = errors.New("plan is expired")
+	ErrStakesPaused      = errors.New("stake transactions are paused")
+	ErrEvaluationRunning = errors.New("an evaluation is already running")
+	ErrDryMode           = errors.New("dry_mode is on, stake transactions are not sent")
+)
+
+// PlanNode is the difference between the on-chain state of a node and the what-to-stake recommendation.
+type PlanNode struct {
+	Address        string   `json:"address"`
+	CurrentChains  []string `json:"current_chains"`
+	ProposedChains []string `json:"proposed_chains"`
+	// Change is true when the proposed chains are not the ones on-chain
+	Change bool `json:"change"`
+	// Signer is true when there is a key able to sign the stake of this node
+	Signer bool `json:"signer"`
+	// Watched is true when the node is on servicer_addresses
+	Watched bool   `json:"watched"`
+	Error   string `json:"error,omitempty"`
+	// OnChain is the node as read from the rpc, it has everything needed to sign its stake tx offline
+	OnChain *pocketGoProvider.Node `json:"on_chain,omitempty"`
+}
+
+// Plan is what an evaluation would do with the what-to-stake recommendation.
+type Plan struct {
+	// ID, RunID, Status and ExpiresAt are only set on plans that need approval
+	ID         string     `json:"id,omitempty"`
+	RunID      string     `json:"run_id,omitempty"`
+	Status     PlanStatus `json:"status,omitempty"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	AppliedAt  *time.Time `json:"applied_at,omitempty"`
+	ApplyRunID string     `json:"apply_run_id,omitempty"`
+
+	CreatedAt             time.Time   `json:"created_at"`
+	DoUpdate              bool        `json:"do_update"`
+	Reason                string      `json:"reason"`
+	GainChangePercent     float64     `json:"gain_change_percent"`
+	CurrentModeledGain24h float64     `json:"current_modeled_gain_24h"`
+	OptimalModeledGain24h float64     `json:"optimal_modeled_gain_24h"`
+	Nodes                 []*PlanNode `json:"nodes"`
+}
+
+// NewWtsInput builds the what-to-stake request input from the config
+func NewWtsInput(cfg *Config) generated.WtsProcessRequestInput {
+	return generated.WtsProcessRequestInput{
+		Domain:               cfg.Domain,
+		Service_pool:         cfg.ServicePool,
+		Min_increase_percent: cfg.MinIncreasePercent,
+		Stake_weight:         int(cfg.StakeWeight),
+		Min_service_stake:    cfg.MinServiceStake.CastToGqlType(),
+		Time_period:          int(cfg.TimePeriod),
+	}
+}
+
+// BuildPlan reads the on-chain state of every recommended servicer and compares it with the recommendation.
+// It does not touch chain state.
+func (app *App) BuildPlan(ctx context.Context, resp *generated.GetWhatToStakeResponse) *Plan {
+	app, release := app.pin()
+	defer release()
+
+	wts := resp.GetWhatToStake
+	plan := &Plan{
+		CreatedAt:             app.Clock.Now(),
+		DoUpdate:              wts.Do_update,
+		Reason:                wts.Reason,
+		GainChangePercent:     wts.Gain_change_percent,
+		CurrentModeledGain24h: wts.Current_modeled_gain_24h,
+		OptimalModeledGain24h: wts.Optimal_modeled_gain_24h,
+		Nodes:                 make([]*PlanNode, len(wts.Servicers)),
+	}
+
+	group := app.Workers.Group()
+
+	for i := range wts.Servicers {
+		servicer := &wts.Servicers[i]
+		planNode := &PlanNode{
+			Address:        servicer.Address,
+			ProposedChains: servicer.Services,
+		}
+		_, planNode.Signer = app.Signers.Load(servicer.Address)
+		planNode.Watched = app.IsWatchedAddress(servicer.Address)
+		plan.Nodes[i] = planNode
+
+		group.Submit(func() {
+			node, err := app.Rpc.GetNodeWithCtx(ctx, planNode.Address, &pocketGoProvider.GetNodeOptions{Height: 0})
+			if err != nil {
+				app.Logger.Error().Err(err).Str("address", planNode.Address).Msg("failed to get pocket node")
+				planNode.Error = err.Error()
+				return
+			}
+			planNode.CurrentChains = node.Chains
+			planNode.OnChain = node.Node
+			planNode.Change = !IsSameStrSet(node.Chains, planNode.ProposedChains)
+		})
+	}
+
+	group.Wait()
+
+	return plan
+}
+
+// ChangedNodes returns the nodes that need a stake tx and can be signed
+func (p *Plan) ChangedNodes() (nodes []*PlanNode) {
+	for _, node := range p.Nodes {
+		if node.Change && node.Signer && IsEmptyString(node.Error) {
+			nodes = append(nodes, node)
+		}
+	}
+	return
+}
+
+// OwnNodes returns the nodes that have a signer or are watched, the rest belong to other runners of the domain
+func (p *Plan) OwnNodes() (nodes []*PlanNode) {
+	for _, node := range p.Nodes {
+		if node.Signer || node.Watched {
+			nodes = append(nodes, node)
+		}
+	}
+	return
+}
+
+// IsWatchedAddress reports if the address is on servicer_addresses
+func (app *App) IsWatchedAddress(address string) bool {
+	for _, watched := range app.Config().ServicerAddresses {
+		if watched == address {
+			return true
+		}
+	}
+	return false
+}
+
+// IsWatchOnly reports if there are watch-only addresses but no signers, so stake txs are never sent
+func (app *App) IsWatchOnly() bool {
+	return app.Signers.Size() == 0 && len(app.Config().ServicerAddresses) > 0
+}
+
+// ReportPlan logs the difference between the on-chain state of our nodes and the recommendation
+func (app *App) ReportPlan(plan *Plan) {
+	changes := 0
+	for _, node := range plan.OwnNodes() {
+		if !IsEmptyString(node.Error) {
+			app.Logger.Warn().Str("address", node.Address).Str("error", node.Error).Msg("unable to compare servicer")
+			continue
+		}
+		if !node.Change {
+			app.Logger.Info().Str("address", node.Address).Strs("chains", node.CurrentChains).Msg("servicer matches the recommendation")
+			continue
+		}
+		changes++
+		app.Logger.Info().
+			Str("address", node.Address).
+			Strs("current_chains", node.CurrentChains).
+			Strs("proposed_chains", node.ProposedChains).
+			Bool("signer", node.Signer).
+			Msg("servicer differs from the recommendation")
+	}
+
+	app.Logger.Info().
+		Bool("do_update", plan.DoUpdate).
+		Float64("gain_change_percent", plan.GainChangePercent).
+		Int("nodes", len(plan.OwnNodes())).
+		Int("changes", changes).
+		Msg("recommendation compared with on-chain state")
+}
+
+// IsExpired reports if a pending plan is past its TTL
+func (p *Plan) IsExpired(now time.Time) bool {
+	return p.ExpiresAt != nil && now.After(*p.ExpiresAt)
+}
+
+// NeedsApproval reports if the plan must be approved by a human before it is applied
+func (app *App) NeedsApproval(plan *Plan) bool {
+	cfg := app.Config()
+	if !cfg.RequireApproval {
+		return false
+	}
+	// a plan without changes has nothing to approve, even when approval_min_nodes is 0
+	changed := uint(len(plan.ChangedNodes()))
+	return changed > 0 && changed >= cfg.ApprovalMinNodes
+}
+
+// ProposePlan persists the plan as pending, it will need to be applied before its TTL expires
+func (app *App) ProposePlan(plan *Plan, runID string) error {
+	history := app.History()
+	if history == nil {
+		return ErrHistoryDisabled
+	}
+
+	ttl := app.Config().PlanTTL
+	if ttl == 0 {
+		// plans created by the cli without require_approval
+		ttl = DefaultPlanTTL
+	}
+
+	expiresAt := plan.CreatedAt.Add(time.Duration(ttl) * time.Minute)
+	plan.ID = NewRecordID(plan.CreatedAt)
+	plan.RunID = runID
+	plan.Status = PlanStatusPending
+	plan.ExpiresAt = &expiresAt
+
+	if err := history.SavePlan(plan); err != nil {
+		return err
+	}
+
+	app.Logger.Warn().
+		Str("plan_id", plan.ID).
+		Int("nodes", len(plan.ChangedNodes())).
+		Time("expires_at", expiresAt).
+		Msg("stake plan is waiting for approval")
+
+	return nil
+}
+
+// ApplyPlan submits the stake txs of a pending plan. The plan is claimed before, so it is applied only once even
+// when the daemon and the cli try at the same time. Then it is marked as applied, or failed when any tx failed,
+// which is recorded on the returned run.
+func (app *App) ApplyPlan(id string) (*RunRecord, error) {
+	if app.State.Paused() {
+		return nil, ErrStakesPaused
+	}
+
+	if !app.State.TryStart() {
+		return nil, ErrEvaluationRunning
+	}
+	defer app.State.Done()
+
+	app, release := app.pin()
+	defer release()
+	cfg := app.Config()
+
+	history := app.History()
+	if history == nil {
+		return nil, ErrHistoryDisabled
+	}
+	if cfg.DryMode {
+		return nil, ErrDryMode
+	}
+
+	plan, err := history.ClaimPlan(id, app.Clock.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	app.Logger.Info().Str("plan_id", plan.ID).Msg("applying stake plan")
+
+	run := NewRunRecord(app.Clock.Now())
+	run.ConfigVersion = cfg.Version
+	run.ConfigHash = cfg.Hash
+	run.PlanID = plan.ID
+
+	nodes := plan.ChangedNodes()
+	servicers := make([]generated.GetWhatToStakeGetWhatToStakeWtsOptimizationResponseServicersWtsStakeNode, len(nodes))
+	for i, node := range nodes {
+		servicers[i].Address = node.Address
+		servicers[i].Services = node.ProposedChains
+	}
+
+	run.Results = app.submitStakes(servicers)
+	run.Finish(RunOutcomeApplied, app.Clock.Now())
+
+	appliedAt := app.Clock.Now()
+	plan.Status = PlanStatusApplied
+	for _, result := range run.Results {
+		if result.IsFailure() {
+			plan.Status = PlanStatusFailed
+			break
+		}
+	}
+	plan.AppliedAt = &appliedAt
+	plan.ApplyRunID = run.ID
+	if e := history.SavePlan(plan); e != nil {
+		app.Logger.Error().Err(e).Str("plan_id", plan.ID).Msg("failed to save plan")
+	}
+
+	app.finishRun(run)
+	app.notifyStakes(run, plan.GainChangePercent)
+
+	return run, nil
+}
+
+// SavePlan stores (or replace) the plan
+func (hs *HistoryStore) SavePlan(plan *Plan) error {
+	return hs.put(historyPlansBucket, plan.ID, plan)
+}
+
+// ClaimPlan marks a pending plan as applying and returns it. The check and the update are a single transaction
+// of the (file locked) database, so only one process is able to claim a plan. An expired plan is marked as
+// expired and ErrPlanExpired is returned.
+func (hs *HistoryStore) ClaimPlan(id string, now time.Time) (*Plan, error) {
+	db, err := hs.open(false)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	plan := &Plan{}
+	expired := false
+	err = db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(historyPlansBucket)
+		if bucket == nil {
+			return ErrRecordNotFound
+		}
+		bz := bucket.Get([]byte(id))
+		if bz == nil {
+			return ErrRecordNotFound
+		}
+		if e := json.Unmarshal(bz, plan); e != nil {
+			return e
+		}
+
+		if plan.Status != PlanStatusPending {
+			return fmt.Errorf("%w: status is %s", ErrPlanNotPending, plan.Status)
+		}
+
+		if plan.IsExpired(now) {
+			// keep it, an error would roll back the transaction
+			expired = true
+			plan.Status = PlanStatusExpired
+		} else {
+			plan.Status = PlanStatusApplying
+		}
+
+		bz, e := json.Marshal(plan)
+		if e != nil {
+			return e
+		}
+		return bucket.Put([]byte(id), bz)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if expired {
+		return nil, ErrPlanExpired
+	}
+	return plan, nil
+}
+
+// GetPlan returns a single plan by id
+func (hs *HistoryStore) GetPlan(id string) (*Plan, error) {
+	plan := &Plan{}
+	if err := hs.get(historyPlansBucket, id, plan); err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+
+// ListPlans returns the latest plans first, up to limit. Zero limit returns all of them.
+func (hs *HistoryStore) ListPlans(limit int) (plans []*Plan, err error) {
+	err = hs.list(historyPlansBucket, limit, func(v []byte) error {
+		plan := &Plan{}
+		if e := json.Unmarshal(v, plan); e != nil {
+			return e
+		}
+		plans = append(plans, plan)
+		return nil
+	})
+	return
+}
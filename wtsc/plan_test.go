@@ -0,0 +1,173 @@
+package wtsc
+
+import (
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newTestHistoryStore(t *testing.T) *HistoryStore {
+	t.Helper()
+	return &HistoryStore{path: filepath.Join(t.TempDir(), "history.db")}
+}
+
+func newTestPlan(t *testing.T, hs *HistoryStore, expiresAt time.Time) *Plan {
+	t.Helper()
+	plan := &Plan{
+		ID:        NewRecordID(time.Now()),
+		Status:    PlanStatusPending,
+		ExpiresAt: &expiresAt,
+		CreatedAt: time.Now(),
+		Nodes:     []*PlanNode{{Address: "a", ProposedChains: []string{"0021"}, Change: true, Signer: true}},
+	}
+	if err := hs.SavePlan(plan); err != nil {
+		t.Fatal(err)
+	}
+	return plan
+}
+
+func TestClaimPlanOnlyOnce(t *testing.T) {
+	hs := newTestHistoryStore(t)
+	plan := newTestPlan(t, hs, time.Now().Add(time.Hour))
+
+	const claimers = 8
+	var wg sync.WaitGroup
+	errs := make(chan error, claimers)
+	for i := 0; i < claimers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := hs.ClaimPlan(plan.ID, time.Now())
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	claimed := 0
+	for err := range errs {
+		switch {
+		case err == nil:
+			claimed++
+		case !errors.Is(err, ErrPlanNotPending):
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+	if claimed != 1 {
+		t.Fatalf("plan claimed %d times", claimed)
+	}
+
+	stored, err := hs.GetPlan(plan.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Status != PlanStatusApplying {
+		t.Fatalf("status is %s, expected %s", stored.Status, PlanStatusApplying)
+	}
+}
+
+func TestClaimPlanExpired(t *testing.T) {
+	hs := newTestHistoryStore(t)
+	plan := newTestPlan(t, hs, time.Now().Add(-time.Minute))
+
+	if _, err := hs.ClaimPlan(plan.ID, time.Now()); !errors.Is(err, ErrPlanExpired) {
+		t.Fatalf("got %v, expected %s", err, ErrPlanExpired)
+	}
+
+	stored, err := hs.GetPlan(plan.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Status != PlanStatusExpired {
+		t.Fatalf("status is %s, expected %s", stored.Status, PlanStatusExpired)
+	}
+}
+
+func TestClaimPlanNotFound(t *testing.T) {
+	hs := newTestHistoryStore(t)
+	newTestPlan(t, hs, time.Now().Add(time.Hour))
+
+	if _, err := hs.ClaimPlan("missing", time.Now()); !errors.Is(err, ErrRecordNotFound) {
+		t.Fatalf("got %v, expected %s", err, ErrRecordNotFound)
+	}
+}
+
+func TestNeedsApproval(t *testing.T) {
+	changed := &PlanNode{Address: "a", Change: true, Signer: true}
+	unchanged := &PlanNode{Address: "b", Signer: true}
+
+	cases := []struct {
+		name     string
+		required bool
+		minNodes uint
+		nodes    []*PlanNode
+		expected bool
+	}{
+		{name: "not required", minNodes: 0, nodes: []*PlanNode{changed}},
+		{name: "empty plan", required: true, minNodes: 0, nodes: []*PlanNode{unchanged}},
+		{name: "any change", required: true, minNodes: 0, nodes: []*PlanNode{changed, unchanged}, expected: true},
+		{name: "below min nodes", required: true, minNodes: 2, nodes: []*PlanNode{changed, unchanged}},
+		{name: "min nodes", required: true, minNodes: 1, nodes: []*PlanNode{changed}, expected: true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			cfg := &Config{RequireApproval: c.required, ApprovalMinNodes: c.minNodes}
+			app := &App{Config: func() *Config { return cfg }}
+			if got := app.NeedsApproval(&Plan{Nodes: c.nodes}); got != c.expected {
+				t.Fatalf("got %t, expected %t", got, c.expected)
+			}
+		})
+	}
+}
+
+func TestApplyPlanDryMode(t *testing.T) {
+	hs := newTestHistoryStore(t)
+	plan := newTestPlan(t, hs, time.Now().Add(time.Hour))
+	app := newTestApp(t, &Config{DryMode: true, MaxWorkers: 1}, newFakeRpc(), newTestSigners())
+	app.History = func() *HistoryStore { return hs }
+	if _, err := app.ApplyPlan(plan.ID); !errors.Is(err, ErrDryMode) {
+		t.Fatalf("got %v, expected %s", err, ErrDryMode)
+	}
+
+	stored, err := hs.GetPlan(plan.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Status != PlanStatusPending {
+		t.Fatalf("status is %s, expected the plan to stay %s", stored.Status, PlanStatusPending)
+	}
+}
+
+func TestApplyPlan(t *testing.T) {
+	signer := newTestSigner(t, "0")
+	rpc := newFakeRpc()
+	rpc.addNode(signer, []string{"0001"})
+	hs := newTestHistoryStore(t)
+	app := newTestApp(t, &Config{NetworkID: "testnet", TxFee: "10000", MaxWorkers: 1, MaxTimeout: 1000}, rpc, newTestSigners(signer))
+	app.History = func() *HistoryStore { return hs }
+	var observed *RunRecord
+	app.Observe = func(run *RunRecord) { observed = run }
+
+	plan := newTestPlan(t, hs, time.Now().Add(time.Hour))
+	plan.Nodes[0].Address = signer.GetAddress()
+	if err := hs.SavePlan(plan); err != nil {
+		t.Fatal(err)
+	}
+
+	run, err := app.ApplyPlan(plan.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if run.Outcome != RunOutcomeApplied || run.PlanID != plan.ID || observed != run {
+		t.Fatalf("got %s for plan %q, expected %s", run.Outcome, run.PlanID, RunOutcomeApplied)
+	}
+	if len(run.Results) != 1 || run.Results[0].Status != TxStatusSubmitted || rpc.sentTxs() != 1 {
+		t.Fatalf("unexpected results %+v", run.Results)
+	}
+	if stored, e := hs.GetPlan(plan.ID); e != nil || stored.Status != PlanStatusApplied {
+		t.Fatalf("plan is not applied: %v", e)
+	}
+}
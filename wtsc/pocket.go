@@ -2,11 +2,12 @@ package wtsc
 
 import (
 	"context"
-	"crypto/rand"
+	"crypto/sha256"
 	"encoding/hex"
+	"errors"
+	"fmt"
 	"github.com/hashicorp/go-cleanhttp"
 	pocketGoProvider "github.com/pokt-foundation/pocket-go/provider"
-	pocketGoSigner "github.com/pokt-foundation/pocket-go/signer"
 	pocketGoUtils "github.com/pokt-foundation/pocket-go/utils"
 	pocketCoreCodec "github.com/pokt-network/pocket-core/codec"
 	pocketCoreCodecTypes "github.com/pokt-network/pocket-core/codec/types"
@@ -22,9 +23,7 @@ import (
 	pocketCore "github.com/pokt-network/pocket-core/x/pocketcore"
 	"github.com/pokt-scan/wtsc/wtsc/generated"
 	cryptoamino "github.com/tendermint/tendermint/crypto/encoding/amino"
-	"math"
-	"math/big"
-	"strconv"
+	"strings"
 	"time"
 )
 
@@ -54,6 +53,27 @@ func MakeCodec() {
 	pocketCoreCodec.RegisterEvidences(PocketCoreCodec.AminoCodec(), PocketCoreCodec.ProtoCodec())
 }
 
+// DecodeTx decodes a raw tx like the pocket nodes do. DefaultTxDecoder of pocket-core rejects a MsgStake before the
+// non-custodial upgrade height, which is only known by a node, so the codec is called directly.
+func DecodeTx(txBytes []byte) (*pocketCoreAuthTypes.StdTx, error) {
+	if len(txBytes) == 0 {
+		return nil, errors.New("tx bytes are empty")
+	}
+
+	tx := pocketCoreAuthTypes.StdTx{}
+	if err := Codec().UnmarshalBinaryLengthPrefixed(txBytes, &tx, -1); err != nil {
+		return nil, fmt.Errorf("failed to decode tx: %w", err)
+	}
+
+	return &tx, nil
+}
+
+// TxHash returns the hash used by the pocket rpc to identify a raw tx
+func TxHash(txBytes []byte) string {
+	hash := sha256.Sum256(txBytes)
+	return strings.ToUpper(hex.EncodeToString(hash[:]))
+}
+
 func IsValidChainPool(chains []string) bool {
 	if len(chains) == 0 {
 		return false
@@ -82,6 +102,16 @@ func IsValidServicerList(servicerList []string, isDryMode bool) bool {
 	return true
 }
 
+func IsValidAddressList(addressList []string) bool {
+	for _, address := range addressList {
+		if !pocketGoUtils.ValidateAddress(address) {
+			return false
+		}
+	}
+
+	return true
+}
+
 func IsValidMinServiceStake(minServiceStakeList MinServiceStake) bool {
 	for _, minServiceStake := range minServiceStakeList {
 		if err := pocketCoreNodesTypes.ValidateNetworkIdentifier(minServiceStake.Service); err != nil {
@@ -92,134 +122,136 @@ func IsValidMinServiceStake(minServiceStakeList MinServiceStake) bool {
 	return true
 }
 
-func StakeServicer(
-	signer *pocketGoSigner.Signer,
+func (app *App) StakeServicer(
+	signer Signer,
 	servicer *generated.GetWhatToStakeGetWhatToStakeWtsOptimizationResponseServicersWtsStakeNode,
+	report *StakeReport,
 ) func() {
 	return func() {
-		// stake
-		Logger.Debug().Str("address", signer.GetAddress()).Msg("reading node from rpc")
-		node, err := PocketRpcProvider.GetNode(servicer.Address, &pocketGoProvider.GetNodeOptions{Height: 0})
-		if err != nil {
-			Logger.Error().Err(err).Str("address", servicer.Address).Msg("failed to get pocket node")
-			return
-		}
-		nodeTokens, err := strconv.ParseInt(node.Tokens, 10, 64)
-		if err != nil {
-			Logger.Error().Err(err).Str("address", servicer.Address).Str("tokens", node.Tokens).Msg("failed to parse pocket node tokens")
-			return
-		}
-		// --- @NOTE: this should be the required code using pocket-go package but fail due to some unnecessary imports
-		//txBuilder := pocketGoTxBuilder.NewTransactionBuilder(pocketRpcProvider, signer)
-		//
-		//txMsg, err := pocketGoTxBuilder.NewStakeNode(
-		//	signer.GetPublicKey(),
-		//	node.ServiceURL,
-		//	node.OutputAddress,
-		//	servicer.Chains,
-		//	nodeTokens,
-		//)
-		//txOptions := pocketGoTxBuilder.TransactionOptions{
-		//	Memo: AppConfig.TxMemo,
-		//	Fee:  txFee,
-		//}
-		//
-		//result, err := txBuilder.SubmitWithCtx(ctx, AppConfig.NetworkID, txMsg, &txOptions)
-		// --- @END
-
-		// Instead I basically copy&paste just importing the codec things to allows handle the transaction properly.
-		if err != nil {
-			Logger.Error().Err(err).Msg("failed to create stake node tx message")
-			return
+		cfg := app.Config()
+		start := app.Clock.Now()
+		result := &ServicerResult{
+			Address: servicer.Address,
+			Chains:  servicer.Services,
+			Status:  TxStatusFailed,
 		}
+		defer app.finishResult(result, start, report)
 
 		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 		defer cancel()
 
-		// value is already validated
-		txFee, _ := AppConfig.TxFee.Int64()
-
-		feeStruct := pocketCoreTypes.Coins{
-			pocketCoreTypes.Coin{
-				Amount: pocketCoreTypes.NewInt(txFee),
-				Denom:  "upokt",
-			},
-		}
-
-		entropy, err := rand.Int(rand.Reader, big.NewInt(math.MaxInt64))
+		// stake
+		app.Logger.Debug().Str("address", signer.GetAddress()).Msg("reading node from rpc")
+		node, err := app.Rpc.GetNodeWithCtx(ctx, servicer.Address, &pocketGoProvider.GetNodeOptions{Height: 0})
 		if err != nil {
-			Logger.Error().Err(err).Msg("failed to generate entropy")
+			app.Logger.Error().Err(err).Str("address", servicer.Address).Msg("failed to get pocket node")
+			result.Error = err.Error()
 			return
 		}
-
-		cryptoPublicKey, err := pocketCoreCrypto.NewPublicKey(signer.GetPublicKey())
-		if err != nil {
-			Logger.Error().Err(err).Msg("failed to create crypto")
+		if IsSameStrSet(node.Chains, servicer.Services) {
+			// staking the same chains again only costs tx_fee and burns a session edit
+			app.Logger.Info().Str("address", servicer.Address).Strs("chains", node.Chains).Msg("node already has the recommended chains, skipping stake")
+			result.Status = TxStatusNoChange
 			return
 		}
 
-		decodedAddress, err := hex.DecodeString(node.OutputAddress)
+		msg, err := NewStakeMsg(signer.GetPublicKey(), node.Node, servicer.Services)
 		if err != nil {
-			Logger.Error().Err(err).Msg("failed to decode output address")
+			app.Logger.Error().Err(err).Str("address", servicer.Address).Msg("failed to create stake node tx message")
+			result.Error = err.Error()
+			return
 		}
 
-		txMsg := &pocketCoreNodesTypes.MsgStake{
-			PublicKey:        cryptoPublicKey,
-			Chains:           servicer.Services, // aka chains on morse
-			Value:            pocketCoreTypes.NewInt(nodeTokens),
-			ServiceUrl:       node.ServiceURL,
-			Output:           decodedAddress,
-			RewardDelegators: node.RewardDelegators,
+		// value is already validated
+		txFee, _ := cfg.TxFee.Int64()
+
+		// a tx that can not pay its fee is rejected anyway
+		if !app.checkBalance(ctx, signer.GetAddress(), txFee, cfg.LowBalanceRestakes, result) {
+			return
 		}
 
-		signBytes, err := pocketCoreAuth.StdSignBytes(AppConfig.NetworkID, entropy.Int64(), feeStruct, txMsg, AppConfig.TxMemo)
+		entropy, err := app.Entropy()
 		if err != nil {
-			Logger.Error().Err(err).Msg("")
+			app.Logger.Error().Err(err).Msg("failed to generate entropy")
+			result.Error = err.Error()
 			return
 		}
 
-		signature, err := signer.SignBytes(signBytes)
+		txBytes, err := NewStakeTx(cfg.NetworkID, msg, txFee, cfg.TxMemo, entropy).Sign(signer)
 		if err != nil {
-			Logger.Error().Err(err).Msg("failed to sign transaction")
+			app.Logger.Error().Err(err).Msg("failed to build stake node transaction")
+			result.Error = err.Error()
 			return
 		}
 
-		signatureStruct := pocketCoreAuthTypes.StdSignature{PublicKey: cryptoPublicKey, Signature: signature}
-		tx := pocketCoreAuthTypes.NewTx(txMsg, feeStruct, signatureStruct, AppConfig.TxMemo, entropy.Int64())
+		app.submitTx(ctx, txBytes, result)
+	}
+}
 
-		txBytes, err := pocketCoreAuth.DefaultTxEncoder(Codec())(tx, -1)
+// finishResult reports the result of a servicer. When its tx was submitted and confirmation_blocks is set, it is
+// reported once the tx is tracked, out of the worker pool.
+func (app *App) finishResult(result *ServicerResult, start time.Time, report *StakeReport) {
+	cfg := app.Config()
+	if result.Status != TxStatusSubmitted || cfg.ConfirmationBlocks == 0 {
+		app.reportResult(result, start, report)
+		return
+	}
 
-		signedTX := hex.EncodeToString(txBytes)
+	// mempool acceptance does not mean the stake took effect, so wait for it to be included
+	report.Track(func() {
+		app.TrackTransaction(result, cfg.ConfirmationBlocks, cfg.ConfirmationTimeout, cfg.ConfirmationPollInterval)
+		app.reportResult(result, start, report)
+	})
+}
 
-		sendTransactionInput := &pocketGoProvider.SendTransactionInput{
-			Address:     signer.GetAddress(),
-			RawHexBytes: signedTX,
-		}
+// reportResult adds the result of a servicer to the report and alerts when the stake did not go through
+func (app *App) reportResult(result *ServicerResult, start time.Time, report *StakeReport) {
+	result.DurationMs = app.Clock.Now().Sub(start).Milliseconds()
+	report.Add(result)
+	if result.IsFailure() {
+		n := NewNotification(EventTxFailed, fmt.Sprintf("stake of %s is %s", result.Address, result.Status))
+		n.Fields["address"] = result.Address
+		n.Fields["hash"] = result.Hash
+		n.Fields["error"] = result.Error
+		app.notify(n)
+	}
+}
 
-		txResult, txErr := PocketRpcProvider.SendTransactionWithCtx(ctx, sendTransactionInput)
+// submitTx sends a signed stake tx of result.Address, finishResult tracks it
+func (app *App) submitTx(ctx context.Context, txBytes []byte, result *ServicerResult) {
+	sendTransactionInput := &pocketGoProvider.SendTransactionInput{
+		Address:     result.Address,
+		RawHexBytes: hex.EncodeToString(txBytes),
+	}
 
-		if txErr != nil {
-			Logger.Error().Err(txErr).Msg("failed to submit stake node transaction")
-			return
-		}
+	txResult, txErr := app.Rpc.SendTransactionWithCtx(ctx, sendTransactionInput)
 
-		Logger.Info().
-			Str("address", signer.GetAddress()).
-			Strs("chains", servicer.Services).
-			Str("height", txResult.Height).
-			Str("hash", txResult.Txhash).
-			Str("raw_log", txResult.RawLog).
-			Msg("successfully submitted stake node transaction")
+	if txErr != nil {
+		app.Logger.Error().Err(txErr).Msg("failed to submit stake node transaction")
+		result.Error = txErr.Error()
+		return
 	}
+
+	app.Logger.Info().
+		Str("address", result.Address).
+		Strs("chains", result.Chains).
+		Str("height", txResult.Height).
+		Str("hash", txResult.Txhash).
+		Str("raw_log", txResult.RawLog).
+		Msg("successfully submitted stake node transaction")
+
+	result.Hash = txResult.Txhash
+	result.Status = TxStatusSubmitted
 }
 
-func NewPocketRpcProvider(url string, maxRetries, maxTimeout uint) {
+// NewPocketRpcProvider returns a pocket rpc provider for url, it is reused by every call
+func NewPocketRpcProvider(url string, maxRetries, maxTimeout uint) *pocketGoProvider.Provider {
 	Logger.Info().Msg("preparing pocket rpc client")
-	// create a pocket rpc provider to reuse it
-	PocketRpcProvider = pocketGoProvider.NewProvider(url)
-	PocketRpcProvider.UpdateRequestConfig(pocketGoProvider.RequestConfigOpts{
+	provider := pocketGoProvider.NewProvider(url)
+	provider.UpdateRequestConfig(pocketGoProvider.RequestConfigOpts{
 		Retries:   int(maxRetries),
 		Timeout:   time.Duration(maxTimeout) * time.Millisecond,
 		Transport: cleanhttp.DefaultPooledTransport(),
 	})
+	return provider
 }
@@ -0,0 +1,50 @@
+package wtsc
+
+import (
+	"github.com/pokt-scan/wtsc/wtsc/generated"
+	"testing"
+)
+
+func TestStakeServicerSkipsNoChange(t *testing.T) {
+	cases := []struct {
+		name        string
+		onChain     []string
+		recommended []string
+		status      TxStatus
+	}{
+		{name: "same chains", onChain: []string{"0001", "0021"}, recommended: []string{"0001", "0021"}, status: TxStatusNoChange},
+		{name: "other order", onChain: []string{"0021", "0001"}, recommended: []string{"0001", "0021"}, status: TxStatusNoChange},
+		{name: "new chain", onChain: []string{"0001"}, recommended: []string{"0001", "0021"}, status: TxStatusSubmitted},
+		{name: "removed chain", onChain: []string{"0001", "0021"}, recommended: []string{"0021"}, status: TxStatusSubmitted},
+		{name: "other repeated chain", onChain: []string{"0001", "0001", "0021"}, recommended: []string{"0001", "0021", "0021"}, status: TxStatusSubmitted},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			signer := newTestSigner(t, "0")
+			rpc := newFakeRpc()
+			rpc.addNode(signer, c.onChain)
+			app := newTestApp(t, &Config{NetworkID: "testnet", TxFee: "10000", MaxWorkers: 1}, rpc, newTestSigners(signer))
+
+			results := app.submitStakes([]generated.GetWhatToStakeGetWhatToStakeWtsOptimizationResponseServicersWtsStakeNode{
+				{Address: signer.GetAddress(), Services: c.recommended},
+			})
+			if len(results) != 1 || results[0].Status != c.status {
+				t.Fatalf("got %+v, expected %s", results, c.status)
+			}
+
+			sent := 0
+			if c.status == TxStatusSubmitted {
+				sent = 1
+			}
+			if rpc.sentTxs() != sent {
+				t.Fatalf("sent %d txs, expected %d", rpc.sentTxs(), sent)
+			}
+
+			summary := NewRunSummary(&RunRecord{Outcome: RunOutcomeCompleted, Results: results})
+			if summary.Changes != sent || summary.ExitCode != ExitCodeOK {
+				t.Fatalf("got %d changes and exit code %d, expected %d and %d", summary.Changes, summary.ExitCode, sent, ExitCodeOK)
+			}
+		})
+	}
+}
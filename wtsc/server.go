@@ -0,0 +1,62 @@
+package wtsc
+
+import (
+	"context"
+	"errors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"net/http"
+	"time"
+)
+
+// NewHttpServer starts the optional http listener that exposes the metrics, health and status. Empty address disables it.
+func NewHttpServer(address string) {
+	if IsEmptyString(address) {
+		Logger.Info().Msg("http server is disabled")
+		return
+	}
+
+	Logger.Info().Str("address", address).Msg("preparing http server")
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(MetricsRegistry, promhttp.HandlerOpts{}))
+	mux.HandleFunc("/healthz", HealthzHandler)
+	mux.HandleFunc("/readyz", ReadyzHandler)
+	mux.HandleFunc("/status", StatusHandler)
+
+	HttpServer = startServer(address, mux)
+}
+
+// StopHttpServer gracefully stops the http listener if it is running
+func StopHttpServer() {
+	stopServer(HttpServer)
+	HttpServer = nil
+}
+
+func startServer(address string, handler http.Handler) *http.Server {
+	server := &http.Server{
+		Addr:              address,
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			Logger.Error().Err(err).Str("address", address).Msg("http server stopped")
+		}
+	}()
+
+	return server
+}
+
+func stopServer(server *http.Server) {
+	if server == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := server.Shutdown(ctx); err != nil {
+		Logger.Error().Err(err).Str("address", server.Addr).Msg("failed to stop http server")
+	}
+}
@@ -0,0 +1,222 @@
+package wtsc
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/hashicorp/go-cleanhttp"
+	pocketGoUtils "github.com/pokt-foundation/pocket-go/utils"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	RemoteSignerKeysPath = "/v1/keys"
+	RemoteSignerSignPath = "/v1/sign"
+)
+
+var ErrInvalidRemoteSignature = errors.New("remote signer returned an invalid signature")
+
+// Signer is anything able to sign the stake txs of a servicer. Private keys could live in the process
+// (pocket-go signer) or in a remote signing service (RemoteSigner).
+type Signer interface {
+	GetAddress() string
+	GetPublicKey() string
+	SignBytes(payload []byte) ([]byte, error)
+}
+
+type RemoteSignerKey struct {
+	Address   string `json:"address"`
+	PublicKey string `json:"public_key"`
+}
+
+type RemoteSignRequest struct {
+	Address string `json:"address"`
+	// Payload is the hex encoded bytes to sign
+	Payload string `json:"payload"`
+}
+
+type RemoteSignResponse struct {
+	// Signature is the hex encoded ed25519 signature
+	Signature string `json:"signature"`
+}
+
+// RemoteSigner signs the payloads calling a remote signing service, so the private key never lives in wtsc.
+type RemoteSigner struct {
+	client    *http.Client
+	url       string
+	token     string
+	address   string
+	publicKey string
+}
+
+func (rs *RemoteSigner) GetAddress() string {
+	return rs.address
+}
+
+func (rs *RemoteSigner) GetPublicKey() string {
+	return rs.publicKey
+}
+
+// SignBytes asks the remote signer for the signature and verifies it with the known public key
+func (rs *RemoteSigner) SignBytes(payload []byte) ([]byte, error) {
+	resp := RemoteSignResponse{}
+	err := remoteSignerCall(rs.client, http.MethodPost, rs.url+RemoteSignerSignPath, rs.token, &RemoteSignRequest{
+		Address: rs.address,
+		Payload: hex.EncodeToString(payload),
+	}, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	signature, err := hex.DecodeString(resp.Signature)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidRemoteSignature, err.Error())
+	}
+
+	publicKey, err := hex.DecodeString(rs.publicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	// never trust a remote signature, a wrong one will waste the tx fee
+	if !ed25519.Verify(publicKey, payload, signature) {
+		return nil, ErrInvalidRemoteSignature
+	}
+
+	return signature, nil
+}
+
+// LoadRemoteSigners returns a signer for each key served by the remote signing service
+func LoadRemoteSigners(url, token string, timeout uint) ([]Signer, error) {
+	client := cleanhttp.DefaultPooledClient()
+	client.Timeout = time.Duration(timeout) * time.Millisecond
+	url = strings.TrimRight(url, "/")
+
+	keys := make([]RemoteSignerKey, 0)
+	if err := remoteSignerCall(client, http.MethodGet, url+RemoteSignerKeysPath, token, nil, &keys); err != nil {
+		return nil, fmt.Errorf("remote_signer_url: %w", err)
+	}
+
+	signers := make([]Signer, 0, len(keys))
+	for _, key := range keys {
+		if !pocketGoUtils.ValidatePublicKey(key.PublicKey) {
+			return nil, fmt.Errorf("remote_signer_url: invalid public key for %s", key.Address)
+		}
+		// the address is derived from the public key, so both must match
+		address, err := pocketGoUtils.GetAddressFromPublickey(key.PublicKey)
+		if err != nil || address != key.Address {
+			return nil, fmt.Errorf("remote_signer_url: public key does not match address %s", key.Address)
+		}
+
+		signers = append(signers, &RemoteSigner{
+			client:    client,
+			url:       url,
+			token:     token,
+			address:   key.Address,
+			publicKey: key.PublicKey,
+		})
+	}
+
+	return signers, nil
+}
+
+func remoteSignerCall(client *http.Client, method, url, token string, in, out any) error {
+	var body bytes.Buffer
+	if in != nil {
+		if err := json.NewEncoder(&body).Encode(in); err != nil {
+			return err
+		}
+	}
+
+	req, err := http.NewRequest(method, url, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("remote signer responded with %s", resp.Status)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// NewRemoteSignerHandler is the http handler of a remote signing service serving the given signers.
+// It is used by the reference signer (cmd/signer), meant for testing.
+func NewRemoteSignerHandler(signers []Signer, token string) http.Handler {
+	byAddress := make(map[string]Signer, len(signers))
+	keys := make([]RemoteSignerKey, 0, len(signers))
+	for _, signer := range signers {
+		byAddress[signer.GetAddress()] = signer
+		keys = append(keys, RemoteSignerKey{Address: signer.GetAddress(), PublicKey: signer.GetPublicKey()})
+	}
+
+	mux := http.NewServeMux()
+
+	mux.HandleFunc(RemoteSignerKeysPath, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeJson(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+			return
+		}
+		writeJson(w, http.StatusOK, keys)
+	})
+
+	mux.HandleFunc(RemoteSignerSignPath, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeJson(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+			return
+		}
+
+		req := RemoteSignRequest{}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJson(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+
+		signer, ok := byAddress[req.Address]
+		if !ok {
+			writeJson(w, http.StatusNotFound, map[string]string{"error": "unknown address"})
+			return
+		}
+
+		payload, err := hex.DecodeString(req.Payload)
+		if err != nil {
+			writeJson(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+
+		signature, err := signer.SignBytes(payload)
+		if err != nil {
+			Logger.Error().Err(err).Str("address", req.Address).Msg("failed to sign payload")
+			writeJson(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			return
+		}
+
+		Logger.Info().Str("address", req.Address).Str("remote", r.RemoteAddr).Msg("payload signed")
+		writeJson(w, http.StatusOK, RemoteSignResponse{Signature: hex.EncodeToString(signature)})
+	})
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// a bare token, or any other scheme, is not accepted
+		reqToken, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(reqToken), []byte(token)) != 1 {
+			Logger.Warn().Str("remote", r.RemoteAddr).Str("path", r.URL.Path).Msg("unauthorized signer request")
+			writeJson(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
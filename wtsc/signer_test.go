@@ -0,0 +1,136 @@
+package wtsc
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// impostorSigner serves the address and public key of one signer but signs with another one
+type impostorSigner struct {
+	Signer
+	as Signer
+}
+
+func (s impostorSigner) GetAddress() string {
+	return s.as.GetAddress()
+}
+
+func (s impostorSigner) GetPublicKey() string {
+	return s.as.GetPublicKey()
+}
+
+func newTestRemoteSigner(t *testing.T, signers ...Signer) string {
+	t.Helper()
+	server := httptest.NewServer(NewRemoteSignerHandler(signers, "token"))
+	t.Cleanup(server.Close)
+	return server.URL
+}
+
+func TestRemoteSigner(t *testing.T) {
+	local := []Signer{newTestSigner(t, "0"), newTestSigner(t, "1")}
+	url := newTestRemoteSigner(t, local...)
+
+	// a trailing slash on the config must work too
+	signers, err := LoadRemoteSigners(url+"/", "token", 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(signers) != len(local) {
+		t.Fatalf("got %d signers, expected %d", len(signers), len(local))
+	}
+
+	payload := []byte("stake tx sign bytes")
+	for i, signer := range signers {
+		if signer.GetAddress() != local[i].GetAddress() || signer.GetPublicKey() != local[i].GetPublicKey() {
+			t.Fatalf("got signer %s, expected %s", signer.GetAddress(), local[i].GetAddress())
+		}
+		signature, e := signer.SignBytes(payload)
+		if e != nil {
+			t.Fatal(e)
+		}
+		// ed25519 signatures are deterministic
+		expected, _ := local[i].SignBytes(payload)
+		if !bytes.Equal(signature, expected) {
+			t.Fatalf("signature of %s differs from the local one", signer.GetAddress())
+		}
+	}
+}
+
+func TestRemoteSignerWrongToken(t *testing.T) {
+	url := newTestRemoteSigner(t, newTestSigner(t, "0"))
+	if _, err := LoadRemoteSigners(url, "wrong", 1000); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Fatalf("got %v, expected unauthorized", err)
+	}
+}
+
+func TestRemoteSignerHandlerAuth(t *testing.T) {
+	handler := NewRemoteSignerHandler([]Signer{newTestSigner(t, "0")}, "token")
+	cases := map[string]int{
+		"Bearer token": http.StatusOK,
+		"token":        http.StatusUnauthorized,
+		"Basic token":  http.StatusUnauthorized,
+		"Bearer wrong": http.StatusUnauthorized,
+		"":             http.StatusUnauthorized,
+	}
+	for authorization, expected := range cases {
+		req := httptest.NewRequest(http.MethodGet, RemoteSignerKeysPath, nil)
+		req.Header.Set("Authorization", authorization)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != expected {
+			t.Errorf("got %d with %q, expected %d", rec.Code, authorization, expected)
+		}
+	}
+}
+
+func TestRemoteSignerWrongPublicKey(t *testing.T) {
+	signer := newTestSigner(t, "0")
+	other := newTestSigner(t, "1")
+	cases := []struct {
+		name string
+		key  RemoteSignerKey
+	}{
+		{name: "other public key", key: RemoteSignerKey{Address: signer.GetAddress(), PublicKey: other.GetPublicKey()}},
+		{name: "invalid public key", key: RemoteSignerKey{Address: signer.GetAddress(), PublicKey: "not a key"}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				writeJson(w, http.StatusOK, []RemoteSignerKey{c.key})
+			}))
+			t.Cleanup(server.Close)
+
+			if _, err := LoadRemoteSigners(server.URL, "token", 1000); err == nil || !strings.Contains(err.Error(), signer.GetAddress()) {
+				t.Fatalf("got %v, expected the key of %s to be rejected", err, signer.GetAddress())
+			}
+		})
+	}
+}
+
+func TestRemoteSignerWrongSignature(t *testing.T) {
+	signer := newTestSigner(t, "0")
+	// the remote signer serves the key of signer, but signs with another one
+	url := newTestRemoteSigner(t, impostorSigner{Signer: newTestSigner(t, "1"), as: signer})
+
+	signers, err := LoadRemoteSigners(url, "token", 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = signers[0].SignBytes([]byte("payload")); !errors.Is(err, ErrInvalidRemoteSignature) {
+		t.Fatalf("got %v, expected %s", err, ErrInvalidRemoteSignature)
+	}
+}
+
+func TestRemoteSignerUnknownAddress(t *testing.T) {
+	url := newTestRemoteSigner(t, newTestSigner(t, "0"))
+	signer := newTestSigner(t, "1")
+	remote := &RemoteSigner{client: http.DefaultClient, url: url, token: "token", address: signer.GetAddress(), publicKey: signer.GetPublicKey()}
+	if _, err := remote.SignBytes([]byte("payload")); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Fatalf("got %v, expected not found", err)
+	}
+}
@@ -0,0 +1,96 @@
+package wtsc
+
+// Exit codes of a single-shot run (`wtsc once`), so the scheduler (kubernetes CronJob, systemd timer) could tell
+// the failures apart. 1 is any other fatal error and 2 a wrong usage of the command.
+const (
+	// ExitCodeOK nothing to do or the stakes were applied
+	ExitCodeOK = 0
+	// ExitCodeConfigError the config file could not be read or is invalid
+	ExitCodeConfigError = 3
+	// ExitCodeWtsFailed the call to what-to-stake service failed
+	ExitCodeWtsFailed = 4
+	// ExitCodeTxFailed at least one stake tx failed, was dropped or could not be paid
+	ExitCodeTxFailed = 5
+	// ExitCodePlanFailed the stake plan needs approval but could not be saved
+	ExitCodePlanFailed = 6
+)
+
+// RunOutcomeConfigError is only reported by the summary of a single-shot run, the run never starts, so it is not
+// on the history.
+const RunOutcomeConfigError RunOutcome = "config_error"
+
+// RunSummary is the machine-readable result of a single-shot run
+type RunSummary struct {
+	RunID             string           `json:"run_id,omitempty"`
+	Outcome           RunOutcome       `json:"outcome"`
+	ExitCode          int              `json:"exit_code"`
+	DryMode           bool             `json:"dry_mode"`
+	DoUpdate          bool             `json:"do_update"`
+	GainChangePercent float64          `json:"gain_change_percent"`
+	PlanID            string           `json:"plan_id,omitempty"`
+	DurationMs        int64            `json:"duration_ms"`
+	ConfigHash        string           `json:"config_hash,omitempty"`
+	Counts            map[TxStatus]int `json:"counts"`
+	// Changes are the nodes that need a stake tx, on dry mode and watch-only runs they are never sent
+	Changes int               `json:"changes"`
+	Results []*ServicerResult `json:"results,omitempty"`
+	Error   string            `json:"error,omitempty"`
+}
+
+// NewRunSummary summarizes a finished run and resolves its exit code
+func NewRunSummary(run *RunRecord) *RunSummary {
+	summary := &RunSummary{
+		RunID:      run.ID,
+		Outcome:    run.Outcome,
+		DryMode:    run.DryMode,
+		PlanID:     run.PlanID,
+		DurationMs: run.DurationMs,
+		ConfigHash: run.ConfigHash,
+		Counts:     make(map[TxStatus]int),
+		Results:    run.Results,
+		Error:      run.Error,
+	}
+
+	if run.Response != nil {
+		summary.DoUpdate = run.Response.GetWhatToStake.Do_update
+		summary.GainChangePercent = run.Response.GetWhatToStake.Gain_change_percent
+	}
+
+	if run.Plan != nil {
+		for _, node := range run.Plan.OwnNodes() {
+			if node.Change {
+				summary.Changes++
+			}
+		}
+	}
+
+	for _, result := range run.Results {
+		summary.Counts[result.Status]++
+		if !result.IsSkipped() {
+			summary.Changes++
+		}
+	}
+
+	switch {
+	case run.Outcome == RunOutcomeWtsFailed:
+		summary.ExitCode = ExitCodeWtsFailed
+	case run.Outcome == RunOutcomePlanFailed:
+		summary.ExitCode = ExitCodePlanFailed
+	case summary.Counts[TxStatusFailed] > 0 || summary.Counts[TxStatusDropped] > 0 || summary.Counts[TxStatusInsufficientFunds] > 0:
+		summary.ExitCode = ExitCodeTxFailed
+	default:
+		summary.ExitCode = ExitCodeOK
+	}
+
+	return summary
+}
+
+// NewConfigErrorSummary is the summary of a single-shot run that could not start due to the config
+func NewConfigErrorSummary(err error) *RunSummary {
+	return &RunSummary{
+		Outcome:  RunOutcomeConfigError,
+		ExitCode: ExitCodeConfigError,
+		Counts:   make(map[TxStatus]int),
+		Error:    err.Error(),
+	}
+}
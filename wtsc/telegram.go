@@ -0,0 +1,58 @@
+package wtsc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+const (
+	// telegramMaxText is the max length of a telegram message
+	telegramMaxText = 4096
+)
+
+// TelegramApiURL is the Telegram Bot API base url
+var TelegramApiURL = "https://api.telegram.org"
+
+type telegramMessage struct {
+	ChatID                string `json:"chat_id"`
+	Text                  string `json:"text"`
+	DisableWebPagePreview bool   `json:"disable_web_page_preview"`
+}
+
+func (tc *TelegramConfig) Name() string {
+	return "telegram"
+}
+
+// Send posts the notification text with the sendMessage method of the Bot API
+func (tc *TelegramConfig) Send(ctx context.Context, n *Notification) error {
+	text := n.Text()
+	if len(text) > telegramMaxText {
+		text = text[:telegramMaxText]
+	}
+
+	body, err := json.Marshal(&telegramMessage{
+		ChatID:                tc.ChatID,
+		Text:                  text,
+		DisableWebPagePreview: true,
+	})
+	if err != nil {
+		return err
+	}
+
+	return postJson(ctx, fmt.Sprintf("%s/bot%s/sendMessage", TelegramApiURL, tc.BotToken), body)
+}
+
+func CheckTelegram(telegram []TelegramConfig) (errs []*ConfigError) {
+	for i, tc := range telegram {
+		key := fmt.Sprintf("telegram[%d]", i)
+		if IsEmptyString(tc.BotToken) {
+			errs = append(errs, newConfigError(key+".bot_token", nil, "is required"))
+		}
+		if IsEmptyString(tc.ChatID) {
+			errs = append(errs, newConfigError(key+".chat_id", nil, "is required"))
+		}
+		errs = append(errs, CheckNotificationFilter(key, tc.NotificationFilter)...)
+	}
+	return errs
+}
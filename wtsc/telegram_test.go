@@ -0,0 +1,23 @@
+package wtsc
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestTelegramSendDoesNotLeakBotToken(t *testing.T) {
+	const token = "123456:ABC-DEF1234ghIkl-zyx57W2v1u123ew11"
+	apiURL := TelegramApiURL
+	TelegramApiURL = closedServerURL(t)
+	defer func() { TelegramApiURL = apiURL }()
+
+	telegram := &TelegramConfig{BotToken: token, ChatID: "1"}
+	err := telegram.Send(context.Background(), &Notification{Event: EventTxFailed, Message: "test", Fields: map[string]any{}})
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if strings.Contains(err.Error(), token) {
+		t.Fatalf("error leaks the bot token: %s", err)
+	}
+}
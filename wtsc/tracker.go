@@ -0,0 +1,220 @@
+package wtsc
+
+import (
+	"context"
+	"fmt"
+	pocketGoProvider "github.com/pokt-foundation/pocket-go/provider"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultConfirmationPollInterval is used when confirmation_poll_interval is not set (milliseconds)
+	DefaultConfirmationPollInterval = 30000
+	// BlockTime is the expected time between pocket blocks, used to bound the confirmation when
+	// confirmation_timeout is not set
+	BlockTime = 15 * time.Minute
+	// MaxTrackedTxs is the max amount of txs of a run tracked at the same time
+	MaxTrackedTxs = 64
+)
+
+type TxStatus string
+
+const (
+	// TxStatusSubmitted the tx was accepted by the mempool but confirmation tracking is disabled
+	TxStatusSubmitted TxStatus = "submitted"
+	// TxStatusConfirmed the tx was included with code 0 and the node chains match the requested ones
+	TxStatusConfirmed TxStatus = "confirmed"
+	// TxStatusFailed the tx was rejected, included with an error code or the chains does not match after inclusion
+	TxStatusFailed TxStatus = "failed"
+	// TxStatusDropped the tx was not found on chain before the block/time budget expires
+	TxStatusDropped TxStatus = "dropped"
+	// TxStatusNoChange the node already has the recommended chains, so no tx was sent
+	TxStatusNoChange TxStatus = "no_change"
+	// TxStatusInsufficientFunds the signer account can not pay the tx_fee, so no tx was sent
+	TxStatusInsufficientFunds TxStatus = "insufficient_funds"
+	// TxStatusNoSigner the servicer was recommended but there is no signer for it (i.e. a watch-only address), so no
+	// tx was sent
+	TxStatusNoSigner TxStatus = "no_signer"
+)
+
+// ServicerResult holds what happened to a single servicer during an evaluation run.
+type ServicerResult struct {
+	Address string   `json:"address"`
+	Chains  []string `json:"chains"`
+	Hash    string   `json:"hash,omitempty"`
+	Height  int      `json:"height,omitempty"`
+	Status  TxStatus `json:"status"`
+	Error   string   `json:"error,omitempty"`
+	// DurationMs is the time spent processing the servicer, including the confirmation tracking
+	DurationMs int64 `json:"duration_ms"`
+}
+
+// IsFailure reports if the stake of the servicer did not go through
+func (r *ServicerResult) IsFailure() bool {
+	return r.Status == TxStatusFailed || r.Status == TxStatusDropped || r.Status == TxStatusInsufficientFunds
+}
+
+// IsSkipped reports if no stake tx was needed or possible for the servicer
+func (r *ServicerResult) IsSkipped() bool {
+	return r.Status == TxStatusNoChange || r.Status == TxStatusNoSigner
+}
+
+// StakeReport collects the servicer results of a run. It is safe to use from the worker pool.
+type StakeReport struct {
+	mu      sync.Mutex
+	results []*ServicerResult
+	// tracking are the confirmations running out of the worker pool, bounded by trackingSlots
+	tracking      sync.WaitGroup
+	trackingSlots chan struct{}
+}
+
+// Track runs fn (a confirmation tracking) in its own goroutine, so it does not hold a worker while it waits for
+// blocks. At most MaxTrackedTxs run at the same time, use Wait to wait for them.
+func (sr *StakeReport) Track(fn func()) {
+	sr.mu.Lock()
+	if sr.trackingSlots == nil {
+		sr.trackingSlots = make(chan struct{}, MaxTrackedTxs)
+	}
+	slots := sr.trackingSlots
+	sr.mu.Unlock()
+
+	sr.tracking.Add(1)
+	go func() {
+		defer sr.tracking.Done()
+		slots <- struct{}{}
+		defer func() { <-slots }()
+		fn()
+	}()
+}
+
+// Wait blocks until the tracked confirmations are done
+func (sr *StakeReport) Wait() {
+	sr.tracking.Wait()
+}
+
+func (sr *StakeReport) Add(result *ServicerResult) {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+	sr.results = append(sr.results, result)
+}
+
+func (sr *StakeReport) Results() []*ServicerResult {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+	results := make([]*ServicerResult, len(sr.results))
+	copy(results, sr.results)
+	return results
+}
+
+// CountByStatus returns the amount of results for each status
+func (sr *StakeReport) CountByStatus() map[TxStatus]int {
+	counts := make(map[TxStatus]int)
+	for _, result := range sr.Results() {
+		counts[result.Status]++
+	}
+	return counts
+}
+
+// ConfirmationTimeout returns the confirmation_timeout or, when it is not set, the expected time of the blocks to
+// wait plus one, so the tracking always ends even if the rpc never answers
+func ConfirmationTimeout(maxBlocks, timeout uint) time.Duration {
+	if timeout > 0 {
+		return time.Duration(timeout) * time.Millisecond
+	}
+	return time.Duration(maxBlocks+1) * BlockTime
+}
+
+// TrackTransaction polls the pocket rpc until the tx is included on a block or the configured budget expires.
+// Once included, it checks the result code and re-reads the node to verify that the chains are the expected ones.
+func (app *App) TrackTransaction(result *ServicerResult, maxBlocks, timeout, pollInterval uint) {
+	if pollInterval == 0 {
+		pollInterval = DefaultConfirmationPollInterval
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), ConfirmationTimeout(maxBlocks, timeout))
+	defer cancel()
+
+	logger := app.Logger.With().Str("address", result.Address).Str("hash", result.Hash).Logger()
+
+	startHeight, err := app.Rpc.GetBlockHeightWithCtx(ctx)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to get current height, unable to track transaction")
+		result.Status = TxStatusDropped
+		result.Error = err.Error()
+		return
+	}
+	lastHeight := startHeight + int(maxBlocks)
+
+	logger.Info().Int("start_height", startHeight).Int("last_height", lastHeight).Msg("tracking stake node transaction")
+
+	ticker := time.NewTicker(time.Duration(pollInterval) * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Warn().Msg("transaction was not confirmed before the timeout")
+			result.Status = TxStatusDropped
+			result.Error = "confirmation timeout reached"
+			return
+		case <-ticker.C:
+		}
+
+		tx, txErr := app.Rpc.GetTransactionWithCtx(ctx, result.Hash, &pocketGoProvider.GetTransactionOptions{Prove: false})
+		if txErr == nil && tx.Transaction != nil && tx.Height > 0 {
+			result.Height = tx.Height
+			app.verifyTransaction(ctx, result, tx)
+			return
+		}
+
+		// not found yet (or rpc error), check if we still have blocks to wait
+		height, err := app.Rpc.GetBlockHeightWithCtx(ctx)
+		if err != nil {
+			logger.Debug().Err(err).Msg("failed to get current height")
+			continue
+		}
+
+		if height > lastHeight {
+			logger.Warn().Int("height", height).Msg("transaction was not included within the configured blocks")
+			result.Status = TxStatusDropped
+			result.Error = fmt.Sprintf("not included after %d blocks", maxBlocks)
+			return
+		}
+
+		logger.Debug().Int("height", height).Msg("transaction not included yet")
+	}
+}
+
+func (app *App) verifyTransaction(ctx context.Context, result *ServicerResult, tx *pocketGoProvider.GetTransactionOutput) {
+	logger := app.Logger.With().Str("address", result.Address).Str("hash", result.Hash).Int("height", tx.Height).Logger()
+
+	if tx.TxResult != nil && tx.TxResult.Code != 0 {
+		logger.Error().
+			Int("code", tx.TxResult.Code).
+			Str("codespace", tx.TxResult.Codespace).
+			Str("log", tx.TxResult.Log).
+			Msg("stake node transaction failed")
+		result.Status = TxStatusFailed
+		result.Error = fmt.Sprintf("tx failed with code %d: %s", tx.TxResult.Code, tx.TxResult.Log)
+		return
+	}
+
+	node, err := app.Rpc.GetNodeWithCtx(ctx, result.Address, &pocketGoProvider.GetNodeOptions{Height: 0})
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to get pocket node after confirmation")
+		result.Status = TxStatusFailed
+		result.Error = err.Error()
+		return
+	}
+
+	if !IsSameStrSet(node.Chains, result.Chains) {
+		logger.Error().Strs("chains", node.Chains).Strs("expected", result.Chains).Msg("on-chain chains does not match after confirmation")
+		result.Status = TxStatusFailed
+		result.Error = "on-chain chains does not match"
+		return
+	}
+
+	logger.Info().Strs("chains", node.Chains).Msg("stake node transaction confirmed")
+	result.Status = TxStatusConfirmed
+}
@@ -0,0 +1,86 @@
+package wtsc
+
+import (
+	"errors"
+	"github.com/pokt-scan/wtsc/wtsc/generated"
+	"testing"
+	"time"
+)
+
+func TestConfirmationTimeout(t *testing.T) {
+	if got := ConfirmationTimeout(2, 5000); got != 5*time.Second {
+		t.Fatalf("got %s, expected the configured timeout", got)
+	}
+	if got := ConfirmationTimeout(2, 0); got != 3*BlockTime {
+		t.Fatalf("got %s, expected 3 blocks", got)
+	}
+	if got := ConfirmationTimeout(0, 0); got <= 0 {
+		t.Fatalf("got %s, expected a timeout", got)
+	}
+}
+
+// with a single worker, the second stake must be sent while the first one is tracked
+func TestTrackingDoesNotHoldWorkers(t *testing.T) {
+	signers := []Signer{newTestSigner(t, "0"), newTestSigner(t, "1")}
+	rpc := newFakeRpc()
+	// nothing is included until both txs are sent
+	rpc.include = func(sent int) bool { return sent >= len(signers) }
+	servicers := make([]generated.GetWhatToStakeGetWhatToStakeWtsOptimizationResponseServicersWtsStakeNode, len(signers))
+	for i, signer := range signers {
+		rpc.addNode(signer, []string{"0001"})
+		servicers[i].Address = signer.GetAddress()
+		servicers[i].Services = []string{"0021"}
+	}
+
+	cfg := &Config{
+		NetworkID:                "testnet",
+		TxFee:                    "10000",
+		MaxWorkers:               1,
+		ConfirmationBlocks:       2,
+		ConfirmationTimeout:      2000,
+		ConfirmationPollInterval: 10,
+	}
+	app := newTestApp(t, cfg, rpc, newTestSigners(signers...))
+
+	results := app.submitStakes(servicers)
+	if len(results) != len(signers) {
+		t.Fatalf("got %d results, expected %d", len(results), len(signers))
+	}
+	for _, result := range results {
+		if result.Status != TxStatusConfirmed {
+			t.Fatalf("%s is %s (%s), expected %s", result.Address, result.Status, result.Error, TxStatusConfirmed)
+		}
+	}
+}
+
+func TestTrackTransactionEndsWhenRpcFails(t *testing.T) {
+	signer := newTestSigner(t, "0")
+	rpc := newFakeRpc()
+	rpc.addNode(signer, []string{"0001"})
+	rpc.include = func(int) bool { return false }
+	app := newTestApp(t, &Config{MaxWorkers: 1}, rpc, newTestSigners(signer))
+
+	result := &ServicerResult{Address: signer.GetAddress(), Hash: "AA", Status: TxStatusSubmitted}
+	// the rpc answers the start height, then it is down
+	go func() {
+		time.Sleep(30 * time.Millisecond)
+		rpc.mu.Lock()
+		rpc.heightErr = errors.New("rpc is down")
+		rpc.mu.Unlock()
+	}()
+
+	done := make(chan struct{})
+	go func() {
+		app.TrackTransaction(result, 2, 200, 10)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("tracking did not end")
+	}
+	if result.Status != TxStatusDropped {
+		t.Fatalf("got %s, expected %s", result.Status, TxStatusDropped)
+	}
+}
@@ -0,0 +1,374 @@
+package wtsc
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	pocketGoProvider "github.com/pokt-foundation/pocket-go/provider"
+	pocketCoreCrypto "github.com/pokt-network/pocket-core/crypto"
+	pocketCoreTypes "github.com/pokt-network/pocket-core/types"
+	pocketCoreAuthTypes "github.com/pokt-network/pocket-core/x/auth/types"
+	pocketCoreNodesTypes "github.com/pokt-network/pocket-core/x/nodes/types"
+	"math"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// FeeDenom is the denomination of the tx fee
+const FeeDenom = "upokt"
+
+// EntropySource returns the entropy (nonce) of a tx, the same entropy and fields always produce the same tx
+type EntropySource func() (int64, error)
+
+// RandomEntropy is the EntropySource of the real txs
+func RandomEntropy() (int64, error) {
+	entropy, err := rand.Int(rand.Reader, big.NewInt(math.MaxInt64))
+	if err != nil {
+		return 0, err
+	}
+	return entropy.Int64(), nil
+}
+
+// FixedEntropy always returns the same entropy, to build reproducible txs
+func FixedEntropy(entropy int64) EntropySource {
+	return func() (int64, error) {
+		return entropy, nil
+	}
+}
+
+// StakeTx is a stake tx without its signature. It is built without any call, so the same inputs always build the
+// same sign bytes and, with a deterministic signer (ed25519), the same raw tx.
+type StakeTx struct {
+	NetworkID string
+	Msg       *pocketCoreNodesTypes.MsgStake
+	Fee       pocketCoreTypes.Coins
+	Memo      string
+	Entropy   int64
+}
+
+// NewStakeMsg returns the MsgStake that re-stakes the node with the given chains, keeping its tokens, service url,
+// output address and reward delegators
+func NewStakeMsg(publicKey string, node *pocketGoProvider.Node, chains []string) (*pocketCoreNodesTypes.MsgStake, error) {
+	cryptoPublicKey, err := pocketCoreCrypto.NewPublicKey(publicKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid public key: %w", err)
+	}
+
+	nodeTokens, err := strconv.ParseInt(node.Tokens, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid node tokens %q: %w", node.Tokens, err)
+	}
+
+	outputAddress, err := hex.DecodeString(node.OutputAddress)
+	if err != nil {
+		return nil, fmt.Errorf("invalid output address %q: %w", node.OutputAddress, err)
+	}
+
+	return &pocketCoreNodesTypes.MsgStake{
+		PublicKey:        cryptoPublicKey,
+		Chains:           chains, // aka chains on morse
+		Value:            pocketCoreTypes.NewInt(nodeTokens),
+		ServiceUrl:       node.ServiceURL,
+		Output:           outputAddress,
+		RewardDelegators: node.RewardDelegators,
+	}, nil
+}
+
+// NewStakeTx returns the stake tx of the msg, paying fee upokt
+func NewStakeTx(networkID string, msg *pocketCoreNodesTypes.MsgStake, fee int64, memo string, entropy int64) *StakeTx {
+	return &StakeTx{
+		NetworkID: networkID,
+		Msg:       msg,
+		Fee: pocketCoreTypes.Coins{
+			pocketCoreTypes.Coin{
+				Amount: pocketCoreTypes.NewInt(fee),
+				Denom:  FeeDenom,
+			},
+		},
+		Memo:    memo,
+		Entropy: entropy,
+	}
+}
+
+// SignBytes returns the payload that the node key must sign
+func (tx *StakeTx) SignBytes() ([]byte, error) {
+	signBytes, err := pocketCoreAuthTypes.StdSignBytes(tx.NetworkID, tx.Entropy, tx.Fee, tx.Msg, tx.Memo)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sign bytes: %w", err)
+	}
+	return signBytes, nil
+}
+
+// Encode returns the raw tx with the signature, ready to be submitted
+func (tx *StakeTx) Encode(signature []byte) ([]byte, error) {
+	signatureStruct := pocketCoreAuthTypes.StdSignature{PublicKey: tx.Msg.PublicKey, Signature: signature}
+	stdTx := pocketCoreAuthTypes.NewTx(tx.Msg, tx.Fee, signatureStruct, tx.Memo, tx.Entropy)
+
+	txBytes, err := pocketCoreAuthTypes.DefaultTxEncoder(Codec())(stdTx, -1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode transaction: %w", err)
+	}
+	return tx.sortRewardDelegators(txBytes)
+}
+
+// sortRewardDelegators rewrites the reward delegators of an encoded tx sorted by address. The proto encoder writes
+// the map in its (random) iteration order, so the same tx would get different bytes and hash on each encoding.
+// The entries are found walking the proto fields (tx.msg -> any.value -> msg.reward_delegators), they are written
+// together and keep their bytes in any order, so sorting them in place changes no length.
+func (tx *StakeTx) sortRewardDelegators(txBytes []byte) ([]byte, error) {
+	if len(tx.Msg.RewardDelegators) < 2 {
+		return txBytes, nil
+	}
+
+	size, n := binary.Uvarint(txBytes)
+	if n <= 0 || size != uint64(len(txBytes)-n) {
+		return nil, fmt.Errorf("invalid length prefix on the encoded transaction")
+	}
+
+	// offset is the position of msg inside txBytes
+	offset, msg := n, txBytes[n:]
+	for _, num := range []uint64{1, 2} {
+		field, err := findProtoField(msg, num)
+		if err != nil {
+			return nil, err
+		}
+		offset += field.value
+		msg = msg[field.value:field.end]
+	}
+
+	fields, err := readProtoFields(msg)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]protoField, 0, len(tx.Msg.RewardDelegators))
+	for _, field := range fields {
+		if field.num != 6 {
+			continue
+		}
+		if len(entries) > 0 && entries[len(entries)-1].end != field.start {
+			return nil, fmt.Errorf("reward delegators are not contiguous on the encoded transaction")
+		}
+		entries = append(entries, field)
+	}
+	if len(entries) != len(tx.Msg.RewardDelegators) {
+		return nil, fmt.Errorf("found %d reward delegators on the encoded transaction, expected %d", len(entries), len(tx.Msg.RewardDelegators))
+	}
+
+	addresses := make(map[int]string, len(entries))
+	for _, entry := range entries {
+		key, e := findProtoField(msg[entry.value:entry.end], 1)
+		if e != nil {
+			return nil, fmt.Errorf("invalid reward delegator entry: %w", e)
+		}
+		addresses[entry.start] = string(msg[entry.value+key.value : entry.value+key.end])
+	}
+	sorted := append([]protoField{}, entries...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return addresses[sorted[i].start] < addresses[sorted[j].start]
+	})
+
+	result := append([]byte{}, txBytes...)
+	position := offset + entries[0].start
+	for _, entry := range sorted {
+		position += copy(result[position:], msg[entry.start:entry.end])
+	}
+
+	// the result must be the same tx, only with another order of the map
+	decoded, err := StakeTxFromRaw(result, tx.NetworkID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode the sorted transaction: %w", err)
+	}
+	signBytes, err := tx.SignBytes()
+	if err != nil {
+		return nil, err
+	}
+	decodedSignBytes, err := decoded.SignBytes()
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(signBytes, decodedSignBytes) {
+		return nil, fmt.Errorf("sorting the reward delegators changed the transaction")
+	}
+
+	return result, nil
+}
+
+// protoField is a field of an encoded proto message, the offsets are relative to the message
+type protoField struct {
+	num      uint64
+	wireType uint64
+	// start is the offset of the tag, value the one of the value (after the length, if any) and end the next field
+	start, value, end int
+}
+
+// readProtoFields splits an encoded proto message in its fields
+func readProtoFields(bz []byte) ([]protoField, error) {
+	fields := make([]protoField, 0)
+	for offset := 0; offset < len(bz); {
+		tag, n := binary.Uvarint(bz[offset:])
+		if n <= 0 {
+			return nil, fmt.Errorf("invalid proto tag at %d", offset)
+		}
+		field := protoField{num: tag >> 3, wireType: tag & 7, start: offset, value: offset + n}
+
+		switch field.wireType {
+		case 0:
+			_, m := binary.Uvarint(bz[field.value:])
+			if m <= 0 {
+				return nil, fmt.Errorf("invalid proto varint at %d", field.value)
+			}
+			field.end = field.value + m
+		case 1:
+			field.end = field.value + 8
+		case 2:
+			length, m := binary.Uvarint(bz[field.value:])
+			if m <= 0 || length > uint64(len(bz)-field.value-m) {
+				return nil, fmt.Errorf("invalid proto length at %d", field.value)
+			}
+			field.value += m
+			field.end = field.value + int(length)
+		case 5:
+			field.end = field.value + 4
+		default:
+			return nil, fmt.Errorf("unsupported proto wire type %d at %d", field.wireType, offset)
+		}
+		if field.end > len(bz) {
+			return nil, fmt.Errorf("truncated proto field at %d", offset)
+		}
+
+		fields = append(fields, field)
+		offset = field.end
+	}
+	return fields, nil
+}
+
+// findProtoField returns the first length-delimited field num of an encoded proto message
+func findProtoField(bz []byte, num uint64) (protoField, error) {
+	fields, err := readProtoFields(bz)
+	if err != nil {
+		return protoField{}, err
+	}
+	for _, field := range fields {
+		if field.num == num && field.wireType == 2 {
+			return field, nil
+		}
+	}
+	return protoField{}, fmt.Errorf("proto field %d not found", num)
+}
+
+// VerifySignature reports if the signature is the one of the sign bytes by the public key of the msg
+func (tx *StakeTx) VerifySignature(signature []byte) (bool, error) {
+	signBytes, err := tx.SignBytes()
+	if err != nil {
+		return false, err
+	}
+	return tx.Msg.PublicKey.VerifyBytes(signBytes, signature), nil
+}
+
+// Sign signs the tx with the signer and returns the raw tx
+func (tx *StakeTx) Sign(signer Signer) ([]byte, error) {
+	signBytes, err := tx.SignBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	signature, err := signer.SignBytes(signBytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign transaction: %w", err)
+	}
+
+	return tx.Encode(signature)
+}
+
+// DecodedStakeTx is the readable version of a raw stake tx
+type DecodedStakeTx struct {
+	Hash             string            `json:"hash"`
+	Type             string            `json:"type"`
+	Address          string            `json:"address"`
+	PublicKey        string            `json:"public_key"`
+	Chains           []string          `json:"chains"`
+	Value            string            `json:"value"`
+	ServiceURL       string            `json:"service_url"`
+	OutputAddress    string            `json:"output_address"`
+	RewardDelegators map[string]uint32 `json:"reward_delegators,omitempty"`
+	Fee              string            `json:"fee"`
+	Memo             string            `json:"memo"`
+	Entropy          int64             `json:"entropy"`
+	SignerPublicKey  string            `json:"signer_public_key"`
+	Signature        string            `json:"signature"`
+	// SignatureValid is only set when the network id is known
+	SignatureValid *bool `json:"signature_valid,omitempty"`
+}
+
+// StakeTxFromRaw rebuilds the StakeTx of a raw stake tx (signed or not) for the network, so its sign bytes could
+// be computed again
+func StakeTxFromRaw(txBytes []byte, networkID string) (*StakeTx, error) {
+	tx, err := DecodeTx(txBytes)
+	if err != nil {
+		return nil, err
+	}
+	return stakeTxFromStdTx(tx, networkID)
+}
+
+func stakeTxFromStdTx(tx *pocketCoreAuthTypes.StdTx, networkID string) (*StakeTx, error) {
+	var msg *pocketCoreNodesTypes.MsgStake
+	switch m := tx.Msg.(type) {
+	case *pocketCoreNodesTypes.MsgStake:
+		msg = m
+	case pocketCoreNodesTypes.MsgStake:
+		msg = &m
+	default:
+		return nil, fmt.Errorf("not a stake tx: %T", tx.Msg)
+	}
+	if msg.PublicKey == nil {
+		return nil, fmt.Errorf("stake tx without public key")
+	}
+
+	return &StakeTx{NetworkID: networkID, Msg: msg, Fee: tx.Fee, Memo: tx.Memo, Entropy: tx.Entropy}, nil
+}
+
+// DecodeStakeTx decodes a raw stake tx. When networkID is not empty, the signature is verified for it.
+func DecodeStakeTx(txBytes []byte, networkID string) (*DecodedStakeTx, error) {
+	tx, err := DecodeTx(txBytes)
+	if err != nil {
+		return nil, err
+	}
+	stakeTx, err := stakeTxFromStdTx(tx, networkID)
+	if err != nil {
+		return nil, err
+	}
+	msg := stakeTx.Msg
+
+	decoded := &DecodedStakeTx{
+		Hash:             TxHash(txBytes),
+		Type:             msg.Type(),
+		Address:          strings.ToLower(msg.PublicKey.Address().String()),
+		PublicKey:        msg.PublicKey.RawString(),
+		Chains:           msg.Chains,
+		Value:            msg.Value.String(),
+		ServiceURL:       msg.ServiceUrl,
+		OutputAddress:    hex.EncodeToString(msg.Output),
+		RewardDelegators: msg.RewardDelegators,
+		Fee:              tx.Fee.String(),
+		Memo:             tx.Memo,
+		Entropy:          tx.Entropy,
+		Signature:        hex.EncodeToString(tx.Signature.Signature),
+	}
+	if tx.Signature.PublicKey != nil {
+		decoded.SignerPublicKey = tx.Signature.PublicKey.RawString()
+	}
+
+	if !IsEmptyString(networkID) {
+		valid, e := stakeTx.VerifySignature(tx.Signature.Signature)
+		if e != nil {
+			return nil, e
+		}
+		decoded.SignatureValid = &valid
+	}
+
+	return decoded, nil
+}
@@ -0,0 +1,265 @@
+package wtsc
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	pocketGoProvider "github.com/pokt-foundation/pocket-go/provider"
+	pocketGoSigner "github.com/pokt-foundation/pocket-go/signer"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// updateGolden rewrites the golden files with the current encoding, only when a change on it is expected:
+//
+//	go test ./wtsc -run TestStakeTxGolden -update
+var updateGolden = flag.Bool("update", false, "rewrite the golden files of testdata")
+
+var stakeTxGoldenFile = filepath.Join("testdata", "stake_tx.golden.json")
+
+// goldenCase is a stake tx built with fixed inputs, its sign bytes and raw tx must never change, otherwise the
+// txs that wtsc broadcast changed too
+type goldenCase struct {
+	name             string
+	outputAddress    string
+	rewardDelegators map[string]uint32
+	chains           []string
+	networkID        string
+	fee              int64
+	memo             string
+	entropy          int64
+}
+
+// goldenTx is the expected encoding of a goldenCase
+type goldenTx struct {
+	Name      string `json:"name"`
+	Address   string `json:"address"`
+	SignBytes string `json:"sign_bytes"`
+	RawTx     string `json:"raw_tx"`
+	Hash      string `json:"hash"`
+}
+
+var goldenCases = []goldenCase{
+	{name: "custodial", chains: []string{"0021"}, networkID: "testnet", fee: 10000, entropy: 1},
+	{
+		name:          "non_custodial",
+		outputAddress: strings.Repeat("a", 40),
+		rewardDelegators: map[string]uint32{
+			strings.Repeat("b", 40): 10,
+			strings.Repeat("c", 40): 5,
+		},
+		chains:    []string{"0021", "0003"},
+		networkID: "testnet",
+		fee:       10000,
+		memo:      "wtsc",
+		entropy:   math.MaxInt64,
+	},
+	{name: "mainnet", chains: []string{"0001", "0021", "0003"}, networkID: "mainnet", fee: 20000, entropy: -42},
+	{
+		// hex addresses with "2" and a share of 50 hold 0x32 bytes, the tag of the reward delegators field
+		name:          "many_delegators",
+		outputAddress: strings.Repeat("2", 40),
+		rewardDelegators: map[string]uint32{
+			strings.Repeat("32", 20):       50,
+			strings.Repeat("2", 40):        20,
+			strings.Repeat("a2", 20):       15,
+			strings.Repeat("f", 39) + "2":  10,
+			strings.Repeat("0", 38) + "32": 2,
+		},
+		chains:    []string{"0021", "0032"},
+		networkID: "testnet",
+		fee:       10000,
+		memo:      "22",
+		entropy:   0x3232,
+	},
+}
+
+func TestStakeTxGolden(t *testing.T) {
+	current := make([]goldenTx, 0, len(goldenCases))
+	for _, c := range goldenCases {
+		current = append(current, *buildGoldenTx(t, c))
+	}
+
+	if *updateGolden {
+		bz, err := json.MarshalIndent(current, "", "  ")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = os.WriteFile(stakeTxGoldenFile, append(bz, '\n'), 0644); err != nil {
+			t.Fatal(err)
+		}
+		t.Logf("%s updated with %d txs", stakeTxGoldenFile, len(current))
+		return
+	}
+
+	bz, err := os.ReadFile(stakeTxGoldenFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := make(map[string]goldenTx)
+	golden := make([]goldenTx, 0)
+	if err = json.Unmarshal(bz, &golden); err != nil {
+		t.Fatal(err)
+	}
+	for _, tx := range golden {
+		expected[tx.Name] = tx
+	}
+
+	for _, tx := range current {
+		t.Run(tx.Name, func(t *testing.T) {
+			golden, ok := expected[tx.Name]
+			if !ok {
+				t.Fatalf("missing on %s, run with -update", stakeTxGoldenFile)
+			}
+			if golden.SignBytes != tx.SignBytes {
+				t.Errorf("sign bytes changed\n  expected %s\n  got      %s", golden.SignBytes, tx.SignBytes)
+			}
+			if golden != tx {
+				t.Errorf("encoding changed\n  expected %s\n  got      %s", golden.RawTx, tx.RawTx)
+			}
+		})
+	}
+}
+
+// the proto encoder writes the reward delegators map in random order, the bytes must be the same anyway
+func TestStakeTxEncodingIsStable(t *testing.T) {
+	for _, c := range goldenCases {
+		if len(c.rewardDelegators) < 2 {
+			continue
+		}
+		t.Run(c.name, func(t *testing.T) {
+			first := buildGoldenTx(t, c)
+			for i := 0; i < 50; i++ {
+				if tx := buildGoldenTx(t, c); tx.RawTx != first.RawTx {
+					t.Fatalf("encoding %d differs\n  first %s\n  got   %s", i, first.RawTx, tx.RawTx)
+				}
+			}
+		})
+	}
+}
+
+func TestStakeTxSortsRewardDelegators(t *testing.T) {
+	c := goldenCases[3]
+	txBytes, err := hex.DecodeString(buildGoldenTx(t, c).RawTx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// length prefix -> tx.msg -> any.value -> msg.reward_delegators
+	_, n := binary.Uvarint(txBytes)
+	msg := txBytes[n:]
+	for _, num := range []uint64{1, 2} {
+		field, e := findProtoField(msg, num)
+		if e != nil {
+			t.Fatal(e)
+		}
+		msg = msg[field.value:field.end]
+	}
+	fields, err := readProtoFields(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	addresses := make([]string, 0)
+	for _, field := range fields {
+		if field.num != 6 {
+			continue
+		}
+		key, e := findProtoField(msg[field.value:field.end], 1)
+		if e != nil {
+			t.Fatal(e)
+		}
+		addresses = append(addresses, string(msg[field.value+key.value:field.value+key.end]))
+	}
+	if len(addresses) != len(c.rewardDelegators) || !sort.StringsAreSorted(addresses) {
+		t.Fatalf("got reward delegators %v, expected the %d of the tx sorted", addresses, len(c.rewardDelegators))
+	}
+}
+
+func TestReadProtoFieldsInvalid(t *testing.T) {
+	cases := map[string][]byte{
+		"truncated length":  {0x32, 0x05, 0x01},
+		"truncated varint":  {0x08, 0x80},
+		"truncated fixed64": {0x09, 0x01},
+		"group wire type":   {0x0b},
+	}
+
+	for name, bz := range cases {
+		if _, err := readProtoFields(bz); err == nil {
+			t.Errorf("%s: invalid proto was read", name)
+		}
+	}
+}
+
+func buildGoldenTx(t *testing.T, c goldenCase) *goldenTx {
+	t.Helper()
+	// ed25519 signatures are deterministic, so a fixed key gives a fixed raw tx
+	seed := sha256.Sum256([]byte("wtsc-golden-" + c.name))
+	signer, err := pocketGoSigner.NewSignerFromPrivateKey(hex.EncodeToString(ed25519.NewKeyFromSeed(seed[:])))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	outputAddress := c.outputAddress
+	if outputAddress == "" {
+		outputAddress = signer.GetAddress()
+	}
+	node := &pocketGoProvider.Node{
+		Address:          signer.GetAddress(),
+		Chains:           []string{"0001"},
+		PublicKey:        signer.GetPublicKey(),
+		ServiceURL:       "https://node.golden.wtsc:443",
+		Status:           2,
+		Tokens:           "15000000000",
+		OutputAddress:    outputAddress,
+		RewardDelegators: c.rewardDelegators,
+	}
+
+	msg, err := NewStakeMsg(signer.GetPublicKey(), node, c.chains)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stakeTx := NewStakeTx(c.networkID, msg, c.fee, c.memo, c.entropy)
+	signBytes, err := stakeTx.SignBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	txBytes, err := stakeTx.Sign(signer)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the decoder must read back what was built
+	decoded, err := DecodeStakeTx(txBytes, c.networkID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !*decoded.SignatureValid {
+		t.Fatal("invalid signature")
+	}
+	if decoded.Address != signer.GetAddress() || !IsSameStrSet(decoded.Chains, c.chains) || decoded.Value != node.Tokens ||
+		decoded.ServiceURL != node.ServiceURL || decoded.OutputAddress != outputAddress || decoded.Memo != c.memo ||
+		decoded.Entropy != c.entropy {
+		t.Fatalf("decoded tx does not match the built one: %+v", decoded)
+	}
+	if len(c.rewardDelegators) > 0 && !reflect.DeepEqual(decoded.RewardDelegators, c.rewardDelegators) {
+		t.Fatalf("decoded reward delegators %v, expected %v", decoded.RewardDelegators, c.rewardDelegators)
+	}
+
+	return &goldenTx{
+		Name:      c.name,
+		Address:   signer.GetAddress(),
+		SignBytes: string(signBytes),
+		RawTx:     hex.EncodeToString(txBytes),
+		Hash:      TxHash(txBytes),
+	}
+}
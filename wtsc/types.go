@@ -29,7 +29,47 @@ func (ss MinServiceStake) CastToGqlType() (r []generated.WtsMinServiceStakeInput
 	return
 }
 
+// NotificationFilter is shared by every notification channel
+type NotificationFilter struct {
+	// Events to send, empty means all of them
+	Events []string `json:"events"`
+	// MinGainChangePercent skips the notifications of recommendations with a lower gain_change_percent
+	MinGainChangePercent float64 `json:"min_gain_change_percent"`
+}
+
+type WebhookConfig struct {
+	NotificationFilter
+	// URL of the webhook, it usually contains a secret
+	URL string `json:"url"`
+	// Format of the payload: generic (default, the notification as JSON), slack or discord
+	Format string `json:"format"`
+}
+
+type TelegramConfig struct {
+	NotificationFilter
+	// BotToken of the Telegram bot that sends the messages
+	BotToken string `json:"bot_token"`
+	// ChatID is the user, group or channel that receives the messages
+	ChatID string `json:"chat_id"`
+}
+
+type EmailConfig struct {
+	NotificationFilter
+	// Host and Port of the SMTP server, STARTTLS is used when the server supports it
+	Host string `json:"host"`
+	Port uint   `json:"port"`
+	// Username and Password for PLAIN auth, empty username disables the auth
+	Username string   `json:"username"`
+	Password string   `json:"password"`
+	From     string   `json:"from"`
+	To       []string `json:"to"`
+}
+
 type Config struct {
+	// Version is assigned on each load or reload of the config by this process, it is not part of the file
+	Version uint64 `json:"-"`
+	// Hash is the sha256 of the config values, it is not part of the file
+	Hash string `json:"-"`
 	// DryMode allows you to run the service without impact your stake, this will just print logs and save results
 	// if ResultsPath has a value.
 	DryMode bool `json:"dry_mode"`
@@ -50,6 +90,21 @@ type Config struct {
 	ServicePool []string `json:"service_pool"`
 	// ServicerKeys list of the private keys to use for sign nodes
 	ServicerKeys []string `json:"servicer_keys"`
+	// ServicerAddresses list of watch-only servicer addresses, their on-chain state is compared with the
+	// what-to-stake recommendation but there is no key to sign them.
+	ServicerAddresses []string `json:"servicer_addresses"`
+	// ServicerKeyFiles list of encrypted key files (`pocket accounts export` format) to use for sign nodes
+	ServicerKeyFiles []string `json:"servicer_keyfiles"`
+	// ServicerKeyFilesDir is a directory where every *.json file is an encrypted key file
+	ServicerKeyFilesDir string `json:"servicer_keyfiles_dir"`
+	// RemoteSignerURL is the url of a remote signing service, every key it serves is used to sign nodes.
+	// Refer to cmd/signer for the reference implementation.
+	RemoteSignerURL string `json:"remote_signer_url"`
+	// RemoteSignerToken is the bearer token of the remote signing service
+	RemoteSignerToken string `json:"remote_signer_token"`
+	// KeyFilePassphraseFile is a file with the passphrase of the key files.
+	// If empty, the passphrase is read from WTSC_KEYFILE_PASSPHRASE env var.
+	KeyFilePassphraseFile string `json:"keyfile_passphrase_file"`
 	// StakeWeight the stake weight that will be sent to what-to-stake service
 	StakeWeight uint `json:"stake_weight"`
 	// MinIncreasePercent the amount of change percent sent to what-to-stake service
@@ -62,6 +117,9 @@ type Config struct {
 	// This will also allow you to share with POKTscan in case you think something is wrong.
 	// Empty value disable this.
 	ResultsPath string `json:"results_path"`
+	// HistoryPath is the file of the embedded database that keeps a journal of every evaluation run.
+	// Empty value disable this.
+	HistoryPath string `json:"history_path"`
 	// LogLevel is the level of logging
 	LogLevel string `json:"log_level"`
 	// LogFormat allows to use JSON(optimal) or ColorizedText(slower). Values allowed: json|text
@@ -79,6 +137,39 @@ type Config struct {
 	MaxRetries uint `json:"max_retries"`
 	// MaxTimeout for PocketRpc and WhatToStake call (milliseconds)
 	MaxTimeout uint `json:"max_timeout"`
+	// HttpAddress is the address of the optional http listener that exposes /metrics (e.g. ":9090").
+	// Empty value disable this.
+	HttpAddress string `json:"http_address"`
+	// AdminAddress is the address of the optional admin api listener, keep it local (e.g. "127.0.0.1:9091").
+	// Empty value disable this.
+	AdminAddress string `json:"admin_address"`
+	// AdminToken is the bearer token required by the admin api
+	AdminToken string `json:"admin_token"`
+	// Webhooks notified about run outcomes and stake changes
+	Webhooks []WebhookConfig `json:"webhooks"`
+	// Telegram chats notified about run outcomes and stake changes
+	Telegram []TelegramConfig `json:"telegram"`
+	// Email recipients notified about run outcomes and stake changes
+	Email []EmailConfig `json:"email"`
+	// DigestSchedule is the cron schedule of the summary of the runs of the last 24h (e.g. "0 9 * * *").
+	// It requires HistoryPath. Empty value disable this.
+	DigestSchedule string `json:"digest_schedule"`
+	// RequireApproval persists a plan on each run that must be applied by a human (cli or admin api) instead
+	// of submitting the stake txs right away. It requires HistoryPath.
+	RequireApproval bool `json:"require_approval"`
+	// ApprovalMinNodes is the amount of nodes that needs to change to require an approval. Zero means always.
+	ApprovalMinNodes uint `json:"approval_min_nodes"`
+	// PlanTTL is the time a plan could wait for approval before expire (minutes)
+	PlanTTL uint `json:"plan_ttl"`
+	// ConfirmationBlocks is the amount of blocks to wait for a stake tx to be included. Zero disables the tracking.
+	ConfirmationBlocks uint `json:"confirmation_blocks"`
+	// ConfirmationTimeout is the max time to wait for a stake tx to be included (milliseconds). Zero means no limit.
+	ConfirmationTimeout uint `json:"confirmation_timeout"`
+	// ConfirmationPollInterval is how often the pocket rpc is queried for the stake tx (milliseconds)
+	ConfirmationPollInterval uint `json:"confirmation_poll_interval"`
+	// LowBalanceRestakes alerts when the balance of a servicer can pay less than this amount of restakes (tx_fee
+	// each) after its stake tx. Zero disables the alert.
+	LowBalanceRestakes uint `json:"low_balance_restakes"`
 }
 
 type AuthedTransport struct {
@@ -6,6 +6,7 @@ import (
 	"os"
 	"reflect"
 	"regexp"
+	"sort"
 	"strings"
 )
 
@@ -24,6 +25,23 @@ func GetStrSliceDiff(slice1, slice2 []string) (diff []string) {
 	return
 }
 
+// IsSameStrSet checks if both slices contain the same strings, repeated the same times, no matter the order
+func IsSameStrSet(slice1, slice2 []string) bool {
+	if len(slice1) != len(slice2) {
+		return false
+	}
+	sorted1 := append([]string(nil), slice1...)
+	sorted2 := append([]string(nil), slice2...)
+	sort.Strings(sorted1)
+	sort.Strings(sorted2)
+	for i := range sorted1 {
+		if sorted1[i] != sorted2[i] {
+			return false
+		}
+	}
+	return true
+}
+
 func GetServiceStakeSliceDiff(slice1, slice2 []ServiceStake) (updated, removed, added []ServiceStake) {
 	map1 := make(map[string]ServiceStake)
 	map2 := make(map[string]ServiceStake)
@@ -69,6 +87,18 @@ func FindStringInSlice(slice []string, str string) bool {
 	return false
 }
 
+// FindDuplicate returns the first string that is repeated on the slice
+func FindDuplicate(slice []string) (string, bool) {
+	seen := make(map[string]bool, len(slice))
+	for _, item := range slice {
+		if seen[item] {
+			return item, true
+		}
+		seen[item] = true
+	}
+	return "", false
+}
+
 // IsEmptyString checks if a string is empty by comparing it to an empty string.
 func IsEmptyString(str string) bool {
 	return str == ""
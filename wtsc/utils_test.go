@@ -0,0 +1,82 @@
+package wtsc
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestIsSameStrSet(t *testing.T) {
+	cases := []struct {
+		name   string
+		a, b   []string
+		expect bool
+	}{
+		{name: "empty", expect: true},
+		{name: "nil and empty", a: nil, b: []string{}, expect: true},
+		{name: "same order", a: []string{"0001", "0021"}, b: []string{"0001", "0021"}, expect: true},
+		{name: "other order", a: []string{"0021", "0003", "0001"}, b: []string{"0001", "0021", "0003"}, expect: true},
+		{name: "other length", a: []string{"0001"}, b: []string{"0001", "0021"}},
+		{name: "other value", a: []string{"0001", "0021"}, b: []string{"0001", "0003"}},
+		{name: "other repeated value", a: []string{"a", "a", "b"}, b: []string{"a", "b", "b"}},
+		{name: "same repeated value", a: []string{"a", "b", "a"}, b: []string{"a", "a", "b"}, expect: true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := IsSameStrSet(c.a, c.b); got != c.expect {
+				t.Fatalf("IsSameStrSet(%v, %v) = %t, expected %t", c.a, c.b, got, c.expect)
+			}
+			if got := IsSameStrSet(c.b, c.a); got != c.expect {
+				t.Fatalf("IsSameStrSet(%v, %v) = %t, expected %t", c.b, c.a, got, c.expect)
+			}
+		})
+	}
+}
+
+func TestIsSameStrSetKeepsOrder(t *testing.T) {
+	a := []string{"0021", "0001"}
+	b := []string{"0001", "0021"}
+	if !IsSameStrSet(a, b) {
+		t.Fatal("expected the same set")
+	}
+	if a[0] != "0021" || b[0] != "0001" {
+		t.Fatalf("the slices were sorted in place: %v %v", a, b)
+	}
+}
+
+func TestGetStrSliceDiff(t *testing.T) {
+	diff := GetStrSliceDiff([]string{"0001", "0021", "0003"}, []string{"0021"})
+	sort.Strings(diff)
+	if !reflect.DeepEqual(diff, []string{"0001", "0003"}) {
+		t.Fatalf("got %v", diff)
+	}
+	if diff = GetStrSliceDiff([]string{"0021"}, []string{"0021", "0001"}); len(diff) != 0 {
+		t.Fatalf("got %v, expected no diff", diff)
+	}
+}
+
+func TestFindDuplicate(t *testing.T) {
+	if dup, ok := FindDuplicate([]string{"a", "b", "a", "b"}); !ok || dup != "a" {
+		t.Fatalf("got %q %t, expected the first repeated one", dup, ok)
+	}
+	if _, ok := FindDuplicate([]string{"a", "b"}); ok {
+		t.Fatal("found a duplicate on unique values")
+	}
+}
+
+func TestIsValidHttpURI(t *testing.T) {
+	cases := map[string]bool{
+		"":                         false,
+		"https://api.poktscan.com": true,
+		"http://127.0.0.1:8081/v1": true,
+		"ftp://host":               false,
+		"https://":                 false,
+		"not a uri":                false,
+	}
+	for uri, expect := range cases {
+		if got := IsValidHttpURI(uri); got != expect {
+			t.Errorf("IsValidHttpURI(%q) = %t, expected %t", uri, got, expect)
+		}
+	}
+}
@@ -0,0 +1,331 @@
+package wtsc
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/robfig/cron/v3"
+	"github.com/rs/zerolog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// ConfigError is a config value that does not pass the validation
+type ConfigError struct {
+	// Key is the path of the value, i.e. service_pool[1] or webhooks[0].url
+	Key string
+	// Value is the offending value, nil when it is a secret or there is nothing to show
+	Value any
+	// Rule is the human-readable rule that the value violates
+	Rule string
+}
+
+func (ce *ConfigError) Error() string {
+	switch v := ce.Value.(type) {
+	case nil:
+		return fmt.Sprintf("%s: %s", ce.Key, ce.Rule)
+	case string:
+		return fmt.Sprintf("%s: %s (got %q)", ce.Key, ce.Rule, v)
+	default:
+		return fmt.Sprintf("%s: %s (got %v)", ce.Key, ce.Rule, v)
+	}
+}
+
+func newConfigError(key string, value any, rule string) *ConfigError {
+	return &ConfigError{Key: key, Value: value, Rule: rule}
+}
+
+// CheckConfig returns every value of the config that does not pass the validation
+func CheckConfig(cfg *Config) (errs []*ConfigError) {
+	add := func(key string, value any, rule string) {
+		errs = append(errs, newConfigError(key, value, rule))
+	}
+
+	if !IsValidHttpURI(cfg.POKTscanApi) {
+		add("poktscan_api", cfg.POKTscanApi, "must be a http(s) url")
+	}
+
+	if IsEmptyString(cfg.POKTscanApiToken) {
+		add("poktscan_api_token", nil, "is required")
+	}
+
+	if cfg.NetworkID != "mainnet" && cfg.NetworkID != "testnet" {
+		add("network_id", cfg.NetworkID, `must be "mainnet" or "testnet"`)
+	}
+
+	if fee, err := cfg.TxFee.Int64(); err != nil || fee <= 0 {
+		add("tx_fee", cfg.TxFee.String(), "must be a positive integer")
+	}
+
+	if !IsValidDomain(cfg.Domain) {
+		add("domain", cfg.Domain, "must be a valid domain")
+	}
+
+	if len(cfg.ServicePool) == 0 {
+		add("service_pool", nil, "must have at least one service")
+	}
+	for i, service := range cfg.ServicePool {
+		if !IsValidChainPool([]string{service}) {
+			add(fmt.Sprintf("service_pool[%d]", i), service, "must be a valid service id (4 hex characters)")
+		}
+	}
+	if dup, ok := FindDuplicate(cfg.ServicePool); ok {
+		add("service_pool", dup, "must not have duplicates")
+	}
+
+	// key files or remote signer could be the only source of signers, and watch-only addresses does not need them
+	keysOptional := cfg.DryMode || HasKeyFiles(cfg) || !IsEmptyString(cfg.RemoteSignerURL) || len(cfg.ServicerAddresses) > 0
+	if len(cfg.ServicerKeys) == 0 && !keysOptional {
+		add("servicer_keys", nil, "must have at least one key unless dry_mode, key files, remote signer or servicer_addresses are used")
+	}
+	for i, key := range cfg.ServicerKeys {
+		// never show a private key
+		if !IsValidServicerList([]string{key}, true) {
+			add(fmt.Sprintf("servicer_keys[%d]", i), nil, "must be a hex encoded ed25519 private key")
+		}
+	}
+	if _, ok := FindDuplicate(cfg.ServicerKeys); ok {
+		add("servicer_keys", nil, "must not have duplicates")
+	}
+
+	for i, address := range cfg.ServicerAddresses {
+		if !IsValidAddressList([]string{address}) {
+			add(fmt.Sprintf("servicer_addresses[%d]", i), address, "must be a hex encoded address")
+		}
+	}
+	if dup, ok := FindDuplicate(cfg.ServicerAddresses); ok {
+		add("servicer_addresses", dup, "must not have duplicates")
+	}
+
+	if !IsEmptyString(cfg.RemoteSignerURL) && !IsValidHttpURI(cfg.RemoteSignerURL) {
+		add("remote_signer_url", cfg.RemoteSignerURL, "must be a http(s) url")
+	}
+
+	errs = append(errs, CheckKeyFiles(cfg)...)
+
+	if cfg.StakeWeight < 1 || cfg.StakeWeight > 4 {
+		add("stake_weight", cfg.StakeWeight, "must be between 1 and 4")
+	}
+
+	if cfg.MinIncreasePercent < 1 || cfg.MinIncreasePercent > 100 {
+		add("min_increase_percent", cfg.MinIncreasePercent, "must be between 1 and 100")
+	}
+
+	for i, minServiceStake := range cfg.MinServiceStake {
+		key := fmt.Sprintf("min_service_stake[%d].service", i)
+		if !IsValidMinServiceStake(MinServiceStake{minServiceStake}) {
+			add(key, minServiceStake.Service, "must be a valid service id (4 hex characters)")
+		} else if !FindStringInSlice(cfg.ServicePool, minServiceStake.Service) {
+			add(key, minServiceStake.Service, "must be one of service_pool")
+		}
+	}
+
+	if cfg.TimePeriod < 6 || cfg.TimePeriod > 48 {
+		add("time_period", cfg.TimePeriod, "must be between 6 and 48 hours")
+	}
+
+	if !IsEmptyString(cfg.ResultsPath) && !IsWritableDirectory(filepath.Join(ProjectRoot, cfg.ResultsPath)) {
+		// empty string disable the feature so it's ok been empty
+		add("results_path", cfg.ResultsPath, "must be a writable directory")
+	}
+
+	if !IsEmptyString(cfg.HistoryPath) && !IsWritableDirectory(filepath.Dir(filepath.Join(ProjectRoot, cfg.HistoryPath))) {
+		// empty string disable the feature so it's ok been empty
+		add("history_path", cfg.HistoryPath, "must be in a writable directory")
+	}
+
+	if _, err := zerolog.ParseLevel(cfg.LogLevel); err != nil {
+		add("log_level", cfg.LogLevel, "must be one of trace, debug, info, warn, error, fatal, panic or empty")
+	}
+
+	if cfg.LogFormat != LogTextFormat && cfg.LogFormat != LogJsonFormat {
+		add("log_format", cfg.LogFormat, fmt.Sprintf("must be %q or %q", LogTextFormat, LogJsonFormat))
+	}
+
+	if _, err := cron.ParseStandard(cfg.Schedule); err != nil {
+		add("schedule", cfg.Schedule, "must be a cron expression or descriptor: "+err.Error())
+	}
+
+	if cfg.MaxWorkers <= 0 {
+		add("max_workers", cfg.MaxWorkers, "must be greater than 0")
+	}
+
+	if !IsValidHttpURI(cfg.PocketRPC) {
+		add("pocket_rpc", cfg.PocketRPC, "must be a http(s) url")
+	}
+
+	// less than 1s is not allowed
+	if cfg.MaxTimeout < 1000 {
+		add("max_timeout", cfg.MaxTimeout, "must be at least 1000 milliseconds")
+	}
+
+	errs = append(errs, CheckWebhooks(cfg.Webhooks)...)
+	errs = append(errs, CheckTelegram(cfg.Telegram)...)
+	errs = append(errs, CheckEmail(cfg.Email)...)
+
+	if !IsEmptyString(cfg.DigestSchedule) {
+		if _, err := cron.ParseStandard(cfg.DigestSchedule); err != nil {
+			add("digest_schedule", cfg.DigestSchedule, "must be a cron expression or descriptor: "+err.Error())
+		}
+		// the digest is built from the history store
+		if IsEmptyString(cfg.HistoryPath) {
+			add("digest_schedule", cfg.DigestSchedule, "requires history_path")
+		}
+	}
+
+	if !IsEmptyString(cfg.AdminAddress) && len(cfg.AdminToken) < 16 {
+		// the admin api is able to trigger stake transactions, so it needs a reasonable token
+		add("admin_token", nil, "must have at least 16 characters when admin_address is set")
+	}
+
+	if cfg.RequireApproval && IsEmptyString(cfg.HistoryPath) {
+		// plans are persisted on the history store
+		add("require_approval", cfg.RequireApproval, "requires history_path")
+	}
+
+	// zero means default, but less than 1s is not allowed
+	if cfg.ConfirmationPollInterval != 0 && cfg.ConfirmationPollInterval < 1000 {
+		add("confirmation_poll_interval", cfg.ConfirmationPollInterval, "must be 0 (default) or at least 1000 milliseconds")
+	}
+
+	return errs
+}
+
+// CheckNotificationFilter validates the events of a notification channel, key is the path of the channel
+func CheckNotificationFilter(key string, filter NotificationFilter) (errs []*ConfigError) {
+	for i, event := range filter.Events {
+		found := false
+		for _, knownEvent := range NotificationEvents {
+			if NotificationEvent(event) == knownEvent {
+				found = true
+				break
+			}
+		}
+		if !found {
+			errs = append(errs, newConfigError(fmt.Sprintf("%s.events[%d]", key, i), event, "must be a known event"))
+		}
+	}
+	return errs
+}
+
+// UnknownConfigKeys returns the keys of the config file that does not match any config value, usually typos,
+// which are ignored otherwise. Nested objects (i.e. webhooks) are checked too.
+func UnknownConfigKeys(bz []byte, format string) ([]string, error) {
+	bz, err := ConfigJson(bz, format)
+	if err != nil {
+		return nil, err
+	}
+
+	var values any
+	if err = json.Unmarshal(bz, &values); err != nil {
+		return nil, err
+	}
+
+	unknown := unknownKeys("", values, reflect.TypeOf(Config{}))
+	sort.Strings(unknown)
+	return unknown, nil
+}
+
+func unknownKeys(prefix string, value any, t reflect.Type) (unknown []string) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch v := value.(type) {
+	case []any:
+		if t.Kind() != reflect.Slice {
+			return nil
+		}
+		for i, item := range v {
+			unknown = append(unknown, unknownKeys(fmt.Sprintf("%s[%d]", prefix, i), item, t.Elem())...)
+		}
+	case map[string]any:
+		if t.Kind() != reflect.Struct {
+			return nil
+		}
+		fields := jsonFields(t)
+		for key, item := range v {
+			path := key
+			if prefix != "" {
+				path = prefix + "." + key
+			}
+			field, ok := fields[key]
+			if !ok {
+				unknown = append(unknown, path)
+				continue
+			}
+			unknown = append(unknown, unknownKeys(path, item, field)...)
+		}
+	}
+
+	return unknown
+}
+
+// jsonFields returns the type of each json key of a struct, including the ones of embedded structs
+func jsonFields(t reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		key := strings.Split(field.Tag.Get("json"), ",")[0]
+		if field.Anonymous && key == "" {
+			for k, ft := range jsonFields(field.Type) {
+				fields[k] = ft
+			}
+			continue
+		}
+		if key == "-" || !field.IsExported() {
+			continue
+		}
+		if key == "" {
+			key = field.Name
+		}
+		fields[key] = field.Type
+	}
+	return fields
+}
+
+// ConfigReport is the result of the validation of a config file
+type ConfigReport struct {
+	Path string
+	// Warnings does not stop wtsc, like unknown keys
+	Warnings []string
+	Errors   []*ConfigError
+}
+
+func (cr *ConfigReport) Valid() bool {
+	return len(cr.Errors) == 0
+}
+
+// ValidateConfigFile reads the config file (and its env overrides) and returns every problem found on it.
+// The error is only returned when the file could not be read or decoded.
+func ValidateConfigFile(configPath string) (*ConfigReport, error) {
+	report := &ConfigReport{Path: configPath}
+
+	bz, err := os.ReadFile(configPath)
+	if err != nil {
+		return nil, err
+	}
+
+	format := ConfigFormat(configPath)
+	cfg := Config{}
+	if err = DecodeConfig(bz, format, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal %s config file: %w", format, err)
+	}
+
+	unknown, err := UnknownConfigKeys(bz, format)
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range unknown {
+		report.Warnings = append(report.Warnings, fmt.Sprintf("%s: unknown key, it is ignored", key))
+	}
+
+	if _, err = ApplyEnvOverrides(&cfg); err != nil {
+		return nil, err
+	}
+
+	report.Errors = CheckConfig(&cfg)
+	return report, nil
+}
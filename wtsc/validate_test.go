@@ -0,0 +1,205 @@
+package wtsc
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// newValidTestConfig returns a config without errors
+func newValidTestConfig() *Config {
+	return &Config{
+		POKTscanApi:        "https://api.poktscan.com/poktscan/api/graphql",
+		POKTscanApiToken:   "test",
+		NetworkID:          "mainnet",
+		TxFee:              "10000",
+		Domain:             "wtsc.test",
+		ServicePool:        []string{"0001", "0021"},
+		ServicerKeys:       []string{testPrivateKey("0")},
+		StakeWeight:        1,
+		MinIncreasePercent: 5,
+		MinServiceStake:    MinServiceStake{{Service: "0021", MinNode: 1}},
+		TimePeriod:         24,
+		LogLevel:           "info",
+		LogFormat:          LogTextFormat,
+		Schedule:           "@every 5m",
+		MaxWorkers:         1,
+		PocketRPC:          "http://127.0.0.1:8081",
+		MaxTimeout:         5000,
+	}
+}
+
+func TestCheckConfig(t *testing.T) {
+	address := newTestSigner(t, "0").GetAddress()
+
+	cases := []struct {
+		name   string
+		change func(cfg *Config)
+		// expect are the keys with errors, nil when the config is valid
+		expect []string
+	}{
+		{name: "valid", change: func(cfg *Config) {}},
+		{name: "min_increase_percent lower bound", change: func(cfg *Config) { cfg.MinIncreasePercent = 1 }},
+		{name: "min_increase_percent upper bound", change: func(cfg *Config) { cfg.MinIncreasePercent = 100 }},
+		{
+			name:   "min_increase_percent below 1",
+			change: func(cfg *Config) { cfg.MinIncreasePercent = 0.5 },
+			expect: []string{"min_increase_percent"},
+		},
+		{
+			name:   "min_increase_percent above 100",
+			change: func(cfg *Config) { cfg.MinIncreasePercent = 100.5 },
+			expect: []string{"min_increase_percent"},
+		},
+		{name: "max_timeout of 1s", change: func(cfg *Config) { cfg.MaxTimeout = 1000 }},
+		{
+			name:   "max_timeout below 1s",
+			change: func(cfg *Config) { cfg.MaxTimeout = 999 },
+			expect: []string{"max_timeout"},
+		},
+		{
+			name:   "duplicated service",
+			change: func(cfg *Config) { cfg.ServicePool = []string{"0001", "0021", "0001"} },
+			expect: []string{"service_pool"},
+		},
+		{
+			name:   "invalid service",
+			change: func(cfg *Config) { cfg.ServicePool = []string{"0001", "0021", "zz"} },
+			expect: []string{"service_pool[2]"},
+		},
+		{
+			name:   "empty service pool",
+			change: func(cfg *Config) { cfg.ServicePool = nil; cfg.MinServiceStake = nil },
+			expect: []string{"service_pool"},
+		},
+		{
+			name:   "duplicated key",
+			change: func(cfg *Config) { cfg.ServicerKeys = append(cfg.ServicerKeys, testPrivateKey("0")) },
+			expect: []string{"servicer_keys"},
+		},
+		{
+			name:   "duplicated address",
+			change: func(cfg *Config) { cfg.ServicerAddresses = []string{address, address} },
+			expect: []string{"servicer_addresses"},
+		},
+		{
+			name:   "min service stake out of the service pool",
+			change: func(cfg *Config) { cfg.MinServiceStake = MinServiceStake{{Service: "0003", MinNode: 1}} },
+			expect: []string{"min_service_stake[0].service"},
+		},
+		{
+			name:   "invalid min service stake",
+			change: func(cfg *Config) { cfg.MinServiceStake = append(cfg.MinServiceStake, ServiceStake{Service: "zz"}) },
+			expect: []string{"min_service_stake[1].service"},
+		},
+		{
+			name:   "no keys",
+			change: func(cfg *Config) { cfg.ServicerKeys = nil },
+			expect: []string{"servicer_keys"},
+		},
+		{
+			name:   "watch-only addresses without keys",
+			change: func(cfg *Config) { cfg.ServicerKeys = nil; cfg.ServicerAddresses = []string{address} },
+		},
+		{
+			name:   "invalid watch-only address",
+			change: func(cfg *Config) { cfg.ServicerKeys = nil; cfg.ServicerAddresses = []string{"not-an-address"} },
+			expect: []string{"servicer_addresses[0]"},
+		},
+		{name: "dry mode without keys", change: func(cfg *Config) { cfg.ServicerKeys = nil; cfg.DryMode = true }},
+		{
+			name: "every error is reported",
+			change: func(cfg *Config) {
+				cfg.NetworkID = "devnet"
+				cfg.StakeWeight = 5
+				cfg.TimePeriod = 5
+				cfg.MaxWorkers = 0
+			},
+			expect: []string{"network_id", "stake_weight", "time_period", "max_workers"},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			cfg := newValidTestConfig()
+			c.change(cfg)
+
+			var keys []string
+			for _, err := range CheckConfig(cfg) {
+				keys = append(keys, err.Key)
+			}
+			if !reflect.DeepEqual(keys, c.expect) {
+				t.Fatalf("got errors on %v, expected %v", keys, c.expect)
+			}
+		})
+	}
+}
+
+func TestConfigErrorHidesSecrets(t *testing.T) {
+	cfg := newValidTestConfig()
+	cfg.ServicerKeys = []string{"not-a-key", "not-a-key"}
+
+	for _, err := range CheckConfig(cfg) {
+		if strings.Contains(err.Error(), "not-a-key") {
+			t.Fatalf("the error shows a private key: %s", err)
+		}
+	}
+}
+
+func TestUnknownConfigKeys(t *testing.T) {
+	cases := []struct {
+		name    string
+		format  string
+		content string
+		expect  []string
+	}{
+		{
+			name:    "known keys",
+			format:  ConfigFormatJson,
+			content: `{"dry_mode": true, "max_timeout": 5000, "min_service_stake": [{"service": "0001", "min_node": 1}]}`,
+		},
+		{
+			name:    "typos",
+			format:  ConfigFormatJson,
+			content: `{"dry_mod": true, "max_timeout_ms": 5000, "max_workers": 1}`,
+			expect:  []string{"dry_mod", "max_timeout_ms"},
+		},
+		{
+			name:    "nested objects",
+			format:  ConfigFormatJson,
+			content: `{"webhooks": [{"url": "https://example.com", "events": ["wts_failed"], "fromat": "slack"}], "min_service_stake": [{"service": "0001", "min_nodes": 1}]}`,
+			expect:  []string{"min_service_stake[0].min_nodes", "webhooks[0].fromat"},
+		},
+		{
+			name:    "yaml",
+			format:  ConfigFormatYaml,
+			content: "max_timeout: 5000\nmax_timout: 5000\n",
+			expect:  []string{"max_timout"},
+		},
+		{
+			name:    "toml",
+			format:  ConfigFormatToml,
+			content: "max_timeout = 5000\n\n[[webhooks]]\nurl = \"https://example.com\"\nformat = \"slack\"\nchannel = \"wtsc\"\n",
+			expect:  []string{"webhooks[0].channel"},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			unknown, err := UnknownConfigKeys([]byte(c.content), c.format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(unknown) == 0 {
+				unknown = nil
+			}
+			if !reflect.DeepEqual(unknown, c.expect) {
+				t.Fatalf("got %v unknown keys, expected %v", unknown, c.expect)
+			}
+		})
+	}
+
+	if _, err := UnknownConfigKeys([]byte(`{"max_timeout": `), ConfigFormatJson); err == nil {
+		t.Fatal("an invalid config file was accepted")
+	}
+}
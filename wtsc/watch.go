@@ -0,0 +1,92 @@
+package wtsc
+
+import (
+	"github.com/fsnotify/fsnotify"
+	"path/filepath"
+	"time"
+)
+
+// configWatchDebounce groups the burst of events of a single save (truncate+write, rename, symlink swap)
+const configWatchDebounce = 500 * time.Millisecond
+
+// ConfigWatcher reloads the config when the config file changes on disk
+type ConfigWatcher struct {
+	watcher *fsnotify.Watcher
+	done    chan struct{}
+}
+
+// WatchConfig starts watching the config file. The directory is watched instead of the file, because editors
+// save by renaming a temp file over it and kubernetes swaps the `..data` symlink of a ConfigMap mount, both of
+// them drop a watch on the file itself.
+func WatchConfig() (*ConfigWatcher, error) {
+	configPath := GetConfigFilePath()
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+
+	if err = watcher.Add(filepath.Dir(configPath)); err != nil {
+		_ = watcher.Close()
+		return nil, err
+	}
+
+	// the real file behind the symlinks, it changes when kubernetes updates a ConfigMap. It is resolved before
+	// returning, otherwise a swap right after the watch starts would be taken as the initial target
+	realPath, _ := filepath.EvalSymlinks(configPath)
+
+	cw := &ConfigWatcher{watcher: watcher, done: make(chan struct{})}
+	go cw.run(configPath, realPath)
+
+	Logger.Info().Str("path", configPath).Msg("watching config file for changes")
+	return cw, nil
+}
+
+// Stop stops watching the config file
+func (cw *ConfigWatcher) Stop() {
+	_ = cw.watcher.Close()
+	<-cw.done
+}
+
+func (cw *ConfigWatcher) run(configPath, realPath string) {
+	defer close(cw.done)
+
+	name := filepath.Clean(configPath)
+
+	debounce := time.NewTimer(configWatchDebounce)
+	debounce.Stop()
+	defer debounce.Stop()
+
+	for {
+		select {
+		case event, ok := <-cw.watcher.Events:
+			if !ok {
+				return
+			}
+
+			changed := filepath.Clean(event.Name) == name
+			if !changed {
+				// a ConfigMap update does not touch config path, only the symlink target changes
+				currentPath, err := filepath.EvalSymlinks(configPath)
+				changed = err == nil && currentPath != realPath
+			}
+			if !changed {
+				continue
+			}
+
+			Logger.Debug().Str("event", event.String()).Msg("config file change detected")
+			debounce.Reset(configWatchDebounce)
+		case err, ok := <-cw.watcher.Errors:
+			if !ok {
+				return
+			}
+			Logger.Error().Err(err).Msg("config file watcher error")
+		case <-debounce.C:
+			if currentPath, err := filepath.EvalSymlinks(configPath); err == nil {
+				realPath = currentPath
+			}
+			// errors are already logged and reported by ReloadConfig, the last good config keeps running
+			_ = ReloadConfig()
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package wtsc
+
+import (
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// waitForConfig waits until the config in use satisfies cond
+func waitForConfig(t *testing.T, cond func(cfg *Config) bool) {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for !cond(GetConfig()) {
+		if time.Now().After(deadline) {
+			t.Fatalf("config was not reloaded, got %+v", GetConfig())
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+func startTestWatcher(t *testing.T) {
+	t.Helper()
+	watcher, err := WatchConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// registered after the globals of the test, so it is stopped before they are restored
+	t.Cleanup(watcher.Stop)
+}
+
+func TestWatchConfigRenameOnSave(t *testing.T) {
+	configPath, cfg := useTestConfigFile(t, "http://127.0.0.1:1", "", 1, testPrivateKey("0"))
+	SetRuntime(newConfigRuntime(t, cfg))
+	startTestWatcher(t)
+
+	// editors write a temp file and rename it over the config
+	tmp := filepath.Join(filepath.Dir(configPath), ".config.json.swp")
+	writeTestConfigWith(t, tmp, map[string]any{"max_workers": 2})
+	if err := os.Rename(tmp, configPath); err != nil {
+		t.Fatal(err)
+	}
+	waitForConfig(t, func(cfg *Config) bool { return cfg.MaxWorkers == 2 })
+
+	// the file is still watched after the rename, a plain write is reloaded too
+	writeTestConfigWith(t, configPath, map[string]any{"max_workers": 3})
+	waitForConfig(t, func(cfg *Config) bool { return cfg.MaxWorkers == 3 })
+}
+
+func TestWatchConfigKeepsLastGoodConfig(t *testing.T) {
+	configPath, cfg := useTestConfigFile(t, "http://127.0.0.1:1", "", 1, testPrivateKey("0"))
+	SetRuntime(newConfigRuntime(t, cfg))
+	startTestWatcher(t)
+
+	rejected := testutil.ToFloat64(configReloadsMetric.WithLabelValues("rejected"))
+	if err := os.WriteFile(configPath, []byte("{not json"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(10 * time.Second)
+	for testutil.ToFloat64(configReloadsMetric.WithLabelValues("rejected")) == rejected {
+		if time.Now().After(deadline) {
+			t.Fatal("the invalid config file was not rejected")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	if GetConfig() != cfg {
+		t.Fatal("an invalid file replaced the config")
+	}
+
+	// the watch goes on after a rejected file
+
+	writeTestConfigWith(t, configPath, map[string]any{"max_workers": 2})
+	waitForConfig(t, func(cfg *Config) bool { return cfg.MaxWorkers == 2 })
+}
+
+func TestWatchConfigMapSymlinkSwap(t *testing.T) {
+	_, cfg := useTestConfigFile(t, "http://127.0.0.1:1", "", 1, testPrivateKey("0"))
+
+	// the layout of a kubernetes ConfigMap mount: config.json -> ..data/config.json and ..data -> ..v1
+	mount := filepath.Join(ProjectRoot, "mount")
+	for _, version := range []string{"..v1", "..v2"} {
+		if err := os.MkdirAll(filepath.Join(mount, version), 0700); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeTestConfigWith(t, filepath.Join(mount, "..v1", "config.json"), nil)
+	writeTestConfigWith(t, filepath.Join(mount, "..v2", "config.json"), map[string]any{"max_workers": 2})
+	if err := os.Symlink("..v1", filepath.Join(mount, "..data")); err != nil {
+		t.Fatal(err)
+	}
+	configPath := filepath.Join(mount, "config.json")
+	if err := os.Symlink(filepath.Join("..data", "config.json"), configPath); err != nil {
+		t.Fatal(err)
+	}
+	SetConfigFilePath(configPath)
+	SetRuntime(newConfigRuntime(t, cfg))
+	startTestWatcher(t)
+
+	// kubernetes swaps ..data atomically, config.json itself never changes
+	if err := os.Symlink("..v2", filepath.Join(mount, "..data_tmp")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(filepath.Join(mount, "..data_tmp"), filepath.Join(mount, "..data")); err != nil {
+		t.Fatal(err)
+	}
+	waitForConfig(t, func(cfg *Config) bool { return cfg.MaxWorkers == 2 })
+}